@@ -0,0 +1,30 @@
+// Package audit пишет журнал аудита (models.AuditLog)
+package audit
+
+import (
+	"log"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Record сохраняет запись журнала. Ошибка записи только логируется: аудит не должен
+// ломать обработку запроса
+func Record(db *gorm.DB, entry models.AuditLog) {
+	if err := db.Create(&entry).Error; err != nil {
+		log.Printf("Failed to write audit log %s: %v", entry.Action, err)
+	}
+}
+
+// FromRequest заполняет поля записи, известные из запроса: IP, метод, путь и оператора
+func FromRequest(c *gin.Context, action string) models.AuditLog {
+	return models.AuditLog{
+		Action: action,
+		Actor:  c.GetString("username"),
+		IP:     c.ClientIP(),
+		Method: c.Request.Method,
+		Path:   c.FullPath(),
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// BlocklistStore держит в памяти действующие записи блоклиста, чтобы публичные эндпоинты
+// не ходили в БД на каждый запрос
+type BlocklistStore struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+
+	mu          sync.RWMutex
+	telegramIDs map[string]time.Time // telegram_id -> срок блокировки (нулевой — бессрочно)
+	networks    []blockedNetwork
+}
+
+type blockedNetwork struct {
+	prefix    netip.Prefix
+	expiresAt time.Time
+}
+
+// NewBlocklistStore загружает блоклист и запускает его периодическое обновление
+func NewBlocklistStore(db *gorm.DB, logger *logrus.Logger) *BlocklistStore {
+	s := &BlocklistStore{
+		db:          db,
+		logger:      logger,
+		telegramIDs: make(map[string]time.Time),
+	}
+	if err := s.Reload(); err != nil {
+		logger.Errorf("Failed to load blocklist: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Reload(); err != nil {
+				s.logger.Errorf("Failed to refresh blocklist: %v", err)
+			}
+		}
+	}()
+	return s
+}
+
+// IsTelegramIDBlocked сообщает, заблокирован ли telegram_id
+func (s *BlocklistStore) IsTelegramIDBlocked(telegramID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	expiresAt, ok := s.telegramIDs[telegramID]
+	return ok && (expiresAt.IsZero() || time.Now().Before(expiresAt))
+}
+
+// IsIPBlocked сообщает, попадает ли адрес в заблокированный адрес или подсеть
+func (s *BlocklistStore) IsIPBlocked(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, network := range s.networks {
+		if network.prefix.Contains(addr) && (network.expiresAt.IsZero() || now.Before(network.expiresAt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload перечитывает блоклист из БД. Вызывается и после изменения блоклиста через API
+func (s *BlocklistStore) Reload() error {
+	var entries []models.BlocklistEntry
+	if err := s.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&entries).Error; err != nil {
+		return err
+	}
+	s.set(entries)
+	return nil
+}
+
+// set заменяет содержимое кэша записями entries
+func (s *BlocklistStore) set(entries []models.BlocklistEntry) {
+	telegramIDs := make(map[string]time.Time)
+	var networks []blockedNetwork
+	for _, entry := range entries {
+		var expiresAt time.Time
+		if entry.ExpiresAt != nil {
+			expiresAt = *entry.ExpiresAt
+		}
+		switch entry.Kind {
+		case models.BlockKindTelegramID:
+			telegramIDs[entry.Value] = expiresAt
+		case models.BlockKindIP:
+			prefix, err := ParseBlockedIP(entry.Value)
+			if err != nil {
+				s.logger.Warnf("Skipping invalid blocklist entry %d: %v", entry.ID, err)
+				continue
+			}
+			networks = append(networks, blockedNetwork{prefix: prefix, expiresAt: expiresAt})
+		}
+	}
+
+	s.mu.Lock()
+	s.telegramIDs = telegramIDs
+	s.networks = networks
+	s.mu.Unlock()
+}
+
+// ParseBlockedIP разбирает значение записи блоклиста вида ip: адрес или подсеть CIDR
+func ParseBlockedIP(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid CIDR %q", value)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid IP address %q", value)
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
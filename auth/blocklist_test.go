@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestBlocklist(entries ...models.BlocklistEntry) *BlocklistStore {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	s := &BlocklistStore{logger: logger}
+	s.set(entries)
+	return s
+}
+
+func TestBlocklistTelegramID(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	s := newTestBlocklist(
+		models.BlocklistEntry{Kind: models.BlockKindTelegramID, Value: "100"},
+		models.BlocklistEntry{Kind: models.BlockKindTelegramID, Value: "200", ExpiresAt: &future},
+		models.BlocklistEntry{Kind: models.BlockKindTelegramID, Value: "300", ExpiresAt: &past},
+	)
+	for _, tc := range []struct {
+		telegramID string
+		want       bool
+	}{
+		{"100", true},
+		{"200", true},
+		{"300", false}, // блокировка истекла между перечитываниями
+		{"400", false},
+		{"0100", false},
+	} {
+		if got := s.IsTelegramIDBlocked(tc.telegramID); got != tc.want {
+			t.Errorf("IsTelegramIDBlocked(%q) = %v, want %v", tc.telegramID, got, tc.want)
+		}
+	}
+}
+
+func TestBlocklistIP(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	s := newTestBlocklist(
+		models.BlocklistEntry{Kind: models.BlockKindIP, Value: "192.0.2.10"},
+		models.BlocklistEntry{Kind: models.BlockKindIP, Value: "198.51.100.0/24"},
+		models.BlocklistEntry{Kind: models.BlockKindIP, Value: "2001:db8::/32"},
+		models.BlocklistEntry{Kind: models.BlockKindIP, Value: "203.0.113.5", ExpiresAt: &past},
+		models.BlocklistEntry{ID: 7, Kind: models.BlockKindIP, Value: "not-an-ip"},
+	)
+	for _, tc := range []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.10", true},
+		{"192.0.2.11", false},
+		{"198.51.100.77", true},
+		{"198.51.101.1", false},
+		{"::ffff:198.51.100.77", true}, // IPv4, отображённый в IPv6
+		{"2001:db8:1::1", true},
+		{"2001:db9::1", false},
+		{"203.0.113.5", false},
+		{"garbage", false},
+	} {
+		if got := s.IsIPBlocked(tc.ip); got != tc.want {
+			t.Errorf("IsIPBlocked(%q) = %v, want %v", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestParseBlockedIP(t *testing.T) {
+	for _, tc := range []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{"192.0.2.10", "192.0.2.10/32", false},
+		{"::ffff:192.0.2.10", "192.0.2.10/32", false},
+		{"198.51.100.77/24", "198.51.100.0/24", false},
+		{"2001:db8::1", "2001:db8::1/128", false},
+		{"192.0.2.0/33", "", true},
+		{"example.com", "", true},
+	} {
+		prefix, err := ParseBlockedIP(tc.value)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseBlockedIP(%q) error = %v, wantErr %v", tc.value, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && prefix.String() != tc.want {
+			t.Errorf("ParseBlockedIP(%q) = %s, want %s", tc.value, prefix, tc.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// PermissionSet — набор прав одной роли
+type PermissionSet map[string]bool
+
+// Has сообщает, что в наборе есть право permission
+func (s PermissionSet) Has(permission string) bool {
+	return s[permission]
+}
+
+// PermissionStore держит в памяти права всех ролей из таблицы role_permissions,
+// чтобы проверка прав не ходила в БД на каждый запрос
+type PermissionStore struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+
+	mu    sync.RWMutex
+	roles map[string]PermissionSet
+}
+
+// NewPermissionStore заполняет встроенные роли при первом запуске, загружает права
+// и запускает их периодическое обновление
+func NewPermissionStore(db *gorm.DB, logger *logrus.Logger) *PermissionStore {
+	s := &PermissionStore{
+		db:     db,
+		logger: logger,
+		roles:  make(map[string]PermissionSet),
+	}
+	if err := s.seed(); err != nil {
+		logger.Errorf("Failed to seed default roles: %v", err)
+	}
+	if err := s.Reload(); err != nil {
+		logger.Errorf("Failed to load role permissions: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Reload(); err != nil {
+				s.logger.Errorf("Failed to refresh role permissions: %v", err)
+			}
+		}
+	}()
+	return s
+}
+
+// For возвращает права роли; у неизвестной роли прав нет
+func (s *PermissionStore) For(role string) PermissionSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if set, ok := s.roles[role]; ok {
+		return set
+	}
+	return PermissionSet{}
+}
+
+// Reload перечитывает права из БД. Вызывается после изменения ролей через API
+func (s *PermissionStore) Reload() error {
+	var rows []models.RolePermission
+	if err := s.db.Find(&rows).Error; err != nil {
+		return err
+	}
+
+	roles := make(map[string]PermissionSet)
+	for _, row := range rows {
+		if roles[row.Role] == nil {
+			roles[row.Role] = PermissionSet{}
+		}
+		roles[row.Role][row.Permission] = true
+	}
+
+	s.mu.Lock()
+	s.roles = roles
+	s.mu.Unlock()
+	return nil
+}
+
+// seed создаёт встроенные роли с правами по умолчанию. Уже существующие роли не трогает,
+// чтобы не затирать права, изменённые администратором
+func (s *PermissionStore) seed() error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for name, permissions := range models.DefaultRolePermissions {
+			role := models.Role{Name: name}
+			result := tx.Where(models.Role{Name: name}).FirstOrCreate(&role)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			for _, permission := range permissions {
+				if err := tx.Create(&models.RolePermission{Role: name, Permission: permission}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// refreshInterval — как часто кэш перечитывает отзывы из БД, чтобы увидеть
+	// отзывы, сделанные другими экземплярами сервиса
+	refreshInterval = 30 * time.Second
+	// MaxTokenTTL — максимальный срок жизни access-токена; отзывы субъектов старше него больше не нужны
+	MaxTokenTTL = 24 * time.Hour
+)
+
+// Subject возвращает идентификатор владельца токена по его claims
+func Subject(claims jwt.MapClaims) string {
+	if role, _ := claims["role"].(string); role == "user" {
+		telegramID, _ := claims["telegram_id"].(string)
+		stand, _ := claims["stand"].(string)
+		return UserSubject(telegramID, stand)
+	}
+	username, _ := claims["username"].(string)
+	return OperatorSubject(username)
+}
+
+// UserSubject — идентификатор субъекта для пользователя. Токены, выданные для стенда, имеют
+// свой субъект, чтобы отзыв доступа к одному стенду не завершал сессии на других
+func UserSubject(telegramID, stand string) string {
+	if stand != "" {
+		return "user:" + telegramID + "@" + stand
+	}
+	return "user:" + telegramID
+}
+
+// OperatorSubject — идентификатор субъекта для оператора
+func OperatorSubject(username string) string {
+	return "operator:" + username
+}
+
+// IssuedAt возвращает время выдачи токена по claim iat. Новые токены несут iat с миллисекундами,
+// старые — в целых секундах
+func IssuedAt(claims jwt.MapClaims) time.Time {
+	iat, _ := claims["iat"].(float64)
+	return time.UnixMilli(int64(math.Round(iat * 1000)))
+}
+
+// RevocationStore хранит отозванные токены в Postgres и держит их копию в памяти,
+// чтобы проверка в JWT-мидлваре не ходила в БД на каждый запрос
+type RevocationStore struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time // jti -> срок действия токена
+	subjects map[string]time.Time // subject -> отозваны токены, выданные раньше этого момента
+}
+
+// NewRevocationStore загружает отзывы из БД и запускает их периодическое обновление
+func NewRevocationStore(db *gorm.DB, logger *logrus.Logger) *RevocationStore {
+	s := &RevocationStore{
+		db:       db,
+		logger:   logger,
+		tokens:   make(map[string]time.Time),
+		subjects: make(map[string]time.Time),
+	}
+	if err := s.reload(); err != nil {
+		logger.Errorf("Failed to load token revocations: %v", err)
+	}
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.reload(); err != nil {
+				s.logger.Errorf("Failed to refresh token revocations: %v", err)
+			}
+		}
+	}()
+	return s
+}
+
+// RevokeToken отзывает один токен по его jti
+func (s *RevocationStore) RevokeToken(jti, subject string, expiresAt time.Time) error {
+	record := models.RevokedToken{JTI: jti, Subject: subject, ExpiresAt: expiresAt}
+	err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record).Error
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = expiresAt
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeSubject отзывает все access-токены субъекта, выданные до текущего момента,
+// и все его действующие refresh-токены. Токен, выданный сразу после отзыва (например, при
+// повторном входе), остаётся действительным
+func (s *RevocationStore) RevokeSubject(subject string) error {
+	now := time.Now()
+	if err := revokeSubject(s.db, subject, now); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.subjects[subject] = now
+	s.mu.Unlock()
+	return nil
+}
+
+// RevokeSubjectTx записывает отзыв токенов субъекта в транзакции tx, не трогая кэш.
+// Отзыв вступает в силу, когда кэши экземпляров перечитают БД (не дольше refreshInterval)
+func RevokeSubjectTx(tx *gorm.DB, subject string) error {
+	return revokeSubject(tx, subject, time.Now())
+}
+
+func revokeSubject(db *gorm.DB, subject string, now time.Time) error {
+	err := db.Model(&models.RefreshToken{}).
+		Where("subject = ? AND revoked_at IS NULL", subject).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	record := models.SubjectRevocation{Subject: subject, RevokedBefore: now}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&record).Error
+}
+
+// IsRevoked сообщает, отозван ли токен с данным jti, субъектом и временем выдачи
+func (s *RevocationStore) IsRevoked(jti, subject string, issuedAt time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.tokens[jti]; ok {
+		return true
+	}
+	if before, ok := s.subjects[subject]; ok && issuedAt.Before(before) {
+		return true
+	}
+	return false
+}
+
+// reload перечитывает актуальные отзывы и удаляет из БД записи, которые уже не нужны
+func (s *RevocationStore) reload() error {
+	now := time.Now()
+
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("revoked_before < ?", now.Add(-MaxTokenTTL)).Delete(&models.SubjectRevocation{}).Error; err != nil {
+		return err
+	}
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{}).Error; err != nil {
+		return err
+	}
+
+	var revokedTokens []models.RevokedToken
+	if err := s.db.Find(&revokedTokens).Error; err != nil {
+		return err
+	}
+	var revokedSubjects []models.SubjectRevocation
+	if err := s.db.Find(&revokedSubjects).Error; err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revokedTokens))
+	for _, t := range revokedTokens {
+		tokens[t.JTI] = t.ExpiresAt
+	}
+	subjects := make(map[string]time.Time, len(revokedSubjects))
+	for _, r := range revokedSubjects {
+		subjects[r.Subject] = r.RevokedBefore
+	}
+
+	s.mu.Lock()
+	s.tokens = tokens
+	s.subjects = subjects
+	s.mu.Unlock()
+	return nil
+}
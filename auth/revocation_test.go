@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestIsRevokedSubjectCutoff(t *testing.T) {
+	cutoff := time.Date(2026, 3, 1, 12, 0, 0, 500*int(time.Millisecond), time.UTC)
+	store := &RevocationStore{
+		tokens:   map[string]time.Time{"revoked-jti": cutoff.Add(time.Hour)},
+		subjects: map[string]time.Time{"operator:alice": cutoff},
+	}
+
+	cases := []struct {
+		name     string
+		jti      string
+		subject  string
+		issuedAt time.Time
+		want     bool
+	}{
+		{"issued before cutoff", "a", "operator:alice", cutoff.Add(-time.Minute), true},
+		{"issued earlier in the same second", "b", "operator:alice", cutoff.Add(-300 * time.Millisecond), true},
+		{"legacy whole-second iat", "c", "operator:alice", cutoff.Truncate(time.Second), true},
+		{"issued later in the same second", "d", "operator:alice", cutoff.Add(300 * time.Millisecond), false},
+		{"issued after cutoff", "e", "operator:alice", cutoff.Add(time.Minute), false},
+		{"other subject", "f", "operator:bob", cutoff.Add(-time.Minute), false},
+		{"revoked jti", "revoked-jti", "operator:bob", cutoff.Add(time.Minute), true},
+	}
+	for _, tc := range cases {
+		if got := store.IsRevoked(tc.jti, tc.subject, tc.issuedAt); got != tc.want {
+			t.Errorf("%s: IsRevoked = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIssuedAt(t *testing.T) {
+	cases := []struct {
+		iat  interface{}
+		want time.Time
+	}{
+		{float64(1700000000), time.Unix(1700000000, 0)},
+		{1700000000.123, time.UnixMilli(1700000000123)},
+		{nil, time.UnixMilli(0)},
+	}
+	for _, tc := range cases {
+		if got := IssuedAt(jwt.MapClaims{"iat": tc.iat}); !got.Equal(tc.want) {
+			t.Errorf("IssuedAt(%v) = %v, want %v", tc.iat, got, tc.want)
+		}
+	}
+}
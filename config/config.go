@@ -1,6 +1,28 @@
 package config
 
-import "os"
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Хранилища вложений (STORAGE_BACKEND)
+const (
+	StorageLocal = "local"
+	StorageS3    = "s3"
+)
+
+// defaultAttachmentTypes — типы вложений по умолчанию: скриншоты, документы и логи
+var defaultAttachmentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain", "application/zip", "application/x-gzip"}
+
+// Политики выдачи токенов пользователям (CONSUMER_TOKEN_POLICY)
+const (
+	// ConsumerPolicyOpen — токен выдаётся по любому telegram_id
+	ConsumerPolicyOpen = "open"
+	// ConsumerPolicyWhitelist — токен выдаётся только для стенда, доступ к которому одобрен в whitelist
+	ConsumerPolicyWhitelist = "whitelist"
+)
 
 type Config struct {
 	DBHost     string
@@ -9,15 +31,145 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	JWTSecret  string
+	// EventLogRetention — сколько хранить журнал событий для дочитывания SSE-клиентами
+	EventLogRetention time.Duration
+	// AccessTokenTTL — срок жизни access-токена (JWT)
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL — срок жизни refresh-токена
+	RefreshTokenTTL time.Duration
+	// OutboxMaxAttempts — после стольких неудачных попыток уведомление стенда уходит в dead
+	OutboxMaxAttempts int
+	// OutboxStandConcurrency — сколько уведомлений одного стенда доставляется одновременно
+	OutboxStandConcurrency int
+	// BootstrapAdminUsername и BootstrapAdminPassword — учётная запись администратора,
+	// создаваемая при старте, если в базе ещё нет ни одного администратора
+	BootstrapAdminUsername string
+	BootstrapAdminPassword string
+	// ConsumerTokenPolicy — кому выдаются токены пользователей: ConsumerPolicyOpen или ConsumerPolicyWhitelist
+	ConsumerTokenPolicy string
+	// BotAPIKey — общий ключ бота для публичных эндпоинтов (заголовок X-Bot-Api-Key); пустой — ключ не проверяется
+	BotAPIKey string
+	// RateLimitPerIP и RateLimitPerTelegram — сколько запросов к публичным эндпоинтам допускается
+	// за RateLimitWindow с одного IP и для одного telegram_id; 0 отключает лимит
+	RateLimitPerIP       int
+	RateLimitPerTelegram int
+	RateLimitWindow      time.Duration
+	// TrustedProxies — адреса или подсети прокси, которым доверяется X-Forwarded-For при определении IP клиента
+	TrustedProxies []string
+	// TelegramAPIURL — адрес Telegram Bot API; подменяется, например, на локальный фейковый сервер
+	TelegramAPIURL string
+	// SMTPAddr (host:port), SMTPUsername и SMTPPassword — сервер для ответов почтового канала;
+	// пустой SMTPAddr — ответы по почте не отправляются. EmailFrom — адрес поддержки в From
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	EmailFrom    string
+	// EmailIngestToken — токен приёма входящих писем (заголовок X-Email-Ingest-Token); пустой — приём выключен
+	EmailIngestToken string
+	// EmailAuthservID — authserv-id почтового релея, который проверяет SPF/DKIM/DMARC входящих писем и пишет
+	// итог в Authentication-Results. Учитываются только заголовки с этим authserv-id; пустой — отправители
+	// писем не считаются проверенными
+	EmailAuthservID string
+	// StorageBackend — где хранятся вложения: StorageLocal (каталог StorageDir) или StorageS3
+	StorageBackend string
+	StorageDir     string
+	// S3Endpoint, S3Region, S3Bucket, S3AccessKey и S3SecretKey — S3-совместимое хранилище вложений
+	S3Endpoint  string
+	S3Region    string
+	S3Bucket    string
+	S3AccessKey string
+	S3SecretKey string
+	// AttachmentMaxSize — предельный размер одного вложения в байтах, AttachmentMaxFiles — вложений в сообщении
+	AttachmentMaxSize  int64
+	AttachmentMaxFiles int
+	// AttachmentAllowedTypes — допустимые типы вложений ("image/png" или "image/*"),
+	// сверяются с типом, определённым по содержимому файла
+	AttachmentAllowedTypes []string
 }
 
 func LoadConfig() *Config {
 	return &Config{
-		DBHost:     os.Getenv("DB_HOST"),
-		DBPort:     os.Getenv("DB_PORT"),
-		DBUser:     os.Getenv("DB_USER"),
-		DBPassword: os.Getenv("DB_PASSWORD"),
-		DBName:     os.Getenv("DB_NAME"),
-		JWTSecret:  os.Getenv("JWT_SECRET"),
+		DBHost:                 os.Getenv("DB_HOST"),
+		DBPort:                 os.Getenv("DB_PORT"),
+		DBUser:                 os.Getenv("DB_USER"),
+		DBPassword:             os.Getenv("DB_PASSWORD"),
+		DBName:                 os.Getenv("DB_NAME"),
+		JWTSecret:              os.Getenv("JWT_SECRET"),
+		EventLogRetention:      getEnvDuration("EVENT_LOG_RETENTION", 72*time.Hour),
+		AccessTokenTTL:         getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:        getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour),
+		OutboxMaxAttempts:      getEnvInt("OUTBOX_MAX_ATTEMPTS", 10),
+		OutboxStandConcurrency: getEnvInt("OUTBOX_STAND_CONCURRENCY", 2),
+		BootstrapAdminUsername: os.Getenv("BOOTSTRAP_ADMIN_USERNAME"),
+		BootstrapAdminPassword: os.Getenv("BOOTSTRAP_ADMIN_PASSWORD"),
+		ConsumerTokenPolicy:    getEnv("CONSUMER_TOKEN_POLICY", ConsumerPolicyOpen),
+		BotAPIKey:              os.Getenv("BOT_API_KEY"),
+		RateLimitPerIP:         getEnvInt("PUBLIC_RATE_LIMIT_PER_IP", 30),
+		RateLimitPerTelegram:   getEnvInt("PUBLIC_RATE_LIMIT_PER_TELEGRAM_ID", 5),
+		RateLimitWindow:        getEnvDuration("PUBLIC_RATE_LIMIT_WINDOW", time.Minute),
+		TrustedProxies:         getEnvList("TRUSTED_PROXIES", nil),
+		TelegramAPIURL:         getEnv("TELEGRAM_API_URL", "https://api.telegram.org"),
+		SMTPAddr:               os.Getenv("SMTP_ADDR"),
+		SMTPUsername:           os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:           os.Getenv("SMTP_PASSWORD"),
+		EmailFrom:              getEnv("EMAIL_FROM", "support@helpdesk.local"),
+		EmailIngestToken:       os.Getenv("EMAIL_INGEST_TOKEN"),
+		EmailAuthservID:        os.Getenv("EMAIL_AUTHSERV_ID"),
+		StorageBackend:         getEnv("STORAGE_BACKEND", StorageLocal),
+		StorageDir:             getEnv("STORAGE_DIR", "data/attachments"),
+		S3Endpoint:             os.Getenv("S3_ENDPOINT"),
+		S3Region:               getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:               os.Getenv("S3_BUCKET"),
+		S3AccessKey:            os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:            os.Getenv("S3_SECRET_KEY"),
+		AttachmentMaxSize:      int64(getEnvInt("ATTACHMENT_MAX_SIZE", 10<<20)),
+		AttachmentMaxFiles:     getEnvInt("ATTACHMENT_MAX_FILES", 5),
+		AttachmentAllowedTypes: getEnvList("ATTACHMENT_ALLOWED_TYPES", defaultAttachmentTypes),
+	}
+}
+
+// getEnv читает строку, возвращая значение по умолчанию, если переменная не задана
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvList читает список через запятую; пустые элементы отбрасываются.
+// Если переменная не задана или пуста, возвращается fallback
+func getEnvList(key string, fallback []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return fallback
+	}
+	return values
+}
+
+// getEnvDuration читает длительность в формате time.ParseDuration ("15m", "72h"),
+// возвращая значение по умолчанию, если переменная не задана или некорректна
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
+
+// getEnvInt читает целое число, возвращая значение по умолчанию, если переменная не задана или некорректна
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
 	}
+	return value
 }
@@ -15,38 +15,70 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/consumers/token/": {
-            "post": {
-                "description": "Регистрирует или возвращает токен для пользователя по Telegram ID",
-                "consumes": [
-                    "application/json"
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Возвращает записи журнала аудита, новые сверху: отказы публичных эндпоинтов (неверный ключ бота, блоклист, превышение лимита) и изменения блоклиста. Об отказах по одному IP или telegram_id пишется не больше одной записи за окно лимита. Требуется право operators.manage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Получить JWT-токен для пользователя",
+                "summary": "Журнал аудита",
                 "parameters": [
                     {
-                        "description": "Telegram ID пользователя",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.TokenInput"
-                        }
+                        "type": "string",
+                        "description": "Действие, например public.rate_limited",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP",
+                        "name": "ip",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Telegram ID",
+                        "name": "telegram_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "access: JWT-токен",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/handlers.auditListResponse"
                         }
                     },
                     "400": {
@@ -58,36 +90,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
-            }
-        },
-        "/logout/": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Подтверждает выход оператора; клиент должен удалить токен",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "auth"
-                ],
-                "summary": "Выход оператора",
-                "responses": {
-                    "200": {
-                        "description": "message: Успешный выход",
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -95,8 +108,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -107,28 +120,28 @@ const docTemplate = `{
                 }
             }
         },
-        "/tickets/": {
+        "/admin/operators/": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает все тикеты для оператора или тикеты текущего пользователя",
+                "description": "Возвращает всех операторов, включая отключённых. Требуется право operators.manage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "admin"
                 ],
-                "summary": "Получить список тикетов",
+                "summary": "Список операторов",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Ticket"
+                                "$ref": "#/definitions/models.Operator"
                             }
                         }
                     },
@@ -141,6 +154,15 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -151,16 +173,14 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/tickets/create": {
+            },
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создает тикет от текущего пользователя",
+                "description": "Создаёт учётную запись оператора с одной из существующих ролей. Требуется право operators.manage",
                 "consumes": [
                     "application/json"
                 ],
@@ -168,17 +188,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "admin"
                 ],
-                "summary": "Создать новый тикет",
+                "summary": "Создать оператора",
                 "parameters": [
                     {
-                        "description": "Данные тикета",
-                        "name": "ticket",
+                        "description": "Данные оператора",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.createTicketInput"
+                            "$ref": "#/definitions/handlers.createOperatorInput"
                         }
                     }
                 ],
@@ -186,7 +206,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Ticket"
+                            "$ref": "#/definitions/models.Operator"
                         }
                     },
                     "400": {
@@ -207,6 +227,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -219,26 +257,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/tickets/{ticket_id}/close/": {
+        "/admin/operators/{id}/disable": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Закрывает указанный тикет",
+                "description": "Запрещает оператору вход и отзывает все его сессии. Последнего активного сотрудника с правом operators.manage отключить нельзя",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tickets"
+                    "admin"
                 ],
-                "summary": "Закрыть тикет",
+                "summary": "Отключить оператора",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID тикета",
-                        "name": "ticket_id",
+                        "type": "integer",
+                        "description": "ID оператора",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -247,7 +285,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Ticket"
+                            "$ref": "#/definitions/models.Operator"
                         }
                     },
                     "400": {
@@ -298,26 +336,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/tickets/{ticket_id}/messages/": {
-            "get": {
+        "/admin/operators/{id}/enable": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает все сообщения для указанного тикета",
+                "description": "Снова разрешает отключённому оператору вход",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Получить историю сообщений тикета",
+                "summary": "Включить оператора",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID тикета",
-                        "name": "ticket_id",
+                        "type": "integer",
+                        "description": "ID оператора",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -326,10 +364,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Message"
-                            }
+                            "$ref": "#/definitions/models.Operator"
                         }
                     },
                     "401": {
@@ -369,14 +404,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/operators/{id}/password": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Добавляет сообщение в указанный тикет",
+                "description": "Устанавливает оператору новый пароль и отзывает все его сессии",
                 "consumes": [
                     "application/json"
                 ],
@@ -384,32 +421,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "messages"
+                    "admin"
                 ],
-                "summary": "Добавить сообщение в тикет",
+                "summary": "Сбросить пароль оператора",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID тикета",
-                        "name": "ticket_id",
+                        "type": "integer",
+                        "description": "ID оператора",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Данные сообщения",
-                        "name": "message",
+                        "description": "Новый пароль",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.addMessageInput"
+                            "$ref": "#/definitions/handlers.operatorPasswordInput"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "message",
                         "schema": {
-                            "$ref": "#/definitions/models.Message"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
@@ -460,9 +500,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/token/": {
-            "post": {
-                "description": "Авторизует оператора по логину и паролю, возвращает JWT-токен",
+        "/admin/operators/{id}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Назначает оператору одну из существующих ролей и отзывает его сессии, чтобы новая роль попала в токен. Нельзя отобрать право operators.manage у последнего активного сотрудника, у которого оно есть",
                 "consumes": [
                     "application/json"
                 ],
@@ -470,28 +515,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Логин оператора",
+                "summary": "Сменить роль оператора",
                 "parameters": [
                     {
-                        "description": "Данные оператора",
+                        "type": "integer",
+                        "description": "ID оператора",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
                         "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.OperatorLoginInput"
+                            "$ref": "#/definitions/handlers.operatorRoleInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "access: JWT-токен",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/models.Operator"
                         }
                     },
                     "400": {
@@ -512,6 +561,24 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -524,33 +591,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/whitelist": {
+        "/admin/permissions": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает все записи whitelist со статусом \"Pending\" (permission = 0)",
+                "description": "Возвращает все права, которые можно выдать роли. Требуется право operators.manage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "whitelist"
+                    "admin"
                 ],
-                "summary": "Получить список заявок в ожидании",
+                "summary": "Список прав",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Whitelist"
+                                "type": "string"
                             }
                         }
                     },
-                    "500": {
-                        "description": "error",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -559,54 +635,44 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/admin/roles/": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Добавляет новую заявку в whitelist со статусом \"Pending\" (permission = 0). Если заявка уже существует (по telegram_id и from), возвращает 200 OK.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает роли сотрудников вместе с их правами. Требуется право operators.manage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "whitelist"
-                ],
-                "summary": "Создать новую заявку в whitelist",
-                "parameters": [
-                    {
-                        "description": "Данные заявки",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.WhitelistRequestInput"
-                        }
-                    }
+                    "admin"
                 ],
+                "summary": "Список ролей",
                 "responses": {
                     "200": {
-                        "description": "message: Запрос уже существует",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.roleResponse"
                             }
                         }
                     },
-                    "201": {
-                        "description": "message: Запрос создан, id: \u003cid\u003e",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "error",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -615,7 +681,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -624,35 +690,80 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/whitelist/all": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает все записи whitelist независимо от статуса",
+                "description": "Создаёт роль с заданным набором прав, например аудитора только с tickets.read_all. Требуется право operators.manage",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "whitelist"
+                    "admin"
+                ],
+                "summary": "Создать роль",
+                "parameters": [
+                    {
+                        "description": "Роль",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createRoleInput"
+                        }
+                    }
                 ],
-                "summary": "Получить все записи whitelist",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Whitelist"
+                            "$ref": "#/definitions/handlers.roleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
                     "500": {
-                        "description": "error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -663,14 +774,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/whitelist/{telegram_id}/edit": {
-            "post": {
+        "/admin/roles/{name}": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Изменяет статус заявки (Approved = 1 или Denied = 2) для указанного Telegram ID",
+                "description": "Меняет описание роли и заменяет её набор прав целиком. Изменения действуют сразу, без перевыпуска токенов. Нельзя отобрать право operators.manage, если после этого не останется ни одного активного сотрудника с ним. Требуется право operators.manage",
                 "consumes": [
                     "application/json"
                 ],
@@ -678,30 +789,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "whitelist"
+                    "admin"
                 ],
-                "summary": "Обновить статус заявки в whitelist",
+                "summary": "Изменить роль",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Telegram ID пользователя",
-                        "name": "telegram_id",
+                        "description": "Имя роли",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Новое значение разрешения",
-                        "name": "permission",
+                        "description": "Роль",
+                        "name": "input",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.WhitelistEditInput"
+                            "$ref": "#/definitions/handlers.updateRoleInput"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "message: OK",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.roleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -709,8 +826,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "400": {
-                        "description": "error",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -719,7 +845,7 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "error",
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -728,7 +854,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "error",
+                        "description": "Internal Server Error",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -737,188 +863,5401 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handlers.OperatorLoginInput": {
-            "type": "object",
-            "required": [
-                "password",
-                "username"
-            ],
-            "properties": {
-                "password": {
-                    "type": "string",
-                    "example": "securepassword"
-                },
-                "username": {
-                    "type": "string",
-                    "example": "operator1"
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет роль, если она не встроенная и ни один оператор её не использует. Требуется право operators.manage",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Удалить роль",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя роли",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "handlers.TokenInput": {
-            "type": "object",
-            "required": [
-                "telegram_id"
-            ],
-            "properties": {
-                "telegram_id": {
-                    "type": "string",
-                    "example": "88376478"
+        "/channels/email/inbound": {
+            "post": {
+                "description": "Принимает письмо целиком (RFC 5322, MIME) — например, от почтового сервера через pipe или от почтового провайдера. Отправитель находится или создаётся по адресу From. Письмо продолжает тикет отправителя, если In-Reply-To/References ссылаются на письма тикета, а для отправителя, подтверждённого почтовым релеем (Authentication-Results с authserv-id из EMAIL_AUTHSERV_ID), — и по метке [#\u003cshort_id\u003e] в теме; закрытый тикет переоткрывается. Иначе открывается новый тикет с source=email. SPF/DKIM/DMARC сервис сам не проверяет — это задача релея. Повтор письма с тем же Message-ID и автоответы пропускаются. Требуется заголовок X-Email-Ingest-Token",
+                "consumes": [
+                    "message/rfc822"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "channels"
+                ],
+                "summary": "Приём входящего письма",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен приёма писем",
+                        "name": "X-Email-Ingest-Token",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.emailIngestResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Email channel is disabled",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
                 }
             }
         },
-        "handlers.WhitelistEditInput": {
-            "type": "object",
-            "required": [
-                "perm"
-            ],
+        "/channels/telegram/{stand}/webhook": {
+            "post": {
+                "description": "Принимает обновления (Update) от Telegram Bot API для бота стенда. Подлинность проверяется по заголовку X-Telegram-Bot-Api-Secret-Token. Личное сообщение пользователя продолжает его активный тикет на стенде (не resolved и не closed) или открывает новый; пользователь создаётся по from.id. Правки, сообщения в группах и от ботов пропускаются, повтор того же update_id — тоже. При политике whitelist сообщения принимаются только от пользователей с одобренным доступом к стенду",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "channels"
+                ],
+                "summary": "Webhook бота Telegram",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Секрет webhook",
+                        "name": "X-Telegram-Bot-Api-Secret-Token",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновление Telegram",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/telegram.Update"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пустой ответ или вызов sendMessage",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.telegramReply"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/consumers/token/": {
+            "post": {
+                "description": "Регистрирует или возвращает пару токенов (access + refresh) для пользователя по Telegram ID (положительное число без ведущих нулей). Стенд записывается в токен (claim stand), и тикеты пользователя создаются для этого стенда. При политике CONSUMER_TOKEN_POLICY=whitelist стенд обязателен, а токен выдаётся, только если доступ пользователя к стенду одобрен в whitelist; после отклонения или отзыва доступа токены для стенда перестают действовать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Получить JWT-токен для пользователя",
+                "parameters": [
+                    {
+                        "description": "Telegram ID пользователя и стенд",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/events/stream": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отдаёт те же события, что и WebSocket (ticket.created, message.created, ticket.status_changed, ticket.assigned; сотрудникам также note.created и адресованные им operator.mentioned), в формате text/event-stream. Пользователь получает события только своих тикетов, оператор — всех. Параметр ticket_id сужает поток до перечисленных тикетов. При переподключении клиент передаёт заголовок Last-Event-ID (или параметр last_event_id) и получает пропущенные события из журнала",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "events"
+                ],
+                "summary": "Поток событий тикетов (Server-Sent Events)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикетов через запятую",
+                        "name": "ticket_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события, если нельзя передать заголовок",
+                        "name": "last_event_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID последнего полученного события",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поток событий",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/logout/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает текущий токен пользователя или оператора; после выхода токен больше не принимается. Если в теле передан refresh-токен, отзывается и вся его цепочка",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Выход",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен текущей сессии",
+                        "name": "input",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.logoutInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Успешный выход",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/blocklist/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает заблокированные telegram_id и IP, включая истёкшие блокировки",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocklist"
+                ],
+                "summary": "Блоклист публичных эндпоинтов",
+                "parameters": [
+                    {
+                        "enum": [
+                            "telegram_id",
+                            "ip"
+                        ],
+                        "type": "string",
+                        "description": "Вид записи",
+                        "name": "kind",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.BlocklistEntry"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Запрещает telegram_id, IP-адресу или подсети (CIDR) подавать заявки в whitelist и получать токен пользователя. Отказы заблокированным пишутся в журнал аудита. Блокировка действует на всех экземплярах сервиса в течение 30 секунд",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocklist"
+                ],
+                "summary": "Заблокировать telegram_id или IP",
+                "parameters": [
+                    {
+                        "description": "Блокировка",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createBlocklistInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.BlocklistEntry"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/blocklist/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет запись из блоклиста",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocklist"
+                ],
+                "summary": "Снять блокировку",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID записи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/notifications/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает уведомления оператора, новые сверху, и число непрочитанных",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Уведомления текущего оператора",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "Только непрочитанные",
+                        "name": "unread",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.notificationListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/notifications/read-all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Отметить все уведомления прочитанными",
+                "responses": {
+                    "200": {
+                        "description": "Сколько уведомлений отмечено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/notifications/{id}/read": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Отметить уведомление прочитанным",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID уведомления",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Notification"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/outbox/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает сообщения outbox, по умолчанию — недоставленные (dead), у которых исчерпаны попытки. В last_error — причина последней неудачи. Ответы пользователям (ticket.reply, email.reply) видны только с правом tickets.read_all",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "Список исходящих уведомлений",
+                "parameters": [
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Статус сообщения (по умолчанию dead)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип сообщения",
+                        "name": "kind",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.outboxListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/outbox/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает в очередь все dead-сообщения, например после восстановления стенда. Параметр stand ограничивает переотправку одним стендом. Без права tickets.read_all переотправляются только уведомления стендов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "Переотправить все недоставленные уведомления",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message, count",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/outbox/{id}/retry": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает недоставленное (dead) сообщение в очередь с обнулённым счётчиком попыток. Ответы пользователям переотправляются только с правом tickets.read_all",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "outbox"
+                ],
+                "summary": "Переотправить уведомление",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID сообщения outbox",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/search": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ищет по теме и описанию тикетов, по тексту сообщений и внутренних заметок (русская морфология PostgreSQL). Запрос q поддерживает синтаксис websearch: \"фраза в кавычках\", OR, -исключение. Результаты отсортированы по релевантности и фильтруются теми же параметрами, что и список тикетов",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Полнотекстовый поиск по тикетам и сообщениям",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статусы через запятую",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Источник тикета",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dev",
+                            "ift",
+                            "psi",
+                            "prom"
+                        ],
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тикет создан не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тикет создан не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тикет обновлён не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тикет обновлён не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.searchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/sessions/revoke-all": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отзывает все токены текущего оператора, включая тот, с которым выполнен запрос",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Завершить все сессии оператора",
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/settings/telegram/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает подключённые боты Telegram. Токены ботов и секреты webhook не отдаются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Боты Telegram стендов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.TelegramChannel"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/settings/telegram/{stand}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Проверяет токен бота, генерирует новый секрет webhook и регистрирует webhook в Bot API (setWebhook). После этого сообщения боту превращаются в тикеты стенда. Повторный вызов меняет бота или адрес webhook и заодно секрет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Подключить бота Telegram к стенду",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Бот",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.telegramChannelInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.TelegramChannel"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bot API error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Снимает webhook в Bot API (deleteWebhook) и удаляет настройки бота. Если Bot API недоступен, бот всё равно отключается: обновления с его старым секретом больше не принимаются",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Отключить бота Telegram от стенда",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/settings/{id}/secret": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Генерирует новый секрет для HMAC-подписи запросов к стенду и возвращает его. Секрет больше нигде не показывается, его нужно сразу передать на стенд: запросы, подписанные старым секретом, стенд отвергнет",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "settings"
+                ],
+                "summary": "Сменить секрет стенда",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID стенда",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.endpointSecretResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/templates/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает шаблоны сообщений о решениях по заявкам whitelist",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Список шаблонов сообщений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "approve",
+                            "deny",
+                            "revoke"
+                        ],
+                        "type": "string",
+                        "description": "Решение",
+                        "name": "decision",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.MessageTemplate"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт шаблон text/template для стенда и решения. В шаблоне доступны поля заявки: {{.FirstName}}, {{.LastName}}, {{.Username}}, {{.TelegramID}}, {{.From}}, {{.Text}}, а также {{.Decision}}",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Создать шаблон сообщения",
+                "parameters": [
+                    {
+                        "description": "Шаблон",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createTemplateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.MessageTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/templates/preview": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отрисовывает шаблон на реальной заявке whitelist. Если body пуст, используется шаблон, который сработает для стенда заявки: шаблон стенда, затем шаблон по умолчанию, затем встроенный текст. Если шаблон не отрисовался на этой заявке, показывается встроенный текст и template_id пуст — так же уйдёт и уведомление",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Предпросмотр шаблона сообщения",
+                "parameters": [
+                    {
+                        "description": "Заявка, решение и, при необходимости, черновик шаблона",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.previewTemplateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.previewTemplateResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/templates/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменяет текст шаблона",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Изменить шаблон сообщения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст шаблона",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.updateTemplateInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.MessageTemplate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет шаблон; после этого для стенда действует шаблон по умолчанию",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "templates"
+                ],
+                "summary": "Удалить шаблон сообщения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID шаблона",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/ticket/{ticket_id}/claim/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Назначает тикет на текущего оператора, если он ещё никому не назначен. При одновременных запросах тикет достаётся только одному оператору",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Взять тикет в работу",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/ticket/{ticket_id}/notes/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет в переписку тикета заметку, видимую только сотрудникам: пользователю она не возвращается ни в истории, ни в событиях и не доставляется в его канал. Упомянутые через @username операторы с правом tickets.read_all получают уведомление (см. /operator/notifications/) и событие operator.mentioned — только сам упомянутый оператор, даже если он не подписан на тикет. Требуется право tickets.reply",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Добавить внутреннюю заметку к тикету",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Текст заметки",
+                        "name": "note",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.addNoteInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/ticket/{ticket_id}/release/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Снимает назначение тикета с текущего оператора. Сотрудник с правом tickets.assign_any может освободить тикет, назначенный на любого оператора",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Освободить тикет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/ticket/{ticket_id}/transfer/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Переназначает свой или неназначенный тикет на другого оператора. Сотрудник с правом tickets.assign_any может переназначить любой тикет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "operator"
+                ],
+                "summary": "Передать тикет другому оператору",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Оператор-получатель",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transferTicketInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает страницу заявок whitelist со статусом \"pending\", новые сверху. Фильтры и поиск — как у /operator/whitelist/all, параметр permission игнорируется",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Получить список ожидающих заявок whitelist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенды через запятую, например ift,prom",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.whitelistListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist-rules/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает правила в порядке проверки: по возрастанию priority, затем по ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist-rules"
+                ],
+                "summary": "Список правил автоматического решения",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WhitelistRule"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт правило, которое одобряет или отклоняет новые заявки whitelist. Условия: стенд, регулярное выражение для username, список Telegram ID, language_code, наличие действующего одобренного доступа к другому стенду. Заданные условия объединяются по И, нужно хотя бы одно",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist-rules"
+                ],
+                "summary": "Создать правило автоматического решения",
+                "parameters": [
+                    {
+                        "description": "Правило",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.whitelistRuleInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.WhitelistRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist-rules/{id}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Заменяет правило целиком. Уже принятые по нему решения не пересматриваются",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist-rules"
+                ],
+                "summary": "Изменить правило автоматического решения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Правило",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.whitelistRuleInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WhitelistRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет правило. В истории решений остаётся его ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist-rules"
+                ],
+                "summary": "Удалить правило автоматического решения",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID правила",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/all": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает страницу заявок whitelist, новые сверху, с фильтрами по стенду, статусу и дате создания и поиском по тексту",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Получить все записи whitelist",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Стенды через запятую, например ift,prom",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статусы через запятую, например approve,revoke",
+                        "name": "permission",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.whitelistListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Одобряет, отклоняет или отзывает доступ по списку ID заявок либо по всем заявкам, подходящим под фильтр (стенд, language_code, создана до). Каждая заявка обрабатывается в своей транзакции, в ответе — результат по каждой. Уведомления стендов ставятся в outbox и доставляются в фоне. С dry_run=true ничего не меняется, а в ответе видно, что было бы изменено",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Массовое решение по заявкам whitelist",
+                "parameters": [
+                    {
+                        "description": "Решение и отбор заявок",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WhitelistBulkInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.whitelistBulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/export": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Выгружает все заявки whitelist, подходящие под фильтры (те же, что у /operator/whitelist/all, без пагинации), вместе с последним решением по каждой: кто и когда его принял и с каким комментарием",
+                "produces": [
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Выгрузка whitelist в CSV или XLSX",
+                "parameters": [
+                    {
+                        "enum": [
+                            "csv",
+                            "xlsx"
+                        ],
+                        "type": "string",
+                        "description": "Формат файла (по умолчанию csv)",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Стенды через запятую, например ift,prom",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Статусы через запятую, например approve,revoke",
+                        "name": "permission",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создана не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id",
+                        "name": "q",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/user/{telegram_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все заявки whitelist пользователя с указанным telegram_id, по одной на стенд",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Заявки пользователя на все стенды",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telegram ID пользователя",
+                        "name": "telegram_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Whitelist"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/user/{telegram_id}/{stand}/edit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "То же, что /operator/whitelist/{id}/edit, но заявка выбирается по паре telegram_id и стенд. У пользователя может быть по заявке на каждый стенд, решение затрагивает только указанный",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Изменить статус заявки пользователя на стенде",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Telegram ID пользователя",
+                        "name": "telegram_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "dev",
+                            "ift",
+                            "psi",
+                            "prom"
+                        ],
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новое значение permission",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WhitelistEditInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/{id}/edit": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Обновляет статус заявки в whitelist по её ID: \"approve\" (при желании со сроком expires_at), \"deny\" или \"revoke\" для ранее одобренной заявки. Решение с комментарием записывается в историю, уведомление стенда с текстом из шаблона сообщений ставится в outbox и доставляется в фоне с повторами",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Изменить статус заявки в whitelist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки whitelist",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новое значение permission",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WhitelistEditInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/operator/whitelist/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает журнал решений по заявке: кто и когда одобрил, отклонил или отозвал доступ, с комментарием. Решения, принятые автоматически по истечении срока, записаны от имени \"system\"",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "История решений по заявке whitelist",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки whitelist",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WhitelistDecision"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает страницу тикетов: все тикеты для оператора или тикеты текущего пользователя. Поддерживает фильтры, сортировку и курсорную пагинацию; следующая страница запрашивается с параметром cursor из поля next_cursor. Фильтры user_id и assignee доступны только операторам",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Получить список тикетов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Статусы через запятую, например open,pending_user",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Источник тикета",
+                        "name": "source",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя (только для операторов)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "dev",
+                            "ift",
+                            "psi",
+                            "prom"
+                        ],
+                        "type": "string",
+                        "description": "Стенд",
+                        "name": "stand",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Создан не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "created_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлён не раньше (RFC3339 или YYYY-MM-DD)",
+                        "name": "updated_from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Обновлён не позже (RFC3339 или YYYY-MM-DD)",
+                        "name": "updated_to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Назначение: me, none или ID оператора (только для операторов)",
+                        "name": "assignee",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "updated_at",
+                            "last_message_at"
+                        ],
+                        "type": "string",
+                        "description": "Поле сортировки",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Направление сортировки",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Курсор следующей страницы; действует только с теми же sort и order",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ticketListResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/create": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создает тикет от текущего пользователя. Если токен выдан для стенда, тикет создаётся для этого стенда, и поле stand можно не передавать",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Создать новый тикет",
+                "parameters": [
+                    {
+                        "description": "Данные тикета",
+                        "name": "ticket",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.createTicketInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{ticket_id}/attachments/{attachment_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Отдаёт файл, приложенный к сообщению тикета. Доступ — как к истории тикета: владельцу тикета (кроме вложений внутренних заметок) и сотрудникам с правом tickets.read_all",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Скачать вложение сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID вложения",
+                        "name": "attachment_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{ticket_id}/close/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Закрывает указанный тикет",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Закрыть тикет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{ticket_id}/messages/": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает все сообщения для указанного тикета вместе с описанием вложений. Сотрудникам возвращаются и внутренние заметки (internal=true) вперемешку с перепиской, в порядке времени; пользователю — только переписка. сами файлы скачиваются через /tickets/{ticket_id}/attachments/{attachment_id}",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Получить историю сообщений тикета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Message"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет сообщение в указанный тикет. Принимает JSON или multipart/form-data с полями sender, recipient, content и файлами в поле files; число, размер и типы файлов ограничены настройками ATTACHMENT_*, тип определяется по содержимому. Вложения не пересылаются в чат или на почту пользователя, поэтому ответ, уходящий пользователю, должен содержать текст. Ответ оператора пользователю (sender=operator, recipient=user) ставится в очередь доставки: в чат пользователя через бота стенда или на URL стенда, а по тикетам из почты — письмом в ту же цепочку; ход доставки виден в полях delivery_status (queued, sent, failed), delivery_attempts и delivery_error сообщения",
+                "consumes": [
+                    "application/json",
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Добавить сообщение в тикет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Данные сообщения",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.addMessageInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Message"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{ticket_id}/reopen/": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Переоткрывает решённый или закрытый тикет. Пользователь может переоткрыть только свой тикет",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Переоткрыть тикет",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tickets/{ticket_id}/transition": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Переводит тикет в новый статус согласно таблице допустимых переходов (new, open, pending_user, pending_operator, resolved, closed, reopened). Пользователь может только закрыть или переоткрыть свой тикет",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tickets"
+                ],
+                "summary": "Сменить статус тикета",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID тикета",
+                        "name": "ticket_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новый статус",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.transitionTicketInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Ticket"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/token/": {
+            "post": {
+                "description": "Авторизует оператора по логину и паролю, возвращает пару токенов (access + refresh)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Логин оператора",
+                "parameters": [
+                    {
+                        "description": "Данные оператора",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.OperatorLoginInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/token/refresh/": {
+            "post": {
+                "description": "Обменивает refresh-токен пользователя или оператора на новую пару токенов. Refresh-токен одноразовый: повторное предъявление уже использованного токена считается кражей и отзывает всю цепочку сессии",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Обновить пару токенов",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/whitelist": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Добавляет новую заявку в whitelist со статусом \"pending\" и проверяет её по правилам автоматического решения: если сработало правило, заявка сразу одобряется или отклоняется, итог — в поле permission. Если заявка уже существует (по telegram_id и from), возвращает 200 OK.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "whitelist"
+                ],
+                "summary": "Создать новую заявку в whitelist",
+                "parameters": [
+                    {
+                        "description": "Данные заявки",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WhitelistRequestInput"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "message: Запрос уже существует",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "201": {
+                        "description": "message: Запрос создан, id: \u003cid\u003e, permission: \u003cитоговый статус\u003e",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/ws": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Открывает WebSocket-соединение для получения событий ticket.created, message.created, ticket.status_changed и ticket.assigned, а сотрудникам также note.created (внутренние заметки) и адресованные им operator.mentioned. Токен передаётся в заголовке Authorization или параметром access_token. После подключения клиент отправляет команды {\"action\":\"subscribe\",\"ticket_id\":42}, {\"action\":\"unsubscribe\",\"ticket_id\":42}, а оператор также {\"action\":\"subscribe_all\"}. Пользователь может подписаться только на свои тикеты",
+                "tags": [
+                    "events"
+                ],
+                "summary": "Поток событий тикетов по WebSocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "JWT-токен, если заголовок Authorization недоступен",
+                        "name": "access_token",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.OperatorLoginInput": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "securepassword"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "operator1"
+                }
+            }
+        },
+        "handlers.RefreshInput": {
+            "type": "object",
+            "required": [
+                "refresh"
+            ],
+            "properties": {
+                "refresh": {
+                    "type": "string",
+                    "example": "3q2-7w..."
+                }
+            }
+        },
+        "handlers.TokenInput": {
+            "type": "object",
+            "required": [
+                "telegram_id"
+            ],
+            "properties": {
+                "stand": {
+                    "description": "Стенд; обязателен при политике whitelist",
+                    "type": "string",
+                    "enum": [
+                        "dev",
+                        "ift",
+                        "psi",
+                        "prom"
+                    ],
+                    "example": "ift"
+                },
+                "telegram_id": {
+                    "description": "Только цифры",
+                    "type": "string",
+                    "example": "88376478"
+                }
+            }
+        },
+        "handlers.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access": {
+                    "description": "Короткоживущий JWT",
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "Срок жизни access-токена в секундах",
+                    "type": "integer"
+                },
+                "refresh": {
+                    "description": "Одноразовый refresh-токен для получения новой пары",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.WhitelistBulkFilter": {
+            "type": "object",
+            "properties": {
+                "created_before": {
+                    "type": "string",
+                    "example": "2026-10-01T00:00:00Z"
+                },
+                "language_code": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "permission": {
+                    "description": "Текущий статус заявок, по умолчанию pending",
+                    "type": "string",
+                    "enum": [
+                        "pending",
+                        "approve",
+                        "deny",
+                        "revoke"
+                    ],
+                    "example": "pending"
+                },
+                "stand": {
+                    "type": "string",
+                    "example": "ift"
+                }
+            }
+        },
+        "handlers.WhitelistBulkInput": {
+            "type": "object",
+            "required": [
+                "permission"
+            ],
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "dry_run": {
+                    "description": "Только показать, что изменится",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "filter": {
+                    "$ref": "#/definitions/handlers.WhitelistBulkFilter"
+                },
+                "ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "permission": {
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "deny",
+                        "revoke"
+                    ]
+                }
+            }
+        },
+        "handlers.WhitelistEditInput": {
+            "type": "object",
+            "required": [
+                "permission"
+            ],
+            "properties": {
+                "comment": {
+                    "description": "Причина решения, попадает в историю",
+                    "type": "string",
+                    "example": "Согласовано с руководителем"
+                },
+                "expires_at": {
+                    "description": "Срок действия одобрения; после него доступ отзывается автоматически",
+                    "type": "string",
+                    "example": "2026-12-31T23:59:59Z"
+                },
+                "permission": {
+                    "description": "\"approve\", \"deny\" или \"revoke\"",
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "deny",
+                        "revoke"
+                    ]
+                }
+            }
+        },
+        "handlers.WhitelistRequestInput": {
+            "type": "object",
+            "required": [
+                "chatId",
+                "from",
+                "text",
+                "user"
+            ],
+            "properties": {
+                "chatId": {
+                    "type": "integer"
+                },
+                "from": {
+                    "type": "string",
+                    "enum": [
+                        "dev",
+                        "ift",
+                        "psi",
+                        "prom"
+                    ]
+                },
+                "text": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "object",
+                    "required": [
+                        "first_name",
+                        "id",
+                        "last_name",
+                        "username"
+                    ],
+                    "properties": {
+                        "first_name": {
+                            "type": "string"
+                        },
+                        "id": {
+                            "type": "integer"
+                        },
+                        "is_bot": {
+                            "type": "boolean"
+                        },
+                        "language_code": {
+                            "type": "string"
+                        },
+                        "last_name": {
+                            "type": "string"
+                        },
+                        "username": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "handlers.addMessageInput": {
+            "type": "object",
+            "required": [
+                "recipient",
+                "sender"
+            ],
+            "properties": {
+                "content": {
+                    "description": "Может быть пустым, если приложены файлы",
+                    "type": "string",
+                    "example": "Сообщение"
+                },
+                "recipient": {
+                    "type": "string",
+                    "example": "operator"
+                },
+                "sender": {
+                    "type": "string",
+                    "example": "user"
+                }
+            }
+        },
+        "handlers.addNoteInput": {
+            "type": "object",
+            "required": [
+                "content"
+            ],
+            "properties": {
+                "content": {
+                    "type": "string",
+                    "example": "@petrov глянь, похоже на тот же баг, что вчера"
+                }
+            }
+        },
+        "handlers.auditListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditLog"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.createBlocklistInput": {
+            "type": "object",
+            "required": [
+                "kind",
+                "value"
+            ],
+            "properties": {
+                "expires_at": {
+                    "description": "Пусто — бессрочно",
+                    "type": "string",
+                    "example": "2026-12-31T23:59:59Z"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "telegram_id",
+                        "ip"
+                    ],
+                    "example": "telegram_id"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Спам заявками"
+                },
+                "value": {
+                    "description": "telegram_id, IP-адрес или подсеть CIDR",
+                    "type": "string",
+                    "example": "88376478"
+                }
+            }
+        },
+        "handlers.createOperatorInput": {
+            "type": "object",
+            "required": [
+                "password",
+                "role",
+                "username"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "s3cure-passw0rd"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "operator"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "ivanov"
+                }
+            }
+        },
+        "handlers.createRoleInput": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Только просмотр тикетов"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "auditor"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tickets.read_all"
+                    ]
+                }
+            }
+        },
+        "handlers.createTemplateInput": {
+            "type": "object",
+            "required": [
+                "body",
+                "decision"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "{{.FirstName}}, доступ к стенду {{.From}} открыт"
+                },
+                "decision": {
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "deny",
+                        "revoke"
+                    ],
+                    "example": "approve"
+                },
+                "stand": {
+                    "description": "Пустой стенд — шаблон по умолчанию",
+                    "type": "string",
+                    "enum": [
+                        "dev",
+                        "ift",
+                        "psi",
+                        "prom"
+                    ],
+                    "example": "ift"
+                }
+            }
+        },
+        "handlers.createTicketInput": {
+            "type": "object",
+            "required": [
+                "description",
+                "source",
+                "subject"
+            ],
+            "properties": {
+                "description": {
+                    "description": "Описание проблемы",
+                    "type": "string",
+                    "example": "Описание проблемы..."
+                },
+                "source": {
+                    "description": "Источник тикета",
+                    "type": "string",
+                    "example": "Telegram"
+                },
+                "stand": {
+                    "description": "Стенд, с которого пришёл пользователь",
+                    "type": "string",
+                    "enum": [
+                        "dev",
+                        "ift",
+                        "psi",
+                        "prom"
+                    ],
+                    "example": "ift"
+                },
+                "subject": {
+                    "description": "Тема тикета",
+                    "type": "string",
+                    "example": "Проблема с продуктом"
+                }
+            }
+        },
+        "handlers.emailIngestResponse": {
+            "type": "object",
+            "properties": {
+                "message_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "description": "created, appended, duplicate или ignored",
+                    "type": "string",
+                    "example": "created"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.endpointSecretResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "ift"
+                },
+                "secret": {
+                    "type": "string",
+                    "example": "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+                }
+            }
+        },
+        "handlers.logoutInput": {
+            "type": "object",
+            "properties": {
+                "refresh": {
+                    "description": "Refresh-токен сессии; его цепочка тоже будет отозвана",
+                    "type": "string",
+                    "example": "3q2-7w..."
+                }
+            }
+        },
+        "handlers.notificationListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Notification"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "unread": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.operatorPasswordInput": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "n3w-passw0rd"
+                }
+            }
+        },
+        "handlers.operatorRoleInput": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "supervisor"
+                }
+            }
+        },
+        "handlers.outboxListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.OutboxMessage"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.previewTemplateInput": {
+            "type": "object",
+            "required": [
+                "decision",
+                "whitelist_id"
+            ],
+            "properties": {
+                "body": {
+                    "description": "Черновик; если пуст, берётся действующий шаблон стенда заявки",
+                    "type": "string",
+                    "example": "{{.FirstName}}, доступ открыт"
+                },
+                "decision": {
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "deny",
+                        "revoke"
+                    ],
+                    "example": "approve"
+                },
+                "whitelist_id": {
+                    "type": "integer",
+                    "example": 12
+                }
+            }
+        },
+        "handlers.previewTemplateResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "template_id": {
+                    "description": "ID использованного шаблона; null для черновика или встроенного текста",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.roleResponse": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Только просмотр тикетов"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "auditor"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tickets.read_all"
+                    ]
+                }
+            }
+        },
+        "handlers.searchHit": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "rank": {
+                    "type": "number"
+                },
+                "short_id": {
+                    "type": "string"
+                },
+                "snippet": {
+                    "description": "Фрагмент текста в виде HTML: текст экранирован, совпадения выделены тегами \u003cb\u003e\u003c/b\u003e",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "subject": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "\"ticket\", \"message\" или \"note\" (внутренняя заметка)",
+                    "type": "string",
+                    "example": "message"
+                }
+            }
+        },
+        "handlers.searchResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.searchHit"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.telegramChannelInput": {
+            "type": "object",
+            "required": [
+                "bot_token",
+                "webhook_url"
+            ],
             "properties": {
-                "perm": {
-                    "type": "boolean"
+                "bot_token": {
+                    "description": "Токен бота от @BotFather",
+                    "type": "string",
+                    "example": "123456789:AAE..."
+                },
+                "webhook_url": {
+                    "description": "Публичный адрес webhook этого стенда",
+                    "type": "string",
+                    "example": "https://helpdesk.example.com/api/channels/telegram/ift/webhook"
                 }
             }
         },
-        "handlers.WhitelistRequestInput": {
+        "handlers.telegramReply": {
+            "type": "object",
+            "properties": {
+                "chat_id": {
+                    "type": "integer"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ticketListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Ticket"
+                    }
+                },
+                "next_cursor": {
+                    "description": "Пустой, если страниц больше нет",
+                    "type": "string"
+                },
+                "total": {
+                    "description": "Общее число тикетов, подходящих под фильтры",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.transferTicketInput": {
             "type": "object",
             "required": [
-                "chatId",
-                "from",
-                "text",
-                "user"
+                "operator_id"
             ],
             "properties": {
-                "chatId": {
+                "operator_id": {
+                    "description": "ID оператора, которому передаётся тикет",
+                    "type": "integer",
+                    "example": 2
+                }
+            }
+        },
+        "handlers.transitionTicketInput": {
+            "type": "object",
+            "required": [
+                "status"
+            ],
+            "properties": {
+                "status": {
+                    "description": "Новый статус тикета",
+                    "type": "string",
+                    "example": "pending_user"
+                }
+            }
+        },
+        "handlers.updateRoleInput": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Только просмотр тикетов и whitelist"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "tickets.read_all",
+                        "whitelist.read"
+                    ]
+                }
+            }
+        },
+        "handlers.updateTemplateInput": {
+            "type": "object",
+            "required": [
+                "body"
+            ],
+            "properties": {
+                "body": {
+                    "type": "string",
+                    "example": "{{.FirstName}}, доступ к стенду {{.From}} открыт"
+                }
+            }
+        },
+        "handlers.whitelistBulkItem": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "from": {
+                "new_permission": {
+                    "type": "string"
+                },
+                "old_permission": {
+                    "type": "string"
+                },
+                "stand": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "updated, would_update, skipped или failed",
+                    "type": "string"
+                },
+                "telegram_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.whitelistBulkResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.whitelistBulkItem"
+                    }
+                },
+                "skipped": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "description": "При dry_run — сколько заявок было бы изменено",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.whitelistListResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Whitelist"
+                    }
+                },
+                "total": {
+                    "description": "Общее число заявок, подходящих под фильтры",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.whitelistRuleInput": {
+            "type": "object",
+            "required": [
+                "action",
+                "name"
+            ],
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "approve",
+                        "deny"
+                    ],
+                    "example": "approve"
+                },
+                "approved_elsewhere": {
+                    "type": "boolean"
+                },
+                "enabled": {
+                    "description": "По умолчанию true",
+                    "type": "boolean"
+                },
+                "language_code": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "dev для всех"
+                },
+                "priority": {
+                    "type": "integer",
+                    "example": 10
+                },
+                "stand": {
                     "type": "string",
                     "enum": [
                         "dev",
                         "ift",
                         "psi",
                         "prom"
+                    ],
+                    "example": "dev"
+                },
+                "telegram_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "123456789"
                     ]
                 },
-                "text": {
+                "username_pattern": {
+                    "type": "string",
+                    "example": "^qa_"
+                }
+            }
+        },
+        "models.Attachment": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "description": "ContentType определяется по содержимому файла, а не по заявленному клиентом",
+                    "type": "string",
+                    "example": "image/png"
+                },
+                "created_at": {
                     "type": "string"
                 },
-                "user": {
-                    "type": "object",
-                    "required": [
-                        "first_name",
-                        "id",
-                        "last_name",
-                        "username"
-                    ],
-                    "properties": {
-                        "first_name": {
-                            "type": "string"
-                        },
-                        "id": {
-                            "type": "integer"
-                        },
-                        "is_bot": {
-                            "type": "boolean"
-                        },
-                        "language_code": {
-                            "type": "string"
-                        },
-                        "last_name": {
-                            "type": "string"
-                        },
-                        "username": {
-                            "type": "string"
-                        }
+                "filename": {
+                    "type": "string",
+                    "example": "screenshot.png"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "sha256": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 48213
+                },
+                "ticket_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.AuditLog": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Оператор; пусто для анонимных запросов",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "method": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "telegram_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.BlocklistEntry": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Пусто — бессрочно",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "telegram_id или ip",
+                    "type": "string",
+                    "example": "telegram_id"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "Спам заявками"
+                },
+                "value": {
+                    "type": "string",
+                    "example": "88376478"
+                }
+            }
+        },
+        "models.Message": {
+            "type": "object",
+            "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Attachment"
                     }
+                },
+                "content": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "description": "Заменяем gorm.DeletedAt на *time.Time",
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "delivery_attempts": {
+                    "type": "integer"
+                },
+                "delivery_error": {
+                    "type": "string"
+                },
+                "delivery_status": {
+                    "description": "Доставка пользователю: заполняется только для ответов оператора, которые отправляются\nв чат пользователя. Пустой статус — сообщение наружу не отправлялось",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "internal": {
+                    "description": "Internal — внутренняя заметка операторов: пользователю не показывается и никуда не доставляется.\nАвтор заметки — OperatorID",
+                    "type": "boolean"
+                },
+                "operator_id": {
+                    "type": "integer"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "sender": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.addMessageInput": {
+        "models.MessageTemplate": {
             "type": "object",
-            "required": [
-                "content",
-                "recipient",
-                "sender"
-            ],
             "properties": {
-                "content": {
+                "body": {
                     "type": "string",
-                    "example": "Сообщение"
+                    "example": "{{.FirstName}}, доступ к стенду {{.From}} открыт"
                 },
-                "recipient": {
+                "created_at": {
+                    "type": "string"
+                },
+                "decision": {
+                    "description": "approve, deny или revoke",
                     "type": "string",
-                    "example": "operator"
+                    "example": "approve"
                 },
-                "sender": {
+                "id": {
+                    "type": "integer"
+                },
+                "stand": {
                     "type": "string",
-                    "example": "user"
+                    "example": "ift"
+                },
+                "updated_at": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.createTicketInput": {
+        "models.Notification": {
             "type": "object",
-            "required": [
-                "description",
-                "source",
-                "subject"
-            ],
             "properties": {
-                "description": {
-                    "description": "Описание проблемы",
+                "actor": {
+                    "description": "Кто упомянул",
                     "type": "string",
-                    "example": "Описание проблемы..."
+                    "example": "ivanov"
                 },
-                "source": {
-                    "description": "Источник тикета",
-                    "type": "string",
-                    "example": "Telegram"
+                "created_at": {
+                    "type": "string"
                 },
-                "subject": {
-                    "description": "Тема тикета",
+                "id": {
+                    "type": "integer"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "operator_id": {
+                    "description": "Кому адресовано уведомление",
+                    "type": "integer"
+                },
+                "read_at": {
+                    "type": "string"
+                },
+                "ticket_id": {
+                    "type": "integer"
+                },
+                "type": {
                     "type": "string",
-                    "example": "Проблема с продуктом"
+                    "example": "mention"
                 }
             }
         },
-        "models.Message": {
+        "models.Operator": {
             "type": "object",
             "properties": {
-                "content": {
-                    "type": "string"
-                },
                 "created_at": {
                     "type": "string"
                 },
                 "deleted_at": {
-                    "description": "Заменяем gorm.DeletedAt на *time.Time",
                     "type": "string"
                 },
+                "disabled": {
+                    "description": "Отключённый оператор не может войти",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "integer"
                 },
-                "recipient": {
+                "role": {
                     "type": "string"
                 },
-                "sender": {
+                "updated_at": {
                     "type": "string"
                 },
-                "ticket_id": {
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.OutboxMessage": {
+            "type": "object",
+            "properties": {
+                "attempts": {
                     "type": "integer"
                 },
-                "timestamp": {
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "kind": {
+                    "description": "Тип уведомления, по нему выбирается обработчик доставки",
+                    "type": "string"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "string"
+                },
+                "stand": {
+                    "description": "Стенд-получатель, для ограничения параллелизма",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.TelegramChannel": {
+            "type": "object",
+            "properties": {
+                "bot_username": {
+                    "type": "string",
+                    "example": "helpdesk_ift_bot"
+                },
+                "created_at": {
                     "type": "string"
                 },
+                "id": {
+                    "type": "integer"
+                },
+                "stand": {
+                    "type": "string",
+                    "example": "ift"
+                },
                 "updated_at": {
                     "type": "string"
+                },
+                "updated_by": {
+                    "type": "string"
+                },
+                "webhook_url": {
+                    "type": "string",
+                    "example": "https://helpdesk.example.com/api/channels/telegram/ift/webhook"
                 }
             }
         },
         "models.Ticket": {
             "type": "object",
             "properties": {
+                "assigned_at": {
+                    "type": "string"
+                },
+                "assignee": {
+                    "$ref": "#/definitions/models.Operator"
+                },
+                "assignee_id": {
+                    "type": "integer"
+                },
                 "closed_at": {
                     "type": "string"
                 },
@@ -938,12 +6277,19 @@ const docTemplate = `{
                 "id": {
                     "type": "integer"
                 },
+                "last_message_at": {
+                    "description": "LastMessageAt — время последнего сообщения (или создания тикета), используется для сортировки",
+                    "type": "string"
+                },
                 "short_id": {
                     "type": "string"
                 },
                 "source": {
                     "type": "string"
                 },
+                "stand": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
                 },
@@ -973,6 +6319,10 @@ const docTemplate = `{
                 "deleted_at": {
                     "type": "string"
                 },
+                "expires_at": {
+                    "description": "ExpiresAt — когда одобрение истекает; после этого доступ отзывается автоматически",
+                    "type": "string"
+                },
                 "first_name": {
                     "description": "изменено",
                     "type": "string"
@@ -998,7 +6348,8 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "permission": {
-                    "type": "integer"
+                    "description": "\"pending\", \"approve\", \"deny\", \"revoke\"",
+                    "type": "string"
                 },
                 "telegram_id": {
                     "description": "Уникальный индекс",
@@ -1015,6 +6366,177 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "models.WhitelistDecision": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "Срок действия, назначенный при одобрении",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "new_permission": {
+                    "type": "string"
+                },
+                "old_permission": {
+                    "type": "string"
+                },
+                "operator_id": {
+                    "type": "integer"
+                },
+                "operator_username": {
+                    "description": "Имя на момент решения; \"system\" для автоматических",
+                    "type": "string"
+                },
+                "rule_id": {
+                    "description": "RuleID — правило автоматического решения, которое приняло это решение",
+                    "type": "integer"
+                },
+                "whitelist_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WhitelistRule": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "approve или deny",
+                    "type": "string",
+                    "example": "approve"
+                },
+                "approved_elsewhere": {
+                    "description": "У пользователя уже есть действующий (не истёкший) одобренный доступ к другому стенду",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "language_code": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "dev для всех"
+                },
+                "priority": {
+                    "description": "Меньше — раньше",
+                    "type": "integer",
+                    "example": 10
+                },
+                "stand": {
+                    "description": "Условия",
+                    "type": "string",
+                    "example": "dev"
+                },
+                "telegram_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "123456789"
+                    ]
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "username_pattern": {
+                    "description": "Регулярное выражение, без учёта регистра",
+                    "type": "string",
+                    "example": "^qa_"
+                }
+            }
+        },
+        "telegram.Chat": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "private, group, supergroup или channel",
+                    "type": "string"
+                }
+            }
+        },
+        "telegram.Message": {
+            "type": "object",
+            "properties": {
+                "caption": {
+                    "type": "string"
+                },
+                "chat": {
+                    "$ref": "#/definitions/telegram.Chat"
+                },
+                "date": {
+                    "type": "integer"
+                },
+                "from": {
+                    "$ref": "#/definitions/telegram.User"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
+        "telegram.Update": {
+            "type": "object",
+            "properties": {
+                "edited_message": {
+                    "$ref": "#/definitions/telegram.Message"
+                },
+                "message": {
+                    "$ref": "#/definitions/telegram.Message"
+                },
+                "update_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "telegram.User": {
+            "type": "object",
+            "properties": {
+                "first_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_bot": {
+                    "type": "boolean"
+                },
+                "language_code": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,298 @@
+// Package email разбирает входящие письма (RFC 5322 / MIME) и отправляет ответы по SMTP
+// для почтового канала поддержки.
+//
+// Для разработки и тестов отправку можно направить на локальный SMTP-приёмник, например mailpit:
+//
+//	sender, err := email.NewSender("localhost:1025", "", "", "support@helpdesk.local")
+package email
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxTextSize — сколько текста письма сохраняется; остальное отбрасывается
+const maxTextSize = 256 << 10
+
+// ErrNoSender возвращается, если у письма нет корректного адреса From
+var ErrNoSender = errors.New("email has no valid From address")
+
+// Inbound — разобранное входящее письмо
+type Inbound struct {
+	MessageID  string   // Message-ID в угловых скобках
+	InReplyTo  []string // ID писем из In-Reply-To
+	References []string // ID писем из References
+	From       *mail.Address
+	Subject    string
+	Text       string // Текст письма без цитаты предыдущей переписки
+	// AutoSubmitted — письмо отправлено автоматически (автоответ, рассылка); на такие не отвечают,
+	// чтобы не зациклиться с автоответчиком
+	AutoSubmitted bool
+	// AuthenticationResults — значения заголовков Authentication-Results (RFC 8601); см. SenderVerified
+	AuthenticationResults []string
+}
+
+// ThreadIDs возвращает все ID писем, на которые ссылается письмо: сначала In-Reply-To, затем References
+func (m *Inbound) ThreadIDs() []string {
+	return append(append([]string{}, m.InReplyTo...), m.References...)
+}
+
+var (
+	wordDecoder   = &mime.WordDecoder{CharsetReader: charsetReader}
+	messageIDRe   = regexp.MustCompile(`<[^<>\s]+>`)
+	htmlTagRe     = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	htmlBreakRe   = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</tr>`)
+	blankLinesRe  = regexp.MustCompile(`\n{3,}`)
+	quoteHeaderRe = regexp.MustCompile(`(?i)(wrote|написал|написала|писал|писала|пишет)(\(а\))?:\s*$`)
+	commentRe     = regexp.MustCompile(`\([^()]*\)`)
+)
+
+// Parse разбирает письмо целиком, как оно пришло от почтового сервера
+func Parse(r io.Reader) (*Inbound, error) {
+	msg, err := mail.ReadMessage(bufio.NewReader(r))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email: %w", err)
+	}
+
+	parser := mail.AddressParser{WordDecoder: wordDecoder}
+	from, err := parser.Parse(msg.Header.Get("From"))
+	if err != nil {
+		return nil, ErrNoSender
+	}
+	from.Address = strings.ToLower(from.Address)
+
+	subject, err := wordDecoder.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	text, err := extractText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Inbound{
+		MessageID:  firstMessageID(msg.Header.Get("Message-ID")),
+		InReplyTo:  messageIDRe.FindAllString(msg.Header.Get("In-Reply-To"), -1),
+		References: messageIDRe.FindAllString(msg.Header.Get("References"), -1),
+		From:       from,
+		Subject:    strings.TrimSpace(subject),
+		Text:       stripQuotedReply(text),
+
+		AutoSubmitted:         isAutoSubmitted(msg.Header),
+		AuthenticationResults: msg.Header["Authentication-Results"],
+	}, nil
+}
+
+// SenderVerified сообщает, подтвердил ли почтовый релей с authserv-id адрес From: DMARC pass
+// либо DKIM или SPF pass для домена From. Сам сервис подписи не проверяет — это работа релея.
+// Заголовки с чужим authserv-id не учитываются: их мог вписать отправитель, а релей по RFC 8601
+// удаляет из входящих писем только заголовки со своим authserv-id
+func (m *Inbound) SenderVerified(authservID string) bool {
+	if authservID == "" || m.From == nil {
+		return false
+	}
+	domain := m.From.Address[strings.LastIndex(m.From.Address, "@")+1:]
+	for _, value := range m.AuthenticationResults {
+		results := strings.Split(commentRe.ReplaceAllString(value, ""), ";")
+		if fields := strings.Fields(results[0]); len(fields) == 0 || !strings.EqualFold(fields[0], authservID) {
+			continue
+		}
+		for _, result := range results[1:] {
+			if authResultVerifies(strings.Fields(strings.ToLower(result)), domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// authResultVerifies проверяет один результат вида "dkim=pass header.d=example.com"
+func authResultVerifies(fields []string, domain string) bool {
+	if len(fields) == 0 {
+		return false
+	}
+	props := map[string]string{}
+	for _, field := range fields[1:] {
+		if key, value, ok := strings.Cut(field, "="); ok {
+			props[key] = value
+		}
+	}
+	switch fields[0] {
+	case "dmarc=pass":
+		from, ok := props["header.from"]
+		return !ok || from == domain
+	case "dkim=pass":
+		return props["header.d"] == domain
+	case "spf=pass":
+		mailfrom := props["smtp.mailfrom"]
+		return mailfrom[strings.LastIndex(mailfrom, "@")+1:] == domain
+	}
+	return false
+}
+
+// isAutoSubmitted распознаёт автоматические письма по Auto-Submitted (RFC 3834) и Precedence
+func isAutoSubmitted(header mail.Header) bool {
+	if value := strings.ToLower(strings.TrimSpace(header.Get("Auto-Submitted"))); value != "" && value != "no" {
+		return true
+	}
+	switch strings.ToLower(strings.TrimSpace(header.Get("Precedence"))) {
+	case "bulk", "junk", "list", "auto_reply":
+		return true
+	}
+	return false
+}
+
+func firstMessageID(value string) string {
+	if id := messageIDRe.FindString(value); id != "" {
+		return id
+	}
+	return ""
+}
+
+// extractText возвращает текст письма или части multipart: text/plain предпочтительнее text/html,
+// вложения пропускаются
+func extractText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || contentType == "" {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		return extractMultipartText(mediaType, params["boundary"], body)
+	}
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", nil
+	}
+
+	decoded, err := charsetReader(params["charset"], decodeTransfer(encoding, body))
+	if err != nil {
+		return "", err
+	}
+	raw, err := io.ReadAll(io.LimitReader(decoded, maxTextSize))
+	if err != nil {
+		return "", fmt.Errorf("failed to read email body: %w", err)
+	}
+	text := strings.ReplaceAll(string(raw), "\r\n", "\n")
+	if mediaType == "text/html" {
+		text = htmlToText(text)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+func extractMultipartText(mediaType, boundary string, body io.Reader) (string, error) {
+	if boundary == "" {
+		return "", errors.New("multipart email without boundary")
+	}
+	reader := multipart.NewReader(body, boundary)
+	var plain, fallback string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid multipart email: %w", err)
+		}
+		if disposition, _, _ := mime.ParseMediaType(part.Header.Get("Content-Disposition")); disposition == "attachment" {
+			continue
+		}
+
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		text, err := extractText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+		if err != nil {
+			return "", err
+		}
+		if text == "" {
+			continue
+		}
+		if partType == "text/plain" || partType == "" {
+			if plain == "" {
+				plain = text
+			}
+		} else if fallback == "" {
+			fallback = text
+		}
+		// В multipart/mixed текст — первая часть, дальше обычно вложения
+		if mediaType != "multipart/alternative" && (plain != "" || fallback != "") {
+			break
+		}
+	}
+	if plain != "" {
+		return plain, nil
+	}
+	return fallback, nil
+}
+
+// decodeTransfer снимает Content-Transfer-Encoding. У частей multipart quoted-printable
+// снимает сам mime/multipart вместе с заголовком, так что дважды он не декодируется
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// charsetReader перекодирует текст в UTF-8; неизвестная кодировка — ошибка
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	charset = strings.ToLower(strings.TrimSpace(charset))
+	if charset == "" || charset == "utf-8" || charset == "us-ascii" {
+		return input, nil
+	}
+	encoding, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return encoding.NewDecoder().Reader(input), nil
+}
+
+// htmlToText грубо превращает HTML в текст: для тикета важно содержание, а не вёрстка
+func htmlToText(s string) string {
+	s = htmlBreakRe.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(htmlTagRe.ReplaceAllString(s, ""))
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return blankLinesRe.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+// stripQuotedReply отрезает цитату переписки в конце ответа: строки с ">" и строку
+// «… wrote:» / «… писал(а):» перед ними
+func stripQuotedReply(text string) string {
+	lines := strings.Split(text, "\n")
+	end := len(lines)
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line == "" || strings.HasPrefix(line, ">") {
+			end--
+			continue
+		}
+		break
+	}
+	if end < len(lines) && end > 0 && quoteHeaderRe.MatchString(lines[end-1]) {
+		end--
+	}
+	stripped := strings.TrimSpace(strings.Join(lines[:end], "\n"))
+	if stripped == "" {
+		// Письмо из одной цитаты лучше сохранить целиком, чем потерять
+		return strings.TrimSpace(text)
+	}
+	return stripped
+}
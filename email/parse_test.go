@@ -0,0 +1,309 @@
+package email
+
+import (
+	"errors"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// message собирает письмо из строк заголовков и тела с переводами строк CRLF
+func message(lines ...string) string {
+	return strings.Join(lines, "\r\n")
+}
+
+func TestParseText(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		raw     string
+		subject string
+		text    string
+	}{
+		{
+			name: "plain utf-8",
+			raw: message(
+				"From: Иван <Ivan@Example.com>",
+				"Subject: Не работает вход",
+				"Content-Type: text/plain; charset=utf-8",
+				"",
+				"Ввожу пароль — ничего не происходит",
+			),
+			subject: "Не работает вход",
+			text:    "Ввожу пароль — ничего не происходит",
+		},
+		{
+			name: "no content type",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Hello",
+				"",
+				"Just text",
+			),
+			subject: "Hello",
+			text:    "Just text",
+		},
+		{
+			name: "base64 encoded-word subject",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: =?utf-8?B?0J3QtSDRgNCw0LHQvtGC0LDQtdGCINCy0YXQvtC0?=",
+				"",
+				"body",
+			),
+			subject: "Не работает вход",
+			text:    "body",
+		},
+		{
+			name: "windows-1251 quoted-printable",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: =?windows-1251?Q?=D2=E5=EC=E0?=",
+				"Content-Type: text/plain; charset=windows-1251",
+				"Content-Transfer-Encoding: quoted-printable",
+				"",
+				"=CF=F0=E8=E2=E5=F2, =ED=E5 =EC=EE=E3=F3 =E2=EE=E9=F2=E8",
+			),
+			subject: "Тема",
+			text:    "Привет, не могу войти",
+		},
+		{
+			name: "koi8-r base64",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Оплата",
+				"Content-Type: text/plain; charset=koi8-r",
+				"Content-Transfer-Encoding: base64",
+				"",
+				"79vJwsvBIM/QzMHU2Q==",
+			),
+			subject: "Оплата",
+			text:    "Ошибка оплаты",
+		},
+		{
+			name: "alternative prefers plain",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Alt",
+				"Content-Type: multipart/alternative; boundary=b1",
+				"",
+				"--b1",
+				"Content-Type: text/html; charset=utf-8",
+				"",
+				"<p>HTML version</p>",
+				"--b1",
+				"Content-Type: text/plain; charset=utf-8",
+				"",
+				"Plain version",
+				"--b1--",
+			),
+			subject: "Alt",
+			text:    "Plain version",
+		},
+		{
+			name: "html only",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Html",
+				"Content-Type: text/html; charset=utf-8",
+				"",
+				"<html><style>p{color:red}</style><body><p>Первая строка</p><p>Вторая &amp; последняя<br>строка</p></body></html>",
+			),
+			subject: "Html",
+			text:    "Первая строка\nВторая & последняя\nстрока",
+		},
+		{
+			name: "mixed skips attachment",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Mixed",
+				"Content-Type: multipart/mixed; boundary=outer",
+				"",
+				"--outer",
+				"Content-Type: multipart/alternative; boundary=inner",
+				"",
+				"--inner",
+				"Content-Type: text/plain; charset=utf-8",
+				"Content-Transfer-Encoding: quoted-printable",
+				"",
+				"=D0=A1=D0=BA=D1=80=D0=B8=D0=BD=D1=88=D0=BE=D1=82 =D0=B2=D0=BE =D0=B2=D0=BB=D0=BE=D0=B6=D0=B5=D0=BD=D0=B8=D0=B8",
+				"--inner--",
+				"--outer",
+				"Content-Type: text/plain",
+				"Content-Disposition: attachment; filename=log.txt",
+				"",
+				"attachment text",
+				"--outer--",
+			),
+			subject: "Mixed",
+			text:    "Скриншот во вложении",
+		},
+		{
+			name: "quoted reply stripped",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Re: Вход",
+				"",
+				"Спасибо, заработало",
+				"",
+				"Пн, 2 окт. 2023 г. в 10:00, Поддержка <support@helpdesk.local> писал(а):",
+				"> Попробуйте сбросить пароль",
+				">",
+			),
+			subject: "Re: Вход",
+			text:    "Спасибо, заработало",
+		},
+		{
+			name: "english quote header",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Re: Login",
+				"",
+				"Works now, thanks",
+				"On Mon, Oct 2, 2023 at 10:00 AM Support <support@helpdesk.local> wrote:",
+				"> Try resetting the password",
+			),
+			subject: "Re: Login",
+			text:    "Works now, thanks",
+		},
+		{
+			name: "only quote kept",
+			raw: message(
+				"From: ivan@example.com",
+				"Subject: Fwd",
+				"",
+				"> forwarded line",
+			),
+			subject: "Fwd",
+			text:    "> forwarded line",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inbound, err := Parse(strings.NewReader(tc.raw))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if inbound.Subject != tc.subject {
+				t.Errorf("Subject = %q, want %q", inbound.Subject, tc.subject)
+			}
+			if inbound.Text != tc.text {
+				t.Errorf("Text = %q, want %q", inbound.Text, tc.text)
+			}
+		})
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	inbound, err := Parse(strings.NewReader(message(
+		"From: =?utf-8?B?0JjQstCw0L0=?= <Ivan@Example.COM>",
+		"Subject: Re: Вход [#0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b]",
+		"Message-ID: <reply-1@mail.example.com>",
+		"In-Reply-To: <ticket-0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b.msg-5.ab12@helpdesk.local>",
+		"References: <first@mail.example.com>",
+		"  <ticket-0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b.msg-5.ab12@helpdesk.local>",
+		"",
+		"text",
+	)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if inbound.From.Address != "ivan@example.com" || inbound.From.Name != "Иван" {
+		t.Errorf("From = %+v", inbound.From)
+	}
+	if inbound.MessageID != "<reply-1@mail.example.com>" {
+		t.Errorf("MessageID = %q", inbound.MessageID)
+	}
+	want := []string{
+		"<ticket-0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b.msg-5.ab12@helpdesk.local>",
+		"<first@mail.example.com>",
+		"<ticket-0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b.msg-5.ab12@helpdesk.local>",
+	}
+	if got := inbound.ThreadIDs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ThreadIDs = %v, want %v", got, want)
+	}
+	if inbound.AutoSubmitted {
+		t.Error("ordinary email marked as auto-submitted")
+	}
+}
+
+func TestParseAutoSubmitted(t *testing.T) {
+	for _, header := range []string{"Auto-Submitted: auto-replied", "Precedence: bulk", "Precedence: auto_reply"} {
+		inbound, err := Parse(strings.NewReader(message("From: robot@example.com", header, "", "Я в отпуске")))
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", header, err)
+		}
+		if !inbound.AutoSubmitted {
+			t.Errorf("%s: not marked as auto-submitted", header)
+		}
+	}
+	inbound, err := Parse(strings.NewReader(message("From: ivan@example.com", "Auto-Submitted: no", "", "text")))
+	if err != nil || inbound.AutoSubmitted {
+		t.Errorf("Auto-Submitted: no: AutoSubmitted = %v, err %v", inbound != nil && inbound.AutoSubmitted, err)
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	if _, err := Parse(strings.NewReader(message("Subject: no sender", "", "text"))); !errors.Is(err, ErrNoSender) {
+		t.Errorf("missing From: err = %v, want ErrNoSender", err)
+	}
+	_, err := Parse(strings.NewReader(message("From: ivan@example.com", "Content-Type: text/plain; charset=x-unknown", "", "text")))
+	if err == nil || !strings.Contains(err.Error(), "unsupported charset") {
+		t.Errorf("unknown charset: err = %v", err)
+	}
+	_, err = Parse(strings.NewReader(message("From: ivan@example.com", "Content-Type: multipart/mixed", "", "text")))
+	if err == nil {
+		t.Error("multipart without boundary accepted")
+	}
+}
+
+func TestSenderVerified(t *testing.T) {
+	const relay = "mx.helpdesk.local"
+	for _, tc := range []struct {
+		name    string
+		results []string
+		want    bool
+	}{
+		{"no header", nil, false},
+		{"dmarc pass", []string{"mx.helpdesk.local; dmarc=pass (p=reject) header.from=example.com"}, true},
+		{"dmarc pass without header.from", []string{"mx.helpdesk.local; dmarc=pass"}, true},
+		{"dmarc pass for other domain", []string{"mx.helpdesk.local; dmarc=pass header.from=evil.test"}, false},
+		{"dkim pass aligned", []string{"MX.helpdesk.local 1; spf=none; dkim=pass (2048-bit key) header.d=example.com header.i=@example.com"}, true},
+		{"dkim pass other domain", []string{"mx.helpdesk.local; dkim=pass header.d=evil.test"}, false},
+		{"spf pass aligned", []string{"mx.helpdesk.local; spf=pass smtp.mailfrom=bounce@example.com"}, true},
+		{"spf pass other domain", []string{"mx.helpdesk.local; spf=pass smtp.mailfrom=evil.test"}, false},
+		{"all fail", []string{"mx.helpdesk.local; spf=fail smtp.mailfrom=example.com; dkim=fail header.d=example.com; dmarc=fail"}, false},
+		{"forged by another server", []string{"mx.evil.test; dmarc=pass header.from=example.com"}, false},
+		{"relay result after forged one", []string{"mx.evil.test; dmarc=pass", "mx.helpdesk.local; dkim=pass header.d=example.com"}, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			inbound := &Inbound{From: &mail.Address{Address: "ivan@example.com"}, AuthenticationResults: tc.results}
+			if got := inbound.SenderVerified(relay); got != tc.want {
+				t.Errorf("SenderVerified = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	verified := &Inbound{
+		From:                  &mail.Address{Address: "ivan@example.com"},
+		AuthenticationResults: []string{"mx.helpdesk.local; dmarc=pass"},
+	}
+	if verified.SenderVerified("") {
+		t.Error("sender verified without a configured authserv-id")
+	}
+}
+
+func TestParseAuthenticationResults(t *testing.T) {
+	inbound, err := Parse(strings.NewReader(message(
+		"Authentication-Results: mx.helpdesk.local;",
+		"  dkim=pass header.d=example.com",
+		"From: ivan@example.com",
+		"",
+		"text",
+	)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if !inbound.SenderVerified("mx.helpdesk.local") {
+		t.Errorf("folded Authentication-Results not recognised: %q", inbound.AuthenticationResults)
+	}
+}
@@ -0,0 +1,167 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured возвращается при отправке, если SMTP-сервер не задан
+var ErrNotConfigured = errors.New("SMTP is not configured")
+
+// Outbound — исходящее письмо
+type Outbound struct {
+	To         string
+	Subject    string
+	Text       string
+	MessageID  string   // Message-ID в угловых скобках; см. Sender.NewMessageID
+	InReplyTo  string   // Message-ID письма, на которое это ответ
+	References []string // Цепочка Message-ID переписки, от первого письма к последнему
+}
+
+// Sender отправляет письма через SMTP-сервер
+type Sender struct {
+	addr     string
+	username string
+	password string
+	from     mail.Address
+}
+
+// NewSender создаёт отправителя писем от адреса from ("Поддержка <support@example.com>"
+// или просто адрес) через SMTP-сервер addr (host:port). Логин пустой — без авторизации.
+// Если сервер поддерживает STARTTLS, соединение шифруется
+func NewSender(addr, username, password, from string) (*Sender, error) {
+	address, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address %q: %w", from, err)
+	}
+	return &Sender{addr: addr, username: username, password: password, from: *address}, nil
+}
+
+// Configured сообщает, задан ли SMTP-сервер
+func (s *Sender) Configured() bool {
+	return s.addr != ""
+}
+
+// Address возвращает адрес отправителя
+func (s *Sender) Address() string {
+	return s.from.Address
+}
+
+// NewMessageID создаёт Message-ID в домене отправителя; local — читаемая часть до случайного хвоста
+func (s *Sender) NewMessageID(local string) string {
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	domain := "localhost"
+	if at := strings.LastIndex(s.from.Address, "@"); at >= 0 {
+		domain = s.from.Address[at+1:]
+	}
+	return fmt.Sprintf("<%s.%s@%s>", local, hex.EncodeToString(buf), domain)
+}
+
+// Send отправляет письмо, соблюдая срок ctx
+func (s *Sender) Send(ctx context.Context, msg Outbound) error {
+	if !s.Configured() {
+		return ErrNotConfigured
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	raw, err := s.render(msg, to)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(s.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// render собирает письмо: text/plain в UTF-8, quoted-printable
+func (s *Sender) render(msg Outbound, to *mail.Address) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		// Переводы строк в значении позволили бы дописать свои заголовки
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", s.from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	if msg.MessageID != "" {
+		header("Message-ID", msg.MessageID)
+	}
+	if msg.InReplyTo != "" {
+		header("In-Reply-To", msg.InReplyTo)
+	}
+	if len(msg.References) > 0 {
+		header("References", strings.Join(msg.References, " "))
+	}
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "quoted-printable")
+	buf.WriteString("\r\n")
+
+	qp := quotedprintable.NewWriter(&buf)
+	text := strings.ReplaceAll(strings.ReplaceAll(msg.Text, "\r\n", "\n"), "\n", "\r\n")
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,183 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSink — локальный SMTP-приёмник: принимает одно письмо за соединение и отдаёт его в канал
+type smtpSink struct {
+	addr     string
+	messages chan sinkMessage
+}
+
+type sinkMessage struct {
+	From string
+	To   []string
+	Data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	sink := &smtpSink{addr: listener.Addr().String(), messages: make(chan sinkMessage, 1)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	var msg sinkMessage
+	tp.PrintfLine("220 sink ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			tp.PrintfLine("250 sink")
+		case "MAIL":
+			msg.From = strings.Trim(strings.TrimPrefix(line[5:], "FROM:"), "<>")
+			tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, strings.Trim(strings.TrimPrefix(line[5:], "TO:"), "<>"))
+			tp.PrintfLine("250 OK")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := io.ReadAll(tp.DotReader())
+			if err != nil {
+				return
+			}
+			msg.Data = string(data)
+			tp.PrintfLine("250 queued")
+			s.messages <- msg
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpSink) receive(t *testing.T) sinkMessage {
+	t.Helper()
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received by SMTP sink")
+		return sinkMessage{}
+	}
+}
+
+func TestSendDeliversToSMTPSink(t *testing.T) {
+	sink := newSMTPSink(t)
+	sender, err := NewSender(sink.addr, "", "", "Поддержка <support@helpdesk.local>")
+	if err != nil {
+		t.Fatalf("NewSender: %v", err)
+	}
+
+	err = sender.Send(context.Background(), Outbound{
+		To:         "Иван <ivan@example.com>",
+		Subject:    "Re: Не работает вход [#0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b]",
+		Text:       "Попробуйте сбросить пароль.\nЕсли не поможет — напишите.",
+		MessageID:  "<ticket-0b5e2f3a.msg-5.ab12@helpdesk.local>",
+		InReplyTo:  "<first@mail.example.com>",
+		References: []string{"<first@mail.example.com>"},
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	got := sink.receive(t)
+	if got.From != "support@helpdesk.local" || len(got.To) != 1 || got.To[0] != "ivan@example.com" {
+		t.Fatalf("envelope = %s -> %v", got.From, got.To)
+	}
+
+	// Письмо, ушедшее в приёмник, разбирается тем же парсером, что и входящие
+	inbound, err := Parse(strings.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("Parse sent email: %v\n%s", err, got.Data)
+	}
+	if inbound.From.Address != "support@helpdesk.local" || inbound.From.Name != "Поддержка" {
+		t.Errorf("From = %+v", inbound.From)
+	}
+	if inbound.Subject != "Re: Не работает вход [#0b5e2f3a-8c1d-4e6f-9a7b-2c3d4e5f6a7b]" {
+		t.Errorf("Subject = %q", inbound.Subject)
+	}
+	if inbound.Text != "Попробуйте сбросить пароль.\nЕсли не поможет — напишите." {
+		t.Errorf("Text = %q", inbound.Text)
+	}
+	if inbound.MessageID != "<ticket-0b5e2f3a.msg-5.ab12@helpdesk.local>" {
+		t.Errorf("Message-ID = %q", inbound.MessageID)
+	}
+	if ids := inbound.ThreadIDs(); len(ids) != 2 || ids[0] != "<first@mail.example.com>" {
+		t.Errorf("thread IDs = %v", ids)
+	}
+}
+
+func TestSendNotConfigured(t *testing.T) {
+	sender, err := NewSender("", "", "", "support@helpdesk.local")
+	if err != nil {
+		t.Fatalf("NewSender: %v", err)
+	}
+	if err := sender.Send(context.Background(), Outbound{To: "ivan@example.com"}); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("Send = %v, want ErrNotConfigured", err)
+	}
+}
+
+func TestRenderHeaderInjection(t *testing.T) {
+	sender, err := NewSender("localhost:25", "", "", "support@helpdesk.local")
+	if err != nil {
+		t.Fatalf("NewSender: %v", err)
+	}
+	to := &mail.Address{Address: "ivan@example.com"}
+	for _, tc := range []struct {
+		name string
+		msg  Outbound
+	}{
+		{"subject", Outbound{Subject: "Вход\r\nBcc: victim@example.com"}},
+		{"subject lf", Outbound{Subject: "Login\nBcc: victim@example.com"}},
+		{"in-reply-to", Outbound{Subject: "s", InReplyTo: "<a@b>\r\nBcc: victim@example.com"}},
+		{"references", Outbound{Subject: "s", References: []string{"<a@b>", "<c@d>\nBcc: victim@example.com"}}},
+		{"message-id", Outbound{Subject: "s", MessageID: "<a@b>\r\nBcc: victim@example.com"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.msg.Text = "body\r\n\r\nBcc: not-a-header@example.com"
+			raw, err := sender.render(tc.msg, to)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+			if err != nil {
+				t.Fatalf("rendered email does not parse: %v", err)
+			}
+			if bcc := msg.Header.Get("Bcc"); bcc != "" {
+				t.Fatalf("injected Bcc header: %q", bcc)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Типы событий, рассылаемых подписчикам
+const (
+	TypeTicketCreated       = "ticket.created"
+	TypeMessageCreated      = "message.created"
+	TypeTicketStatusChanged = "ticket.status_changed"
+	TypeTicketAssigned      = "ticket.assigned"
+	TypeMessageDelivery     = "message.delivery"
+	// Только для сотрудников (см. PublishStaff)
+	TypeNoteCreated       = "note.created"
+	TypeOperatorMentioned = "operator.mentioned"
+)
+
+// subscriberBuffer — сколько событий может накопиться у подписчика, прежде чем он будет отключён
+const subscriberBuffer = 64
+
+var logger = logrus.New()
+
+// Event — событие по тикету
+type Event struct {
+	ID        uint64 `json:"id"`
+	Type      string `json:"type"`
+	TicketID  uint   `json:"ticket_id"`
+	UserID    uint   `json:"-"` // Владелец тикета: пользователи получают события только своих тикетов
+	StaffOnly bool   `json:"-"` // Событие не доставляется пользователям, даже владельцу тикета
+	// OperatorID — адресат события: если задан, событие получает только этот оператор (см. PublishOperator)
+	OperatorID uint        `json:"-"`
+	Data       interface{} `json:"data"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+// Subscriber — получатель событий: WebSocket-соединение или SSE-поток
+type Subscriber struct {
+	role       string
+	userID     uint
+	operatorID uint
+	ch         chan Event
+
+	mu      sync.Mutex
+	all     bool
+	tickets map[uint]bool
+	closed  bool
+}
+
+// Events возвращает канал событий подписчика. Канал закрывается при отписке
+// или если подписчик не успевает забирать события.
+func (s *Subscriber) Events() <-chan Event {
+	return s.ch
+}
+
+// Follow подписывает на события конкретного тикета. Право доступа к тикету
+// проверяет вызывающий код.
+func (s *Subscriber) Follow(ticketID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets[ticketID] = true
+}
+
+// Unfollow отписывает от событий тикета
+func (s *Subscriber) Unfollow(ticketID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tickets, ticketID)
+}
+
+// FollowAll подписывает на события всех тикетов (только для операторов)
+func (s *Subscriber) FollowAll(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.all = enabled
+}
+
+// Wants сообщает, должно ли событие быть доставлено подписчику
+func (s *Subscriber) Wants(e Event) bool {
+	if e.OperatorID != 0 {
+		// Адресное событие доставляется оператору независимо от его подписок на тикеты
+		return s.role != "user" && s.operatorID == e.OperatorID
+	}
+	if s.role == "user" && (e.UserID != s.userID || e.StaffOnly) {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.all || s.tickets[e.TicketID]
+}
+
+// Hub раздаёт события подписчикам. Если задана БД, каждое событие сначала
+// сохраняется в журнал и получает его ID.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	db          *gorm.DB
+
+	idMu   sync.Mutex
+	nextID uint64 // Последний выданный ID; под idMu
+}
+
+// NewHub создаёт пустой хаб
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[*Subscriber]struct{})}
+}
+
+// Subscribe регистрирует нового подписчика. userID учитывается только для роли "user",
+// operatorID — только для сотрудников: по нему доставляются адресные события.
+func (h *Hub) Subscribe(role string, userID, operatorID uint) *Subscriber {
+	s := &Subscriber{
+		role:       role,
+		userID:     userID,
+		operatorID: operatorID,
+		ch:         make(chan Event, subscriberBuffer),
+		tickets:    make(map[uint]bool),
+	}
+	h.mu.Lock()
+	h.subscribers[s] = struct{}{}
+	h.mu.Unlock()
+	return s
+}
+
+// Unsubscribe удаляет подписчика и закрывает его канал
+func (h *Hub) Unsubscribe(s *Subscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, s)
+	h.mu.Unlock()
+	s.close()
+}
+
+// Publish рассылает событие всем заинтересованным подписчикам. Медленные подписчики,
+// у которых переполнен буфер, отключаются, чтобы не задерживать остальных.
+// Событие записывается в журнал до захвата блокировки хаба: запись в БД не должна
+// задерживать подписку, отписку и другие публикации. Поэтому события, опубликованные
+// одновременно, могут прийти подписчику не в порядке ID.
+func (h *Hub) Publish(e Event) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if e.ID == 0 {
+		h.assignID(&e)
+	}
+
+	var slow []*Subscriber
+	h.mu.RLock()
+	for s := range h.subscribers {
+		if !s.Wants(e) {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			slow = append(slow, s)
+		}
+	}
+	h.mu.RUnlock()
+	if len(slow) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	for _, s := range slow {
+		delete(h.subscribers, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range slow {
+		logger.Warnf("Dropping slow event subscriber (role %s)", s.role)
+		s.close()
+	}
+}
+
+// assignID сохраняет событие в журнал и берёт ID оттуда. Без БД (или при ошибке записи)
+// используется локальный счётчик, чтобы живая доставка не прерывалась.
+func (h *Hub) assignID(e *Event) {
+	h.mu.RLock()
+	db := h.db
+	h.mu.RUnlock()
+
+	if db != nil {
+		payload, err := json.Marshal(e.Data)
+		if err == nil {
+			record := models.EventLog{
+				Type:       e.Type,
+				TicketID:   e.TicketID,
+				UserID:     e.UserID,
+				StaffOnly:  e.StaffOnly,
+				OperatorID: e.OperatorID,
+				Payload:    string(payload),
+				CreatedAt:  e.CreatedAt,
+			}
+			err = db.Create(&record).Error
+			if err == nil {
+				e.ID = record.ID
+				h.idMu.Lock()
+				if record.ID > h.nextID {
+					h.nextID = record.ID
+				}
+				h.idMu.Unlock()
+				return
+			}
+		}
+		logger.Errorf("Failed to persist event %s for ticket %d: %v", e.Type, e.TicketID, err)
+	}
+	h.idMu.Lock()
+	h.nextID++
+	e.ID = h.nextID
+	h.idMu.Unlock()
+}
+
+// Replay возвращает сохранённые события с ID больше afterID, видимые подписчику
+func (h *Hub) Replay(s *Subscriber, afterID uint64, limit int) ([]Event, error) {
+	if h.db == nil {
+		return nil, nil
+	}
+
+	query := h.db.Where("id > ?", afterID)
+	if s.role == "user" {
+		query = query.Where("user_id = ? AND staff_only = ?", s.userID, false)
+	}
+	// Обычные события — по подпискам на тикеты, адресные — только своему оператору
+	visible := h.db.Session(&gorm.Session{NewDB: true}).Where("operator_id = ?", 0)
+	s.mu.Lock()
+	if !s.all {
+		ticketIDs := make([]uint, 0, len(s.tickets))
+		for id := range s.tickets {
+			ticketIDs = append(ticketIDs, id)
+		}
+		visible = visible.Where("ticket_id IN ?", ticketIDs)
+	}
+	s.mu.Unlock()
+	if s.role != "user" && s.operatorID != 0 {
+		visible = visible.Or("operator_id = ?", s.operatorID)
+	}
+	query = query.Where(visible)
+
+	var records []models.EventLog
+	if err := query.Order("id asc").Limit(limit).Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	replayed := make([]Event, 0, len(records))
+	for _, record := range records {
+		replayed = append(replayed, Event{
+			ID:         record.ID,
+			Type:       record.Type,
+			TicketID:   record.TicketID,
+			UserID:     record.UserID,
+			StaffOnly:  record.StaffOnly,
+			OperatorID: record.OperatorID,
+			Data:       json.RawMessage(record.Payload),
+			CreatedAt:  record.CreatedAt,
+		})
+	}
+	return replayed, nil
+}
+
+// Prune удаляет из журнала события старше retention
+func (h *Hub) Prune(retention time.Duration) error {
+	if h.db == nil {
+		return nil
+	}
+	return h.db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.EventLog{}).Error
+}
+
+func (s *Subscriber) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+}
+
+// defaultHub — хаб процесса, через который публикуют обработчики
+var defaultHub = NewHub()
+
+// Default возвращает хаб процесса
+func Default() *Hub {
+	return defaultHub
+}
+
+// Init подключает хаб процесса к журналу событий и запускает его периодическую очистку
+func Init(db *gorm.DB, retention time.Duration) {
+	defaultHub.mu.Lock()
+	defaultHub.db = db
+	defaultHub.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := defaultHub.Prune(retention); err != nil {
+				logger.Errorf("Failed to prune event log: %v", err)
+			}
+		}
+	}()
+}
+
+// Publish публикует событие в хаб процесса
+func Publish(eventType string, ticketID, userID uint, data interface{}) {
+	defaultHub.Publish(Event{Type: eventType, TicketID: ticketID, UserID: userID, Data: data})
+}
+
+// PublishStaff публикует в хаб процесса событие, которое видят только сотрудники
+func PublishStaff(eventType string, ticketID, userID uint, data interface{}) {
+	defaultHub.Publish(Event{Type: eventType, TicketID: ticketID, UserID: userID, Data: data, StaffOnly: true})
+}
+
+// PublishOperator публикует в хаб процесса событие, адресованное одному оператору
+func PublishOperator(eventType string, ticketID, userID, operatorID uint, data interface{}) {
+	defaultHub.Publish(Event{Type: eventType, TicketID: ticketID, UserID: userID, Data: data, StaffOnly: true, OperatorID: operatorID})
+}
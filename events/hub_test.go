@@ -0,0 +1,228 @@
+package events
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"helpdesk-api/models"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+func TestPublishFiltersBySubscriber(t *testing.T) {
+	hub := NewHub()
+	owner := hub.Subscribe("user", 1, 0)
+	owner.FollowAll(true)
+	stranger := hub.Subscribe("user", 2, 0)
+	stranger.FollowAll(true)
+	operator := hub.Subscribe("operator", 0, 0)
+	operator.Follow(10)
+
+	hub.Publish(Event{Type: TypeMessageCreated, TicketID: 10, UserID: 1})
+	hub.Publish(Event{Type: TypeNoteCreated, TicketID: 10, UserID: 1, StaffOnly: true})
+	hub.Publish(Event{Type: TypeMessageCreated, TicketID: 11, UserID: 3})
+
+	for _, tc := range []struct {
+		name  string
+		s     *Subscriber
+		types []string
+	}{
+		{"owner", owner, []string{TypeMessageCreated}},
+		{"stranger", stranger, nil},
+		{"operator", operator, []string{TypeMessageCreated, TypeNoteCreated}},
+	} {
+		var got []string
+		for len(tc.s.ch) > 0 {
+			got = append(got, (<-tc.s.ch).Type)
+		}
+		if len(got) != len(tc.types) {
+			t.Errorf("%s received %v, want %v", tc.name, got, tc.types)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.types[i] {
+				t.Errorf("%s received %v, want %v", tc.name, got, tc.types)
+			}
+		}
+	}
+}
+
+func TestPublishAssignsIDsWithoutDB(t *testing.T) {
+	hub := NewHub()
+	s := hub.Subscribe("operator", 0, 0)
+	s.FollowAll(true)
+
+	var wg sync.WaitGroup
+	for i := 0; i < subscriberBuffer; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hub.Publish(Event{Type: TypeTicketCreated, TicketID: 1})
+		}()
+	}
+	wg.Wait()
+
+	seen := map[uint64]bool{}
+	for len(s.ch) > 0 {
+		e := <-s.ch
+		if e.ID == 0 || seen[e.ID] || e.CreatedAt.IsZero() {
+			t.Fatalf("event %+v: ID must be unique and non-zero, CreatedAt set", e)
+		}
+		seen[e.ID] = true
+	}
+	if len(seen) != subscriberBuffer {
+		t.Fatalf("received %d events, want %d", len(seen), subscriberBuffer)
+	}
+}
+
+func TestPublishDropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	slow := hub.Subscribe("operator", 0, 0)
+	slow.FollowAll(true)
+
+	for i := 0; i <= subscriberBuffer; i++ {
+		hub.Publish(Event{Type: TypeTicketCreated, TicketID: 1})
+	}
+
+	drained := 0
+	for range slow.Events() {
+		drained++
+	}
+	if drained != subscriberBuffer {
+		t.Fatalf("drained %d events, want %d before the channel closed", drained, subscriberBuffer)
+	}
+	hub.mu.RLock()
+	_, still := hub.subscribers[slow]
+	hub.mu.RUnlock()
+	if still {
+		t.Fatal("slow subscriber is still registered")
+	}
+	// Повторная отписка уже отключённого подписчика безопасна
+	hub.Unsubscribe(slow)
+}
+
+func TestPublishAddressedToOperator(t *testing.T) {
+	hub := NewHub()
+	mentioned := hub.Subscribe("operator", 0, 5) // не подписан на тикет, но событие адресовано ему
+	other := hub.Subscribe("operator", 0, 6)
+	other.FollowAll(true)
+	owner := hub.Subscribe("user", 1, 0)
+	owner.FollowAll(true)
+
+	hub.Publish(Event{Type: TypeOperatorMentioned, TicketID: 10, UserID: 1, StaffOnly: true, OperatorID: 5})
+
+	if len(mentioned.ch) != 1 {
+		t.Fatalf("mentioned operator received %d events, want 1", len(mentioned.ch))
+	}
+	if len(other.ch) != 0 || len(owner.ch) != 0 {
+		t.Fatalf("addressed event leaked: other operator %d, ticket owner %d", len(other.ch), len(owner.ch))
+	}
+}
+
+func TestWantsHidesStaffEventsFromUsers(t *testing.T) {
+	hub := NewHub()
+	owner := hub.Subscribe("user", 1, 0)
+	owner.FollowAll(true)
+	// Роль user с operatorID не должна получать адресные события, даже если ID совпал
+	ownerWithOperatorID := hub.Subscribe("user", 1, 5)
+	ownerWithOperatorID.FollowAll(true)
+	operator := hub.Subscribe("operator", 0, 5)
+	operator.Follow(10)
+
+	message := Event{Type: TypeMessageCreated, TicketID: 10, UserID: 1}
+	note := Event{Type: TypeNoteCreated, TicketID: 10, UserID: 1, StaffOnly: true}
+	mention := Event{Type: TypeOperatorMentioned, TicketID: 10, UserID: 1, StaffOnly: true, OperatorID: 5}
+	addressedNotStaffOnly := Event{Type: TypeOperatorMentioned, TicketID: 10, UserID: 1, OperatorID: 5}
+
+	for _, tc := range []struct {
+		name string
+		s    *Subscriber
+		e    Event
+		want bool
+	}{
+		{"owner gets message", owner, message, true},
+		{"owner misses note", owner, note, false},
+		{"owner misses mention", owner, mention, false},
+		{"owner misses addressed event", owner, addressedNotStaffOnly, false},
+		{"user with operator id misses mention", ownerWithOperatorID, mention, false},
+		{"user with operator id misses addressed event", ownerWithOperatorID, addressedNotStaffOnly, false},
+		{"operator gets note", operator, note, true},
+		{"operator gets mention", operator, mention, true},
+	} {
+		if got := tc.s.Wants(tc.e); got != tc.want {
+			t.Errorf("%s: Wants = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// openTestDB подключается к тестовой базе из HELPDESK_TEST_DSN; без неё тест пропускается
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("HELPDESK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("HELPDESK_TEST_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Default.LogMode(gormlogger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.EventLog{}); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+func TestReplayHidesStaffEventsFromUsers(t *testing.T) {
+	db := openTestDB(t)
+	hub := NewHub()
+	hub.db = db
+
+	// Уникальные тикет и пользователь, чтобы не видеть события других тестов
+	ticketID := uint(time.Now().UnixNano() % 1e9)
+	userID := ticketID
+	t.Cleanup(func() { db.Where("ticket_id = ?", ticketID).Delete(&models.EventLog{}) })
+
+	var last models.EventLog
+	db.Order("id desc").Limit(1).Find(&last)
+
+	hub.Publish(Event{Type: TypeMessageCreated, TicketID: ticketID, UserID: userID})
+	hub.Publish(Event{Type: TypeNoteCreated, TicketID: ticketID, UserID: userID, StaffOnly: true})
+	hub.Publish(Event{Type: TypeOperatorMentioned, TicketID: ticketID, UserID: userID, StaffOnly: true, OperatorID: 5})
+
+	replay := func(s *Subscriber) []string {
+		t.Helper()
+		s.Follow(ticketID)
+		events, err := hub.Replay(s, last.ID, 100)
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		var types []string
+		for _, e := range events {
+			if e.TicketID == ticketID {
+				types = append(types, e.Type)
+			}
+		}
+		return types
+	}
+
+	for _, tc := range []struct {
+		name string
+		s    *Subscriber
+		want []string
+	}{
+		{"owner", hub.Subscribe("user", userID, 0), []string{TypeMessageCreated}},
+		{"owner with operator id", hub.Subscribe("user", userID, 5), []string{TypeMessageCreated}},
+		{"other user", hub.Subscribe("user", userID+1, 0), nil},
+		{"mentioned operator", hub.Subscribe("operator", 0, 5), []string{TypeMessageCreated, TypeNoteCreated, TypeOperatorMentioned}},
+		{"other operator", hub.Subscribe("operator", 0, 6), []string{TypeMessageCreated, TypeNoteCreated}},
+	} {
+		if got := replay(tc.s); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("%s replayed %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
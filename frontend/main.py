@@ -1,5 +1,5 @@
 from fastapi import FastAPI, Request, Form, Header, HTTPException
-from fastapi.responses import HTMLResponse
+from fastapi.responses import HTMLResponse, Response
 from fastapi.templating import Jinja2Templates
 import httpx
 from pydantic import BaseModel
@@ -29,13 +29,22 @@ async def operator_login(username: str = Form(...), password: str = Form(...)):
         logger.info(f"Go API response: {response.status_code} - {response.text}")
         return response.json()
 
+@app.post("/operator/token/refresh")
+async def operator_refresh(refresh: str = Form(...)):
+    async with httpx.AsyncClient() as client:
+        response = await client.post(f"{API_URL}/token/refresh/", json={"refresh": refresh})
+        if response.status_code != 200:
+            raise HTTPException(status_code=response.status_code, detail=response.text)
+        return response.json()
+
 @app.get("/operator/tickets")
-async def get_all_tickets(authorization: str = Header(...)):
+async def get_all_tickets(request: Request, authorization: str = Header(...)):
     token = get_token(authorization)
     headers = {"Authorization": f"Bearer {token}"}
     logger.info(f"Sending request to {API_URL}/tickets/ with token: {token[:10]}...")
     async with httpx.AsyncClient() as client:
-        response = await client.get(f"{API_URL}/tickets/", headers=headers)
+        # Пробрасываем фильтры, сортировку и курсор как есть
+        response = await client.get(f"{API_URL}/tickets/", headers=headers, params=dict(request.query_params))
         logger.info(f"Go API response: {response.status_code} - {response.text}")
         return response.json()
 
@@ -67,10 +76,10 @@ async def send_operator_message(
         return response.json()
 
 @app.post("/operator/logout")
-async def operator_logout(token: str = Form(...)):
+async def operator_logout(token: str = Form(...), refresh: str = Form("")):
     headers = {"Authorization": f"Bearer {token}"}
     async with httpx.AsyncClient() as client:
-        response = await client.post(f"{API_URL}/logout/", headers=headers)
+        response = await client.post(f"{API_URL}/logout/", headers=headers, json={"refresh": refresh})
         return response.json()
 
 @app.post("/operator/ticket/{ticket_id}/close")
@@ -84,29 +93,29 @@ async def close_ticket(ticket_id: str, token: str = Form(...)):
         return response.json()
 
 @app.get("/operator/whitelist")
-async def get_whitelist_pending(authorization: str = Header(...)):
+async def get_whitelist_pending(request: Request, authorization: str = Header(...)):
     token = get_token(authorization)
     headers = {"Authorization": f"Bearer {token}"}
     async with httpx.AsyncClient() as client:
-        response = await client.get(f"{API_URL}/operator/whitelist/", headers=headers)
+        response = await client.get(f"{API_URL}/operator/whitelist/", headers=headers, params=request.query_params)
         return response.json()
 
 class WhitelistEdit(BaseModel):
     permission: str
 
-@app.post("/operator/whitelist/{telegram_id}/edit")
+@app.post("/operator/whitelist/{whitelist_id}/edit")
 async def edit_whitelist(
-        telegram_id: str,
+        whitelist_id: int,
         edit: WhitelistEdit,
         authorization: str = Header(...)
 ):
     token = get_token(authorization)
     headers = {"Authorization": f"Bearer {token}"}
     data = {"permission": edit.permission}
-    logger.info(f"Sending POST request to {API_URL}/operator/whitelist/{telegram_id}/edit with data: {data}")
+    logger.info(f"Sending POST request to {API_URL}/operator/whitelist/{whitelist_id}/edit with data: {data}")
     async with httpx.AsyncClient() as client:
         response = await client.post(
-            f"{API_URL}/operator/whitelist/{telegram_id}/edit",
+            f"{API_URL}/operator/whitelist/{whitelist_id}/edit",
             headers=headers,
             json=data
         )
@@ -116,13 +125,27 @@ async def edit_whitelist(
         return response.json()
 
 @app.get("/operator/whitelist/all")
-async def get_whitelist_all(authorization: str = Header(...)):
+async def get_whitelist_all(request: Request, authorization: str = Header(...)):
     token = get_token(authorization)
     headers = {"Authorization": f"Bearer {token}"}
     async with httpx.AsyncClient() as client:
-        response = await client.get(f"{API_URL}/operator/whitelist/all", headers=headers)
+        response = await client.get(f"{API_URL}/operator/whitelist/all", headers=headers, params=request.query_params)
         return response.json()
 
+@app.get("/operator/whitelist/export")
+async def export_whitelist(request: Request, authorization: str = Header(...)):
+    token = get_token(authorization)
+    headers = {"Authorization": f"Bearer {token}"}
+    async with httpx.AsyncClient(timeout=120) as client:
+        response = await client.get(f"{API_URL}/operator/whitelist/export", headers=headers, params=request.query_params)
+        if response.status_code != 200:
+            raise HTTPException(status_code=response.status_code, detail=response.text)
+        return Response(
+            content=response.content,
+            media_type=response.headers.get("content-type"),
+            headers={"Content-Disposition": response.headers.get("content-disposition", "attachment")}
+        )
+
 @app.get("/operator/settings/")
 async def get_settings(authorization: str = Header(...)):
     token = get_token(authorization)
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+	"helpdesk-api/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// attachmentFormField — поле multipart-формы AddMessage с файлами; файлов может быть несколько
+const attachmentFormField = "files"
+
+// maxAttachmentFilename — сколько символов имени файла сохраняется
+const maxAttachmentFilename = 255
+
+// attachmentError — отказ в приёме вложения с HTTP-статусом для клиента
+type attachmentError struct {
+	status  int
+	message string
+}
+
+func (e *attachmentError) Error() string {
+	return e.message
+}
+
+// respondAttachmentError отвечает клиенту на ошибку приёма вложений
+func respondAttachmentError(c *gin.Context, err error) {
+	var rejected *attachmentError
+	if errors.As(err, &rejected) {
+		c.JSON(rejected.status, gin.H{"error": rejected.message})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save attachments: " + err.Error()})
+}
+
+// attachmentUploads возвращает файлы из multipart-формы запроса, проверив их число и размер.
+// Содержимое проверяется позже, в storeAttachments
+func attachmentUploads(c *gin.Context, cfg *config.Config) ([]*multipart.FileHeader, error) {
+	if c.Request.MultipartForm == nil {
+		return nil, nil
+	}
+	files := c.Request.MultipartForm.File[attachmentFormField]
+	if len(files) > cfg.AttachmentMaxFiles {
+		return nil, &attachmentError{http.StatusBadRequest, fmt.Sprintf("Too many files: at most %d per message", cfg.AttachmentMaxFiles)}
+	}
+	for _, file := range files {
+		if file.Size > cfg.AttachmentMaxSize {
+			return nil, &attachmentError{http.StatusRequestEntityTooLarge, fmt.Sprintf("File %s exceeds %d bytes", file.Filename, cfg.AttachmentMaxSize)}
+		}
+		if file.Size == 0 {
+			return nil, &attachmentError{http.StatusBadRequest, fmt.Sprintf("File %s is empty", file.Filename)}
+		}
+	}
+	return files, nil
+}
+
+// limitMessageBody ограничивает тело запроса AddMessage: все вложения предельного размера и запас на поля формы
+func limitMessageBody(c *gin.Context, cfg *config.Config) {
+	limit := cfg.AttachmentMaxSize*int64(cfg.AttachmentMaxFiles) + 1<<20
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+}
+
+// storeAttachments проверяет тип файлов по содержимому, считает SHA-256 и сохраняет их в хранилище.
+// Если сохранить не удалось, уже сохранённые файлы удаляются
+func storeAttachments(ctx context.Context, store storage.Storage, cfg *config.Config, ticketID uint, files []*multipart.FileHeader) ([]models.Attachment, error) {
+	attachments := make([]models.Attachment, 0, len(files))
+	for _, file := range files {
+		attachment, err := storeAttachment(ctx, store, cfg, ticketID, file)
+		if err != nil {
+			deleteStoredAttachments(store, attachments)
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func storeAttachment(ctx context.Context, store storage.Storage, cfg *config.Config, ticketID uint, file *multipart.FileHeader) (models.Attachment, error) {
+	f, err := file.Open()
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	defer f.Close()
+
+	// Тип определяется по первым 512 байтам, как в http.DetectContentType; заявленному клиентом не верим
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return models.Attachment{}, err
+	}
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if !attachmentTypeAllowed(contentType, cfg.AttachmentAllowedTypes) {
+		return models.Attachment{}, &attachmentError{http.StatusUnsupportedMediaType, fmt.Sprintf("File type %s is not allowed", contentType)}
+	}
+
+	hash := sha256.New()
+	hash.Write(head[:n])
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	size += int64(n)
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return models.Attachment{}, err
+	}
+
+	key := fmt.Sprintf("tickets/%d/%s", ticketID, uuid.New().String())
+	if err := store.Put(ctx, key, f, size, contentType); err != nil {
+		return models.Attachment{}, fmt.Errorf("failed to store %s: %w", file.Filename, err)
+	}
+	return models.Attachment{
+		TicketID:    ticketID,
+		Filename:    attachmentFilename(file.Filename),
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}, nil
+}
+
+// attachmentTypeAllowed сверяет тип с разрешёнными; "image/*" разрешает все изображения
+func attachmentTypeAllowed(contentType string, allowed []string) bool {
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == contentType || strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentFilename оставляет от имени файла клиента только само имя, без пути и управляющих символов
+func attachmentFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > maxAttachmentFilename {
+		name = string(runes[:maxAttachmentFilename])
+	}
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// deleteStoredAttachments убирает из хранилища файлы, описание которых так и не попало в базу
+func deleteStoredAttachments(store storage.Storage, attachments []models.Attachment) {
+	for _, attachment := range attachments {
+		if err := store.Delete(context.Background(), attachment.StorageKey); err != nil {
+			log.Printf("Failed to delete orphaned attachment %s: %v", attachment.StorageKey, err)
+		}
+	}
+}
+
+// DownloadAttachment godoc
+// @Summary Скачать вложение сообщения
+// @Description Отдаёт файл, приложенный к сообщению тикета. Доступ — как к истории тикета: владельцу тикета (кроме вложений внутренних заметок) и сотрудникам с правом tickets.read_all
+// @Tags messages
+// @Produce octet-stream
+// @Param ticket_id path string true "ID тикета"
+// @Param attachment_id path string true "ID вложения"
+// @Success 200 {file} file
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /tickets/{ticket_id}/attachments/{attachment_id} [get]
+func DownloadAttachment(c *gin.Context, db *gorm.DB, store storage.Storage) {
+	var ticket models.Ticket
+	if err := db.Where("id = ?", c.Param("ticket_id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	if !authorizeTicketHistory(c, db, ticket) {
+		return
+	}
+
+	query := db.Where("id = ? AND ticket_id = ?", c.Param("attachment_id"), ticket.ID)
+	if !models.IsOperatorRole(c.GetString("role")) {
+		query = query.Where("message_id IN (?)", db.Model(&models.Message{}).Select("id").Where("internal = ?", false))
+	}
+	var attachment models.Attachment
+	if err := query.First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	file, err := store.Open(c.Request.Context(), attachment.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment file is missing"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment: " + err.Error()})
+		return
+	}
+	defer file.Close()
+
+	// Всегда как файл для скачивания: браузер не должен исполнять загруженное пользователем
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, file, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+		"ETag":                   `"` + attachment.SHA256 + `"`,
+	})
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helpdesk-api/config"
+	"helpdesk-api/storage"
+)
+
+func TestAttachmentTypeAllowed(t *testing.T) {
+	allowed := []string{"image/*", "Application/PDF", "text/plain"}
+	for _, tc := range []struct {
+		contentType string
+		want        bool
+	}{
+		{"image/png", true},
+		{"image/jpeg", true},
+		{"application/pdf", true},
+		{"text/plain", true},
+		{"text/html", false},
+		{"text/xml", false},
+		{"imagex/png", false},
+		{"image", false},
+		{"application/octet-stream", false},
+		{"", false},
+	} {
+		if got := attachmentTypeAllowed(tc.contentType, allowed); got != tc.want {
+			t.Errorf("attachmentTypeAllowed(%q) = %v, want %v", tc.contentType, got, tc.want)
+		}
+	}
+	if attachmentTypeAllowed("image/png", nil) {
+		t.Error("empty allow list must allow nothing")
+	}
+}
+
+func TestAttachmentFilename(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want string
+	}{
+		{"screenshot.png", "screenshot.png"},
+		{"../../etc/passwd", "passwd"},
+		{"/var/log/app.log", "app.log"},
+		{`C:\Users\ivan\Desktop\отчёт.pdf`, "отчёт.pdf"},
+		{`..\..\boot.ini`, "boot.ini"},
+		{"evil\r\nX-Injected: 1.txt", "evilX-Injected: 1.txt"},
+		{"tab\tand\x00null.txt", "tabandnull.txt"},
+		{"", "file"},
+		{".", "file"},
+		{"..", "file"},
+		{"/", "file"},
+		{"dir/", "dir"},
+		{"\x01\x02", "file"},
+		{strings.Repeat("я", maxAttachmentFilename+10), strings.Repeat("я", maxAttachmentFilename)},
+	} {
+		if got := attachmentFilename(tc.name); got != tc.want {
+			t.Errorf("attachmentFilename(%q) = %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+// multipartFile собирает multipart-форму с одним файлом и возвращает его заголовок, как его видит обработчик
+func multipartFile(t *testing.T, filename, declaredType string, content []byte) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", `form-data; name="`+attachmentFormField+`"; filename="`+filename+`"`)
+	header.Set("Content-Type", declaredType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatalf("create part: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	if err := req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatalf("parse multipart form: %v", err)
+	}
+	return req.MultipartForm.File[attachmentFormField][0]
+}
+
+func TestStoreAttachmentChecksContent(t *testing.T) {
+	pngHeader := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	cfg := &config.Config{AttachmentAllowedTypes: []string{"image/*", "application/pdf", "text/plain"}}
+
+	for _, tc := range []struct {
+		name         string
+		declaredType string
+		content      []byte
+		wantType     string // пусто — файл должен быть отклонён
+	}{
+		{"png", "image/png", pngHeader, "image/png"},
+		{"png declared as octet-stream", "application/octet-stream", pngHeader, "image/png"},
+		{"html declared as png", "image/png", []byte("<!DOCTYPE html><html><script>alert(1)</script></html>"), ""},
+		{"html without doctype declared as png", "image/png", []byte("<script>alert(document.cookie)</script>"), ""},
+		{"svg declared as image", "image/svg+xml", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), ""},
+		{"plain text", "text/plain", []byte("2024-03-01 12:00:00 ERROR login failed"), "text/plain"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := storage.NewLocal(dir)
+			if err != nil {
+				t.Fatalf("NewLocal: %v", err)
+			}
+			file := multipartFile(t, "screenshot.png", tc.declaredType, tc.content)
+
+			attachments, err := storeAttachments(context.Background(), store, cfg, 42, []*multipart.FileHeader{file})
+			if tc.wantType == "" {
+				var rejected *attachmentError
+				if !errors.As(err, &rejected) || rejected.status != http.StatusUnsupportedMediaType {
+					t.Fatalf("error = %v, want 415 attachmentError", err)
+				}
+				if entries, _ := os.ReadDir(filepath.Join(dir, "tickets", "42")); len(entries) != 0 {
+					t.Fatalf("rejected file was stored: %v", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("storeAttachments: %v", err)
+			}
+			if len(attachments) != 1 || attachments[0].ContentType != tc.wantType || attachments[0].Size != int64(len(tc.content)) {
+				t.Fatalf("attachments = %+v, want one %s of %d bytes", attachments, tc.wantType, len(tc.content))
+			}
+		})
+	}
+}
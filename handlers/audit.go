@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// auditListResponse — конверт ответа со страницей журнала аудита
+type auditListResponse struct {
+	Items []models.AuditLog `json:"items"`
+	Total int64             `json:"total"`
+}
+
+// ListAuditLog godoc
+// @Summary Журнал аудита
+// @Description Возвращает записи журнала аудита, новые сверху: отказы публичных эндпоинтов (неверный ключ бота, блоклист, превышение лимита) и изменения блоклиста. Об отказах по одному IP или telegram_id пишется не больше одной записи за окно лимита. Требуется право operators.manage
+// @Tags admin
+// @Produce json
+// @Param action query string false "Действие, например public.rate_limited"
+// @Param ip query string false "IP"
+// @Param telegram_id query string false "Telegram ID"
+// @Param created_from query string false "Не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Не позже (RFC3339 или YYYY-MM-DD)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} auditListResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func ListAuditLog(c *gin.Context, db *gorm.DB) {
+	limit, offset := defaultTicketPageSize, 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit: " + raw})
+			return
+		}
+		if n > maxTicketPageSize {
+			n = maxTicketPageSize
+		}
+		limit = n
+	}
+	if raw := c.Query("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset: " + raw})
+			return
+		}
+		offset = n
+	}
+	createdFrom, err := parseTimeParam(c, "created_from", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	createdTo, err := parseTimeParam(c, "created_to", true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := db.Model(&models.AuditLog{})
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if ip := c.Query("ip"); ip != "" {
+		query = query.Where("ip = ?", ip)
+	}
+	if telegramID := c.Query("telegram_id"); telegramID != "" {
+		query = query.Where("telegram_id = ?", telegramID)
+	}
+	if createdFrom != nil {
+		query = query.Where("created_at >= ?", *createdFrom)
+	}
+	if createdTo != nil {
+		query = query.Where("created_at <= ?", *createdTo)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	items := []models.AuditLog{}
+	if err := query.Order("id desc").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+
+	c.JSON(http.StatusOK, auditListResponse{Items: items, Total: total})
+}
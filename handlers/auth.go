@@ -3,20 +3,24 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"helpdesk-api/auth"
 	"helpdesk-api/config"
 	"helpdesk-api/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
 // TokenInput структура для входных данных получения токена пользователя
 type TokenInput struct {
-	TelegramID string `json:"telegram_id" binding:"required" example:"88376478"`
+	TelegramID string `json:"telegram_id" binding:"required" example:"88376478"`              // Только цифры
+	Stand      string `json:"stand" binding:"omitempty,oneof=dev ift psi prom" example:"ift"` // Стенд; обязателен при политике whitelist
 }
 
 // OperatorLoginInput структура для входных данных логина оператора
@@ -25,15 +29,33 @@ type OperatorLoginInput struct {
 	Password string `json:"password" binding:"required" example:"securepassword"`
 }
 
+// RefreshInput структура для входных данных обновления токенов
+type RefreshInput struct {
+	Refresh string `json:"refresh" binding:"required" example:"3q2-7w..."`
+}
+
+// logoutInput — необязательное тело запроса логаута
+type logoutInput struct {
+	Refresh string `json:"refresh" example:"3q2-7w..."` // Refresh-токен сессии; его цепочка тоже будет отозвана
+}
+
+// TokenResponse — пара токенов, выдаваемая при входе и обновлении
+type TokenResponse struct {
+	Access    string `json:"access"`     // Короткоживущий JWT
+	Refresh   string `json:"refresh"`    // Одноразовый refresh-токен для получения новой пары
+	ExpiresIn int64  `json:"expires_in"` // Срок жизни access-токена в секундах
+}
+
 // RegisterConsumer godoc
 // @Summary Получить JWT-токен для пользователя
-// @Description Регистрирует или возвращает токен для пользователя по Telegram ID
+// @Description Регистрирует или возвращает пару токенов (access + refresh) для пользователя по Telegram ID (положительное число без ведущих нулей). Стенд записывается в токен (claim stand), и тикеты пользователя создаются для этого стенда. При политике CONSUMER_TOKEN_POLICY=whitelist стенд обязателен, а токен выдаётся, только если доступ пользователя к стенду одобрен в whitelist; после отклонения или отзыва доступа токены для стенда перестают действовать
 // @Tags auth
 // @Accept json
 // @Produce json
-// @Param input body TokenInput true "Telegram ID пользователя"
-// @Success 200 {object} map[string]string "access: JWT-токен"
+// @Param input body TokenInput true "Telegram ID пользователя и стенд"
+// @Success 200 {object} TokenResponse
 // @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /consumers/token/ [post]
 func RegisterConsumer(c *gin.Context, db *gorm.DB, cfg *config.Config) {
@@ -42,6 +64,26 @@ func RegisterConsumer(c *gin.Context, db *gorm.DB, cfg *config.Config) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !models.ValidTelegramID(input.TelegramID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Некорректный telegram_id"})
+		return
+	}
+
+	if cfg.ConsumerTokenPolicy == config.ConsumerPolicyWhitelist {
+		if input.Stand == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не указан стенд"})
+			return
+		}
+		approved, err := consumerStandApproved(db, input.TelegramID, input.Stand)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist"})
+			return
+		}
+		if !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Доступ к стенду не одобрен"})
+			return
+		}
+	}
 
 	var user models.User
 	if err := db.Where("telegram_id = ?", input.TelegramID).First(&user).Error; err != nil {
@@ -51,25 +93,26 @@ func RegisterConsumer(c *gin.Context, db *gorm.DB, cfg *config.Config) {
 		db.Create(&user)
 	}
 
-	token, err := generateJWT(user, "user", cfg.JWTSecret)
+	tokens, err := issueTokens(db, cfg, user, "user", input.Stand, "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать токен"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"access": token})
+	c.JSON(http.StatusOK, tokens)
 }
 
 // Login godoc
 // @Summary Логин оператора
-// @Description Авторизует оператора по логину и паролю, возвращает JWT-токен
+// @Description Авторизует оператора по логину и паролю, возвращает пару токенов (access + refresh)
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param input body OperatorLoginInput true "Данные оператора"
-// @Success 200 {object} map[string]string "access: JWT-токен"
+// @Success 200 {object} TokenResponse
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Router /token/ [post]
 func Login(c *gin.Context, db *gorm.DB, cfg *config.Config) {
@@ -90,27 +133,205 @@ func Login(c *gin.Context, db *gorm.DB, cfg *config.Config) {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный логин или пароль"})
 		return
 	}
+	if operator.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Учётная запись отключена"})
+		return
+	}
 
-	token, err := generateJWT(operator, operator.Role, cfg.JWTSecret)
+	tokens, err := issueTokens(db, cfg, operator, operator.Role, "", "")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать токен"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"access": token})
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RefreshTokens godoc
+// @Summary Обновить пару токенов
+// @Description Обменивает refresh-токен пользователя или оператора на новую пару токенов. Refresh-токен одноразовый: повторное предъявление уже использованного токена считается кражей и отзывает всю цепочку сессии
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body RefreshInput true "Refresh-токен"
+// @Success 200 {object} TokenResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /token/refresh/ [post]
+func RefreshTokens(c *gin.Context, db *gorm.DB, cfg *config.Config) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var stored models.RefreshToken
+	if err := db.Where("token_hash = ?", auth.HashRefreshToken(input.Refresh)).First(&stored).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Неверный refresh-токен"})
+		return
+	}
+	if stored.RevokedAt != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh-токен отозван"})
+		return
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh-токен истёк"})
+		return
+	}
+
+	// Помечаем токен использованным; условие used_at IS NULL не даёт обменять его дважды
+	result := db.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", stored.ID).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить токен"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		// Токен уже обменивали — его кто-то скопировал. Отзываем всю цепочку.
+		if err := revokeTokenFamily(db, stored.FamilyID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось отозвать сессию"})
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh-токен уже использован, сессия отозвана"})
+		return
+	}
+
+	entity, role, stand, err := loadTokenSubject(db, stored.Subject)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Владелец токена не найден"})
+		return
+	}
+
+	// Отзыв доступа и так отзывает refresh-токены стенда; проверка страхует от смены политики
+	// и от токенов, выданных без стенда
+	if user, ok := entity.(models.User); ok && cfg.ConsumerTokenPolicy == config.ConsumerPolicyWhitelist {
+		approved, err := consumerStandApproved(db, user.TelegramID, stand)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist"})
+			return
+		}
+		if !approved {
+			if err := revokeTokenFamily(db, stored.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось отозвать сессию"})
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Доступ к стенду не одобрен"})
+			return
+		}
+	}
+
+	tokens, err := issueTokens(db, cfg, entity, role, stand, stored.FamilyID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось создать токен"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// issueTokens выдаёт access-токен и новый refresh-токен в цепочке familyID
+// (пустой familyID начинает новую цепочку, т.е. новую сессию). stand — стенд пользователя, для операторов пуст
+func issueTokens(db *gorm.DB, cfg *config.Config, entity interface{}, role string, stand string, familyID string) (*TokenResponse, error) {
+	ttl := cfg.AccessTokenTTL
+	if ttl <= 0 || ttl > auth.MaxTokenTTL {
+		ttl = auth.MaxTokenTTL
+	}
+	access, err := generateJWT(entity, role, stand, cfg.JWTSecret, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	var subject string
+	switch e := entity.(type) {
+	case models.User:
+		subject = auth.UserSubject(e.TelegramID, stand)
+	case models.Operator:
+		subject = auth.OperatorSubject(e.Username)
+	}
+
+	refresh, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	if familyID == "" {
+		familyID = uuid.New().String()
+	}
+	record := models.RefreshToken{
+		FamilyID:  familyID,
+		TokenHash: hash,
+		Subject:   subject,
+		ExpiresAt: time.Now().Add(cfg.RefreshTokenTTL),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{Access: access, Refresh: refresh, ExpiresIn: int64(ttl.Seconds())}, nil
+}
+
+// loadTokenSubject находит пользователя или оператора по субъекту refresh-токена.
+// Для пользователя возвращает и стенд, для которого выдан токен
+func loadTokenSubject(db *gorm.DB, subject string) (entity interface{}, role string, stand string, err error) {
+	kind, id, _ := strings.Cut(subject, ":")
+	switch kind {
+	case "user":
+		telegramID, stand, _ := strings.Cut(id, "@")
+		var user models.User
+		if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
+			return nil, "", "", err
+		}
+		return user, "user", stand, nil
+	case "operator":
+		var operator models.Operator
+		if err := db.Where("username = ?", id).First(&operator).Error; err != nil {
+			return nil, "", "", err
+		}
+		if operator.Disabled {
+			return nil, "", "", fmt.Errorf("оператор %s отключён", operator.Username)
+		}
+		return operator, operator.Role, "", nil
+	default:
+		return nil, "", "", fmt.Errorf("неизвестный субъект токена: %s", subject)
+	}
+}
+
+// consumerStandApproved сообщает, одобрен ли пользователю доступ к стенду и не истёк ли он
+func consumerStandApproved(db *gorm.DB, telegramID, stand string) (bool, error) {
+	if stand == "" {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&models.Whitelist{}).
+		Where("telegram_id = ? AND \"from\" = ? AND permission = ?", telegramID, stand, models.WhitelistApprove).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// revokeTokenFamily отзывает все refresh-токены цепочки
+func revokeTokenFamily(db *gorm.DB, familyID string) error {
+	return db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
 }
 
 // Обновленный generateJWT для поддержки ролей
-func generateJWT(entity interface{}, role string, secret string) (string, error) {
+func generateJWT(entity interface{}, role string, stand string, secret string, ttl time.Duration) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"role": role,
-		"exp":  time.Now().Add(time.Hour * 24).Unix(),
-		"iat":  time.Now().Unix(),
+		"exp":  now.Add(ttl).Unix(),
+		"iat":  float64(now.UnixMilli()) / 1000, // С миллисекундами, чтобы повторный вход сразу после отзыва не попадал под него
+		"jti":  uuid.New().String(),             // Идентификатор токена для отзыва
 	}
 
 	switch e := entity.(type) {
 	case models.User:
 		claims["telegram_id"] = e.TelegramID
+		if stand != "" {
+			claims["stand"] = stand
+		}
 	case models.Operator:
 		claims["username"] = e.Username
 	default:
@@ -122,45 +343,95 @@ func generateJWT(entity interface{}, role string, secret string) (string, error)
 }
 
 // Logout godoc
-// @Summary Выход оператора
-// @Description Подтверждает выход оператора; клиент должен удалить токен
+// @Summary Выход
+// @Description Отзывает текущий токен пользователя или оператора; после выхода токен больше не принимается. Если в теле передан refresh-токен, отзывается и вся его цепочка
 // @Tags auth
+// @Accept json
 // @Produce json
+// @Param input body logoutInput false "Refresh-токен текущей сессии"
 // @Success 200 {object} map[string]string "message: Успешный выход"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
 // @Security BearerAuth
 // @Router /logout/ [post]
-func Logout(c *gin.Context, db *gorm.DB, cfg *config.Config) {
-	role, exists := c.Get("role")
-	if !exists || role != "operator" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Доступ только для операторов"})
+func Logout(c *gin.Context, db *gorm.DB, cfg *config.Config, revocations *auth.RevocationStore) {
+	claims, ok := currentClaims(c)
+	if !ok {
 		return
 	}
 
-	// Здесь можно добавить дополнительную логику, если нужно
-	// Например, логирование выхода оператора
-	username, _ := c.Get("username")
-	c.JSON(http.StatusOK, gin.H{"message": "Успешный выход", "username": username})
-}
+	// Тело необязательное: старые клиенты вызывают логаут без него
+	var input logoutInput
+	_ = c.ShouldBindJSON(&input)
+	if input.Refresh != "" {
+		var stored models.RefreshToken
+		err := db.Where("token_hash = ? AND subject = ?", auth.HashRefreshToken(input.Refresh), auth.Subject(claims)).First(&stored).Error
+		if err == nil {
+			if err := revokeTokenFamily(db, stored.FamilyID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось отозвать сессию"})
+				return
+			}
+		}
+	}
 
-// CloseTicketOperator — закрытие тикета (только для операторов)
-func CloseTicketOperator(c *gin.Context, db *gorm.DB) {
-	ticketID := c.Param("ticket_id")
+	expiresAt, _ := claims["exp"].(float64)
+	if err := revocations.RevokeToken(c.GetString("jti"), auth.Subject(claims), time.Unix(int64(expiresAt), 0)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось отозвать токен"})
+		return
+	}
 
-	var ticket models.Ticket
-	if err := db.Where("id = ?", ticketID).First(&ticket).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+	response := gin.H{"message": "Успешный выход"}
+	if username := c.GetString("username"); username != "" {
+		response["username"] = username
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAllSessions godoc
+// @Summary Завершить все сессии оператора
+// @Description Отзывает все токены текущего оператора, включая тот, с которым выполнен запрос
+// @Tags auth
+// @Produce json
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/sessions/revoke-all [post]
+func RevokeAllSessions(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore) {
+	claims, ok := currentClaims(c)
+	if !ok {
 		return
 	}
 
-	if ticket.Status == "CLOSED" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket already closed"})
+	if err := revocations.RevokeSubject(auth.Subject(claims)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось завершить сессии"})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Все сессии завершены"})
+}
 
-	ticket.Status = "CLOSED"
-	if err := db.Save(&ticket).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close ticket"})
+// currentClaims возвращает claims токена текущего запроса
+func currentClaims(c *gin.Context) (jwt.MapClaims, bool) {
+	value, exists := c.Get("claims")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return nil, false
+	}
+	claims, ok := value.(jwt.MapClaims)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid claims type"})
+		return nil, false
+	}
+	return claims, true
+}
+
+// CloseTicketOperator — закрытие тикета (только для операторов)
+func CloseTicketOperator(c *gin.Context, db *gorm.DB) {
+	ticket, ok := findTicketForActor(c, db)
+	if !ok {
+		return
+	}
+	if !applyTicketTransition(c, db, ticket, models.TicketStatusClosed) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Ticket closed successfully"})
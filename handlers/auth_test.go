@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRegisterConsumerRejectsForgedTelegramID(t *testing.T) {
+	// Проверка идёт до обращения к базе, поэтому db не нужна
+	router := gin.New()
+	router.POST("/consumers/token/", func(c *gin.Context) {
+		RegisterConsumer(c, nil, &config.Config{ConsumerTokenPolicy: config.ConsumerPolicyOpen})
+	})
+
+	for _, telegramID := range []string{
+		models.EmailUserTelegramID("victim@example.com"),
+		"email:",
+		"-100123",
+		"088376478",
+		"123 456",
+		"１２３", // полноширинные цифры
+		strings.Repeat("9", 21),
+	} {
+		body := `{"telegram_id":"` + telegramID + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/consumers/token/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("telegram_id %q: status = %d, want 400: %s", telegramID, w.Code, w.Body)
+		}
+	}
+}
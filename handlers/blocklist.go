@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"helpdesk-api/audit"
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// createBlocklistInput структура для входных данных блокировки
+type createBlocklistInput struct {
+	Kind      string     `json:"kind" binding:"required,oneof=telegram_id ip" example:"telegram_id"`
+	Value     string     `json:"value" binding:"required" example:"88376478"` // telegram_id, IP-адрес или подсеть CIDR
+	Reason    string     `json:"reason" example:"Спам заявками"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2026-12-31T23:59:59Z"` // Пусто — бессрочно
+}
+
+// ListBlocklist godoc
+// @Summary Блоклист публичных эндпоинтов
+// @Description Возвращает заблокированные telegram_id и IP, включая истёкшие блокировки
+// @Tags blocklist
+// @Produce json
+// @Param kind query string false "Вид записи" Enums(telegram_id, ip)
+// @Success 200 {array} models.BlocklistEntry
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/blocklist/ [get]
+func ListBlocklist(c *gin.Context, db *gorm.DB) {
+	query := db.Order("id desc")
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	entries := []models.BlocklistEntry{}
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch blocklist"})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// CreateBlocklistEntry godoc
+// @Summary Заблокировать telegram_id или IP
+// @Description Запрещает telegram_id, IP-адресу или подсети (CIDR) подавать заявки в whitelist и получать токен пользователя. Отказы заблокированным пишутся в журнал аудита. Блокировка действует на всех экземплярах сервиса в течение 30 секунд
+// @Tags blocklist
+// @Accept json
+// @Produce json
+// @Param input body createBlocklistInput true "Блокировка"
+// @Success 201 {object} models.BlocklistEntry
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/blocklist/ [post]
+func CreateBlocklistEntry(c *gin.Context, db *gorm.DB, blocklist *auth.BlocklistStore) {
+	var input createBlocklistInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	value := strings.TrimSpace(input.Value)
+	if input.Kind == models.BlockKindIP {
+		// Храним в каноническом виде, чтобы один адрес нельзя было заблокировать дважды в разной записи
+		prefix, err := auth.ParseBlockedIP(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		value = prefix.String()
+		if prefix.IsSingleIP() {
+			value = prefix.Addr().String()
+		}
+	}
+
+	entry := models.BlocklistEntry{
+		Kind:      input.Kind,
+		Value:     value,
+		Reason:    input.Reason,
+		CreatedBy: c.GetString("username"),
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := db.Create(&entry).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Entry is already in the blocklist"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create blocklist entry"})
+		return
+	}
+	reloadBlocklist(blocklist)
+
+	record := audit.FromRequest(c, models.AuditBlocklistAdded)
+	record.Detail = fmt.Sprintf("%s %s: %s", entry.Kind, entry.Value, entry.Reason)
+	if entry.Kind == models.BlockKindTelegramID {
+		record.TelegramID = entry.Value
+	}
+	audit.Record(db, record)
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// DeleteBlocklistEntry godoc
+// @Summary Снять блокировку
+// @Description Удаляет запись из блоклиста
+// @Tags blocklist
+// @Produce json
+// @Param id path int true "ID записи"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/blocklist/{id} [delete]
+func DeleteBlocklistEntry(c *gin.Context, db *gorm.DB, blocklist *auth.BlocklistStore) {
+	var entry models.BlocklistEntry
+	if err := db.First(&entry, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Blocklist entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load blocklist entry"})
+		}
+		return
+	}
+	if err := db.Delete(&entry).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete blocklist entry"})
+		return
+	}
+	reloadBlocklist(blocklist)
+
+	record := audit.FromRequest(c, models.AuditBlocklistRemoved)
+	record.Detail = fmt.Sprintf("%s %s", entry.Kind, entry.Value)
+	if entry.Kind == models.BlockKindTelegramID {
+		record.TelegramID = entry.Value
+	}
+	audit.Record(db, record)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Blocklist entry deleted"})
+}
+
+// reloadBlocklist сразу применяет изменения блоклиста на этом экземпляре;
+// остальные подхватят их при плановом обновлении
+func reloadBlocklist(blocklist *auth.BlocklistStore) {
+	if err := blocklist.Reload(); err != nil {
+		log.Printf("Failed to reload blocklist: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"helpdesk-api/config"
+	"helpdesk-api/email"
+	"helpdesk-api/events"
+	"helpdesk-api/models"
+	"helpdesk-api/outbox"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TicketSourceEmail — источник тикетов, открытых письмом
+const TicketSourceEmail = "email"
+
+// OutboxKindEmailReply — тип сообщения outbox с ответом оператора, который уходит письмом
+const OutboxKindEmailReply = "email.reply"
+
+// HeaderEmailIngestToken — заголовок с токеном приёма входящих писем
+const HeaderEmailIngestToken = "X-Email-Ingest-Token"
+
+// emailIngestMaxBody — предел размера входящего письма вместе с вложениями
+const emailIngestMaxBody = 10 << 20
+
+// emailReferencesLimit — сколько последних писем цепочки перечисляется в References ответа
+const emailReferencesLimit = 10
+
+var (
+	// emailSubjectTagRe находит метку тикета [#<short_id>] в теме письма
+	emailSubjectTagRe = regexp.MustCompile(`\[#([0-9a-fA-F-]{36})\]`)
+	// emailMessageIDTicketRe находит short_id тикета в Message-ID наших ответов (см. DeliverEmailReply)
+	emailMessageIDTicketRe = regexp.MustCompile(`^<ticket-([0-9a-fA-F-]{36})\.`)
+	// emailReplyPrefixRe снимает Re:/Fwd: в начале темы
+	emailReplyPrefixRe = regexp.MustCompile(`(?i)^((re|fwd?|aw|отв|пересл)(\[\d+\])?:\s*)+`)
+)
+
+// emailReplyPayload — ответ оператора по почте в outbox. Заголовки письма собираются при доставке
+type emailReplyPayload struct {
+	TicketID  uint   `json:"ticketId"`
+	MessageID uint   `json:"messageId"`
+	To        string `json:"to"`
+}
+
+// emailIngestResponse — итог приёма письма
+type emailIngestResponse struct {
+	Status    string `json:"status" example:"created"` // created, appended, duplicate или ignored
+	TicketID  uint   `json:"ticket_id,omitempty"`
+	MessageID uint   `json:"message_id,omitempty"`
+}
+
+// emailIngestResult — итог записи письма в тикет
+type emailIngestResult struct {
+	Duplicate      bool
+	Ticket         models.Ticket
+	Message        *models.Message // Сообщение в существующем тикете; nil, если письмо открыло новый тикет
+	PreviousStatus string          // Статус тикета до переоткрытия; пусто, если тикет не переоткрывался
+}
+
+// IngestEmail godoc
+// @Summary Приём входящего письма
+// @Description Принимает письмо целиком (RFC 5322, MIME) — например, от почтового сервера через pipe или от почтового провайдера. Отправитель находится или создаётся по адресу From. Письмо продолжает тикет отправителя, если In-Reply-To/References ссылаются на письма тикета, а для отправителя, подтверждённого почтовым релеем (Authentication-Results с authserv-id из EMAIL_AUTHSERV_ID), — и по метке [#<short_id>] в теме; закрытый тикет переоткрывается. Иначе открывается новый тикет с source=email. SPF/DKIM/DMARC сервис сам не проверяет — это задача релея. Повтор письма с тем же Message-ID и автоответы пропускаются. Требуется заголовок X-Email-Ingest-Token
+// @Tags channels
+// @Accept message/rfc822
+// @Produce json
+// @Param X-Email-Ingest-Token header string true "Токен приёма писем"
+// @Success 200 {object} emailIngestResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Email channel is disabled"
+// @Failure 413 {object} map[string]string "Request Entity Too Large"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /channels/email/inbound [post]
+func IngestEmail(c *gin.Context, db *gorm.DB, cfg *config.Config) {
+	if cfg.EmailIngestToken == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Email channel is disabled"})
+		return
+	}
+	token := c.GetHeader(HeaderEmailIngestToken)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.EmailIngestToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ingest token"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, emailIngestMaxBody)
+	inbound, err := email.Parse(c.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Email is too large"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if inbound.AutoSubmitted {
+		c.JSON(http.StatusOK, emailIngestResponse{Status: "ignored"})
+		return
+	}
+	if inbound.Text == "" {
+		inbound.Text = emailReplyPrefixRe.ReplaceAllString(inbound.Subject, "")
+	}
+	if strings.TrimSpace(inbound.Text) == "" {
+		c.JSON(http.StatusOK, emailIngestResponse{Status: "ignored"})
+		return
+	}
+
+	result, err := ingestEmailMessage(db, inbound, inbound.SenderVerified(cfg.EmailAuthservID))
+	if err != nil {
+		log.Printf("Failed to ingest email %s from %s: %v", inbound.MessageID, inbound.From.Address, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process email"})
+		return
+	}
+
+	switch {
+	case result.Duplicate:
+		c.JSON(http.StatusOK, emailIngestResponse{Status: "duplicate"})
+	case result.Message != nil:
+		if result.PreviousStatus != "" {
+			events.Publish(events.TypeTicketStatusChanged, result.Ticket.ID, result.Ticket.UserID,
+				gin.H{"previous_status": result.PreviousStatus, "ticket": result.Ticket})
+		}
+		events.Publish(events.TypeMessageCreated, result.Ticket.ID, result.Ticket.UserID, *result.Message)
+		c.JSON(http.StatusOK, emailIngestResponse{Status: "appended", TicketID: result.Ticket.ID, MessageID: result.Message.ID})
+	default:
+		events.Publish(events.TypeTicketCreated, result.Ticket.ID, result.Ticket.UserID, result.Ticket)
+		c.JSON(http.StatusOK, emailIngestResponse{Status: "created", TicketID: result.Ticket.ID})
+	}
+}
+
+// ingestEmailMessage в одной транзакции находит или создаёт отправителя и добавляет письмо
+// в тикет его цепочки либо открывает новый тикет. verified — адрес From подтверждён релеем
+func ingestEmailMessage(db *gorm.DB, inbound *email.Inbound, verified bool) (*emailIngestResult, error) {
+	result := &emailIngestResult{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if inbound.MessageID != "" {
+			seen, err := emailAlreadyIngested(tx, inbound.MessageID)
+			if err != nil {
+				return err
+			}
+			if seen {
+				result.Duplicate = true
+				return nil
+			}
+		}
+
+		address := inbound.From.Address
+		var user models.User
+		err := tx.Where(models.User{Email: address}).
+			Attrs(models.User{TelegramID: models.EmailUserTelegramID(address)}).
+			FirstOrCreate(&user).Error
+		if err != nil {
+			return err
+		}
+		// Блокировка пользователя не даёт двум его письмам одновременно открыть два тикета
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+			return err
+		}
+
+		ticket, err := findEmailThreadTicket(tx, user.ID, inbound, verified)
+		if err != nil {
+			return err
+		}
+		if ticket == nil {
+			subject := emailReplyPrefixRe.ReplaceAllString(inbound.Subject, "")
+			if subject == "" {
+				subject = telegramSubject(inbound.Text)
+			}
+			result.Ticket = models.Ticket{
+				UserID:      user.ID,
+				Subject:     subject,
+				Description: inbound.Text,
+				Source:      TicketSourceEmail,
+				Status:      models.TicketStatusNew,
+				ExternalID:  inbound.MessageID,
+			}
+			return tx.Create(&result.Ticket).Error
+		}
+		result.Ticket = *ticket
+
+		// Ответ в цепочку закрытого тикета возвращает его в работу
+		if ticket.Status == models.TicketStatusResolved || ticket.Status == models.TicketStatusClosed {
+			previous := ticket.Status
+			if err := result.Ticket.Transition(models.TicketStatusReopened, models.RoleUser); err != nil {
+				return err
+			}
+			err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(map[string]interface{}{
+				"status":    result.Ticket.Status,
+				"closed_at": result.Ticket.ClosedAt,
+				"closed_by": result.Ticket.ClosedBy,
+			}).Error
+			if err != nil {
+				return err
+			}
+			result.PreviousStatus = previous
+		}
+
+		message := models.Message{
+			TicketID:   ticket.ID,
+			Sender:     "user",
+			Recipient:  "operator",
+			Content:    inbound.Text,
+			ExternalID: inbound.MessageID,
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Update("last_message_at", message.Timestamp).Error; err != nil {
+			return err
+		}
+		result.Message = &message
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// emailAlreadyIngested сообщает, что письмо с таким Message-ID уже записано
+func emailAlreadyIngested(tx *gorm.DB, messageID string) (bool, error) {
+	var count int64
+	if err := tx.Model(&models.Ticket{}).Where("external_id = ?", messageID).Count(&count).Error; err != nil || count > 0 {
+		return count > 0, err
+	}
+	err := tx.Model(&models.Message{}).Where("external_id = ?", messageID).Count(&count).Error
+	return count > 0, err
+}
+
+// findEmailThreadTicket ищет тикет пользователя, к которому относится письмо: по Message-ID писем тикета
+// в In-Reply-To/References, а если verified — ещё по метке в теме и по short_id в Message-ID наших ответов.
+// Метку и short_id знает любой, кто видел тему письма, поэтому письму с поддельным From они тикет
+// не откроют: такое письмо попадёт в новый тикет.
+// Из нескольких кандидатов берётся последний по активности; чужие тикеты не рассматриваются
+func findEmailThreadTicket(tx *gorm.DB, userID uint, inbound *email.Inbound, verified bool) (*models.Ticket, error) {
+	threadIDs := inbound.ThreadIDs()
+	var shortIDs []string
+	if verified {
+		if match := emailSubjectTagRe.FindStringSubmatch(inbound.Subject); match != nil {
+			shortIDs = append(shortIDs, strings.ToLower(match[1]))
+		}
+		for _, id := range threadIDs {
+			if match := emailMessageIDTicketRe.FindStringSubmatch(id); match != nil {
+				shortIDs = append(shortIDs, strings.ToLower(match[1]))
+			}
+		}
+	}
+	if len(shortIDs) == 0 && len(threadIDs) == 0 {
+		return nil, nil
+	}
+
+	conditions := tx.Session(&gorm.Session{NewDB: true})
+	if len(shortIDs) > 0 {
+		conditions = conditions.Or("short_id IN ?", shortIDs)
+	}
+	if len(threadIDs) > 0 {
+		conditions = conditions.
+			Or("external_id IN ?", threadIDs).
+			Or("id IN (?)", tx.Model(&models.Message{}).Select("ticket_id").Where("external_id IN ?", threadIDs))
+	}
+
+	var ticket models.Ticket
+	err := tx.Where("user_id = ?", userID).Where(conditions).Order("last_message_at desc").First(&ticket).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// DeliverEmailReply возвращает обработчик outbox, отправляющий ответ оператора письмом в цепочку тикета.
+// Message-ID ответа создаётся при первой попытке и сохраняется в сообщении, поэтому повторные попытки
+// отправляют то же письмо, и почтовый клиент пользователя не покажет дубликат
+func DeliverEmailReply(db *gorm.DB, sender *email.Sender) outbox.Handler {
+	return func(ctx context.Context, msg models.OutboxMessage) error {
+		var payload emailReplyPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid email reply payload: %w", err)
+		}
+
+		err := sendEmailReply(ctx, db.WithContext(ctx), sender, payload)
+		recordReplyAttempt(db, payload.MessageID, msg.Attempts+1, err)
+		return err
+	}
+}
+
+// sendEmailReply выполняет одну попытку отправки ответа по почте
+func sendEmailReply(ctx context.Context, db *gorm.DB, sender *email.Sender, payload emailReplyPayload) error {
+	if !sender.Configured() {
+		return email.ErrNotConfigured
+	}
+	var ticket models.Ticket
+	if err := db.First(&ticket, payload.TicketID).Error; err != nil {
+		return fmt.Errorf("failed to load ticket %d: %w", payload.TicketID, err)
+	}
+	var message models.Message
+	if err := db.First(&message, payload.MessageID).Error; err != nil {
+		return fmt.Errorf("failed to load message %d: %w", payload.MessageID, err)
+	}
+
+	if message.ExternalID == "" {
+		message.ExternalID = sender.NewMessageID(fmt.Sprintf("ticket-%s.msg-%d", ticket.ShortID, message.ID))
+		if err := db.Model(&message).Update("external_id", message.ExternalID).Error; err != nil {
+			return err
+		}
+	}
+
+	// Цепочка: письмо, открывшее тикет, и письма тикета до этого ответа
+	var earlier []string
+	err := db.Model(&models.Message{}).
+		Where("ticket_id = ? AND id < ? AND external_id <> ''", ticket.ID, message.ID).
+		Order("id desc").
+		Limit(emailReferencesLimit).
+		Pluck("external_id", &earlier).Error
+	if err != nil {
+		return err
+	}
+	var references []string
+	if ticket.ExternalID != "" {
+		references = append(references, ticket.ExternalID)
+	}
+	for i := len(earlier) - 1; i >= 0; i-- {
+		references = append(references, earlier[i])
+	}
+	var inReplyTo string
+	if len(references) > 0 {
+		inReplyTo = references[len(references)-1]
+	}
+
+	subject := emailReplyPrefixRe.ReplaceAllString(ticket.Subject, "")
+	if !emailSubjectTagRe.MatchString(subject) {
+		subject += " [#" + ticket.ShortID + "]"
+	}
+
+	return sender.Send(ctx, email.Outbound{
+		To:         payload.To,
+		Subject:    "Re: " + subject,
+		Text:       message.Content,
+		MessageID:  message.ExternalID,
+		InReplyTo:  inReplyTo,
+		References: references,
+	})
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	testIngestToken = "ingest-token"
+	testAuthservID  = "mx.helpdesk.local"
+)
+
+// testEmailAddress возвращает уникальный адрес отправителя и удаляет его пользователя и тикеты после теста
+func testEmailAddress(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	address := fmt.Sprintf("user-%d@example.com", time.Now().UnixNano())
+	t.Cleanup(func() {
+		users := db.Model(&models.User{}).Select("id").Where("email = ?", address)
+		tickets := db.Model(&models.Ticket{}).Select("id").Where("user_id IN (?)", users)
+		db.Where("ticket_id IN (?)", tickets).Delete(&models.Message{})
+		db.Where("user_id IN (?)", users).Delete(&models.Ticket{})
+		db.Where("email = ?", address).Delete(&models.User{})
+	})
+	return address
+}
+
+func newEmailTestRouter(db *gorm.DB) *gin.Engine {
+	cfg := &config.Config{EmailIngestToken: testIngestToken, EmailAuthservID: testAuthservID}
+	router := gin.New()
+	router.POST("/channels/email/inbound", func(c *gin.Context) {
+		IngestEmail(c, db, cfg)
+	})
+	return router
+}
+
+// postEmail отправляет письмо в приём и возвращает разобранный ответ
+func postEmail(t *testing.T, router *gin.Engine, lines ...string) emailIngestResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/channels/email/inbound", strings.NewReader(strings.Join(lines, "\r\n")))
+	req.Header.Set(HeaderEmailIngestToken, testIngestToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("ingest: status = %d: %s", w.Code, w.Body)
+	}
+	var response emailIngestResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("ingest response %s: %v", w.Body, err)
+	}
+	return response
+}
+
+func TestIngestEmailThreadingRequiresVerifiedSender(t *testing.T) {
+	db := openTestDB(t)
+	router := newEmailTestRouter(db)
+	address := testEmailAddress(t, db)
+	firstID := fmt.Sprintf("<first-%d@example.com>", time.Now().UnixNano())
+
+	created := postEmail(t, router,
+		"From: "+address,
+		"Subject: Не работает вход",
+		"Message-ID: "+firstID,
+		"",
+		"Ввожу пароль — ничего не происходит",
+	)
+	if created.Status != "created" {
+		t.Fatalf("first email: status = %s, want created", created.Status)
+	}
+	var ticket models.Ticket
+	if err := db.First(&ticket, created.TicketID).Error; err != nil {
+		t.Fatalf("load ticket: %v", err)
+	}
+	tag := " [#" + ticket.ShortID + "]"
+
+	for _, tc := range []struct {
+		name    string
+		headers []string
+		want    string
+	}{
+		{
+			name:    "subject tag from unverified sender",
+			headers: []string{"Subject: Re: Не работает вход" + tag},
+			want:    "created",
+		},
+		{
+			name: "subject tag with foreign Authentication-Results",
+			headers: []string{
+				"Authentication-Results: mx.evil.test; dmarc=pass header.from=example.com",
+				"Subject: Re: Не работает вход" + tag,
+			},
+			want: "created",
+		},
+		{
+			name:    "forged reply Message-ID from unverified sender",
+			headers: []string{"Subject: Re: вход", "In-Reply-To: <ticket-" + ticket.ShortID + ".msg-1.0000@helpdesk.local>"},
+			want:    "created",
+		},
+		{
+			name: "subject tag from verified sender",
+			headers: []string{
+				"Authentication-Results: " + testAuthservID + "; dkim=pass header.d=example.com",
+				"Subject: Re: Не работает вход" + tag,
+			},
+			want: "appended",
+		},
+		{
+			name:    "exact In-Reply-To from unverified sender",
+			headers: []string{"Subject: Re: Не работает вход", "In-Reply-To: " + firstID},
+			want:    "appended",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			lines := append([]string{"From: " + address, fmt.Sprintf("Message-ID: <%d@example.com>", time.Now().UnixNano())}, tc.headers...)
+			response := postEmail(t, router, append(lines, "", "Ещё одно письмо")...)
+			if response.Status != tc.want {
+				t.Fatalf("status = %s, want %s", response.Status, tc.want)
+			}
+			if tc.want == "appended" && response.TicketID != ticket.ID {
+				t.Fatalf("appended to ticket %d, want %d", response.TicketID, ticket.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// endpointSecretResponse — новый секрет стенда, показывается один раз
+type endpointSecretResponse struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name" example:"ift"`
+	Secret string `json:"secret" example:"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"`
+}
+
+// RotateEndpointSecret godoc
+// @Summary Сменить секрет стенда
+// @Description Генерирует новый секрет для HMAC-подписи запросов к стенду и возвращает его. Секрет больше нигде не показывается, его нужно сразу передать на стенд: запросы, подписанные старым секретом, стенд отвергнет
+// @Tags settings
+// @Produce json
+// @Param id path int true "ID стенда"
+// @Success 200 {object} endpointSecretResponse
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/settings/{id}/secret [post]
+func RotateEndpointSecret(c *gin.Context, db *gorm.DB) {
+	var endpoint models.Endpoint
+	if err := db.First(&endpoint, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Endpoint not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load endpoint"})
+		}
+		return
+	}
+
+	secret, err := models.NewEndpointSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	if err := db.Model(&endpoint).Update("secret", secret).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save secret"})
+		return
+	}
+	LoadEndpoints(db)
+
+	c.JSON(http.StatusOK, endpointSecretResponse{ID: endpoint.ID, Name: endpoint.Name, Secret: secret})
+}
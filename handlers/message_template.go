@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultMessageTemplates — тексты на случай, если для решения не заведено ни одного шаблона
+var defaultMessageTemplates = map[string]string{
+	models.WhitelistApprove: "{{.FirstName}}, доступ к стенду {{.From}} одобрен.",
+	models.WhitelistDeny:    "{{.FirstName}}, заявка на доступ к стенду {{.From}} отклонена.",
+	models.WhitelistRevoke:  "{{.FirstName}}, доступ к стенду {{.From}} отозван.",
+}
+
+// createTemplateInput структура для входных данных создания шаблона
+type createTemplateInput struct {
+	Stand    string `json:"stand" binding:"omitempty,oneof=dev ift psi prom" example:"ift"` // Пустой стенд — шаблон по умолчанию
+	Decision string `json:"decision" binding:"required,oneof=approve deny revoke" example:"approve"`
+	Body     string `json:"body" binding:"required" example:"{{.FirstName}}, доступ к стенду {{.From}} открыт"`
+}
+
+// updateTemplateInput структура для входных данных изменения шаблона
+type updateTemplateInput struct {
+	Body string `json:"body" binding:"required" example:"{{.FirstName}}, доступ к стенду {{.From}} открыт"`
+}
+
+// previewTemplateInput структура для входных данных предпросмотра
+type previewTemplateInput struct {
+	WhitelistID uint   `json:"whitelist_id" binding:"required" example:"12"`
+	Decision    string `json:"decision" binding:"required,oneof=approve deny revoke" example:"approve"`
+	Body        string `json:"body" example:"{{.FirstName}}, доступ открыт"` // Черновик; если пуст, берётся действующий шаблон стенда заявки
+}
+
+// previewTemplateResponse — результат предпросмотра
+type previewTemplateResponse struct {
+	Message    string `json:"message"`
+	TemplateID *uint  `json:"template_id"` // ID использованного шаблона; null для черновика или встроенного текста
+}
+
+// ListMessageTemplates godoc
+// @Summary Список шаблонов сообщений
+// @Description Возвращает шаблоны сообщений о решениях по заявкам whitelist
+// @Tags templates
+// @Produce json
+// @Param stand query string false "Стенд"
+// @Param decision query string false "Решение" Enums(approve, deny, revoke)
+// @Success 200 {array} models.MessageTemplate
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/templates/ [get]
+func ListMessageTemplates(c *gin.Context, db *gorm.DB) {
+	query := db.Order("stand asc, decision asc")
+	if stand, ok := c.GetQuery("stand"); ok {
+		query = query.Where("stand = ?", stand)
+	}
+	if decision := c.Query("decision"); decision != "" {
+		query = query.Where("decision = ?", decision)
+	}
+
+	templates := []models.MessageTemplate{}
+	if err := query.Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch templates"})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// CreateMessageTemplate godoc
+// @Summary Создать шаблон сообщения
+// @Description Создаёт шаблон text/template для стенда и решения. В шаблоне доступны поля заявки: {{.FirstName}}, {{.LastName}}, {{.Username}}, {{.TelegramID}}, {{.From}}, {{.Text}}, а также {{.Decision}}
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param input body createTemplateInput true "Шаблон"
+// @Success 201 {object} models.MessageTemplate
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/templates/ [post]
+func CreateMessageTemplate(c *gin.Context, db *gorm.DB) {
+	var input createTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateMessageTemplate(input.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing int64
+	if err := db.Model(&models.MessageTemplate{}).Where("stand = ? AND decision = ?", input.Stand, input.Decision).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Template for this stand and decision already exists"})
+		return
+	}
+
+	tmpl := models.MessageTemplate{Stand: input.Stand, Decision: input.Decision, Body: input.Body}
+	if err := db.Create(&tmpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create template"})
+		return
+	}
+	c.JSON(http.StatusCreated, tmpl)
+}
+
+// UpdateMessageTemplate godoc
+// @Summary Изменить шаблон сообщения
+// @Description Заменяет текст шаблона
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Param input body updateTemplateInput true "Текст шаблона"
+// @Success 200 {object} models.MessageTemplate
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/templates/{id} [put]
+func UpdateMessageTemplate(c *gin.Context, db *gorm.DB) {
+	var input updateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateMessageTemplate(input.Body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tmpl, ok := findMessageTemplateByParam(c, db)
+	if !ok {
+		return
+	}
+	if err := db.Model(tmpl).Update("body", input.Body).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// DeleteMessageTemplate godoc
+// @Summary Удалить шаблон сообщения
+// @Description Удаляет шаблон; после этого для стенда действует шаблон по умолчанию
+// @Tags templates
+// @Produce json
+// @Param id path int true "ID шаблона"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/templates/{id} [delete]
+func DeleteMessageTemplate(c *gin.Context, db *gorm.DB) {
+	tmpl, ok := findMessageTemplateByParam(c, db)
+	if !ok {
+		return
+	}
+	if err := db.Delete(tmpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted"})
+}
+
+// PreviewMessageTemplate godoc
+// @Summary Предпросмотр шаблона сообщения
+// @Description Отрисовывает шаблон на реальной заявке whitelist. Если body пуст, используется шаблон, который сработает для стенда заявки: шаблон стенда, затем шаблон по умолчанию, затем встроенный текст. Если шаблон не отрисовался на этой заявке, показывается встроенный текст и template_id пуст — так же уйдёт и уведомление
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param input body previewTemplateInput true "Заявка, решение и, при необходимости, черновик шаблона"
+// @Success 200 {object} previewTemplateResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/templates/preview [post]
+func PreviewMessageTemplate(c *gin.Context, db *gorm.DB) {
+	var input previewTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var whitelist models.Whitelist
+	if err := db.First(&whitelist, input.WhitelistID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Whitelist entry not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load whitelist entry"})
+		}
+		return
+	}
+
+	if input.Body != "" {
+		message, err := executeMessageTemplate(input.Body, whitelist, input.Decision)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, previewTemplateResponse{Message: message})
+		return
+	}
+
+	message, templateID, err := renderWhitelistMessage(db, whitelist, input.Decision)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render template: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, previewTemplateResponse{Message: message, TemplateID: templateID})
+}
+
+// messageTemplateData — данные, доступные в шаблоне: поля заявки и решение
+type messageTemplateData struct {
+	models.Whitelist
+	Decision string
+}
+
+// renderWhitelistMessage отрисовывает сообщение о решении по заявке: шаблон стенда заявки,
+// иначе шаблон по умолчанию, иначе встроенный текст. Возвращает ID использованного шаблона (nil — встроенный текст)
+func renderWhitelistMessage(db *gorm.DB, whitelist models.Whitelist, decision string) (string, *uint, error) {
+	var templates []models.MessageTemplate
+	err := db.Where("decision = ? AND stand IN ?", decision, []string{whitelist.From, ""}).
+		Order("stand desc").
+		Limit(1).
+		Find(&templates).Error
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(templates) == 0 {
+		return renderMessageTemplate(nil, whitelist, decision)
+	}
+	return renderMessageTemplate(&templates[0], whitelist, decision)
+}
+
+// renderMessageTemplate отрисовывает шаблон tmpl, а если он не задан или упал при отрисовке — встроенный текст.
+// Шаблон проверяется при сохранении, но на реальной заявке всё равно может упасть (например, slice
+// по короткому username); решение по заявке из-за этого срываться не должно
+func renderMessageTemplate(tmpl *models.MessageTemplate, whitelist models.Whitelist, decision string) (string, *uint, error) {
+	if tmpl != nil {
+		message, err := executeMessageTemplate(tmpl.Body, whitelist, decision)
+		if err == nil {
+			return message, &tmpl.ID, nil
+		}
+		log.Printf("Message template %d failed for whitelist entry %d, using built-in text: %v", tmpl.ID, whitelist.ID, err)
+	}
+	body, ok := defaultMessageTemplates[decision]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown decision: %s", decision)
+	}
+	message, err := executeMessageTemplate(body, whitelist, decision)
+	return message, nil, err
+}
+
+func executeMessageTemplate(body string, whitelist models.Whitelist, decision string) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, messageTemplateData{Whitelist: whitelist, Decision: decision}); err != nil {
+		return "", fmt.Errorf("template execution failed: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// validateMessageTemplate проверяет шаблон на пробной заявке, чтобы опечатка в имени поля
+// обнаружилась при сохранении, а не при отправке уведомления
+func validateMessageTemplate(body string) error {
+	sample := models.Whitelist{
+		TelegramID: "123456789",
+		From:       "ift",
+		FirstName:  "Иван",
+		LastName:   "Иванов",
+		Username:   "ivanov",
+		Permission: models.WhitelistPending,
+	}
+	_, err := executeMessageTemplate(body, sample, models.WhitelistApprove)
+	return err
+}
+
+// findMessageTemplateByParam загружает шаблон по :id из пути.
+// При ошибке ответ клиенту уже отправлен.
+func findMessageTemplateByParam(c *gin.Context, db *gorm.DB) (*models.MessageTemplate, bool) {
+	var tmpl models.MessageTemplate
+	if err := db.First(&tmpl, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load template"})
+		}
+		return nil, false
+	}
+	return &tmpl, true
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"testing"
+
+	"helpdesk-api/models"
+)
+
+func TestRenderMessageTemplate(t *testing.T) {
+	whitelist := models.Whitelist{ID: 1, FirstName: "Иван", Username: "ab", From: "ift"}
+	templateID := uint(9)
+
+	for _, tc := range []struct {
+		name     string
+		tmpl     *models.MessageTemplate
+		decision string
+		want     string
+		wantID   *uint
+	}{
+		{"no template", nil, models.WhitelistApprove, "Иван, доступ к стенду ift одобрен.", nil},
+		{
+			"custom template",
+			&models.MessageTemplate{ID: templateID, Body: "@{{.Username}}: {{.Decision}} на {{.From}}"},
+			models.WhitelistDeny, "@ab: deny на ift", &templateID,
+		},
+		{
+			// Проверку при сохранении шаблон прошёл бы: у пробной заявки username длиннее трёх символов
+			"runtime failure falls back",
+			&models.MessageTemplate{ID: templateID, Body: `{{slice .Username 0 3}}, доступ одобрен`},
+			models.WhitelistApprove, "Иван, доступ к стенду ift одобрен.", nil,
+		},
+		{
+			"missing field falls back",
+			&models.MessageTemplate{ID: templateID, Body: "{{.Missing}}"},
+			models.WhitelistRevoke, "Иван, доступ к стенду ift отозван.", nil,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			message, id, err := renderMessageTemplate(tc.tmpl, whitelist, tc.decision)
+			if err != nil {
+				t.Fatalf("renderMessageTemplate: %v", err)
+			}
+			if message != tc.want {
+				t.Errorf("message = %q, want %q", message, tc.want)
+			}
+			if (id == nil) != (tc.wantID == nil) || id != nil && *id != *tc.wantID {
+				t.Errorf("template ID = %v, want %v", id, tc.wantID)
+			}
+		})
+	}
+
+	if _, _, err := renderMessageTemplate(nil, whitelist, "maybe"); err == nil {
+		t.Error("unknown decision rendered without error")
+	}
+}
+
+func TestValidateMessageTemplate(t *testing.T) {
+	for body, valid := range map[string]bool{
+		"{{.FirstName}}, доступ к {{.From}}: {{.Decision}}": true,
+		"{{slice .Username 0 3}}":                           true,
+		"{{.Missing}}":                                      false,
+		"{{.FirstName":                                      false,
+	} {
+		if err := validateMessageTemplate(body); (err == nil) != valid {
+			t.Errorf("validateMessageTemplate(%q) = %v, want valid %v", body, err, valid)
+		}
+	}
+}
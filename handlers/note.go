@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/events"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// mentionRe находит упоминания операторов: @username в начале текста или после пробела/знака препинания
+var mentionRe = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@])@([\p{L}\p{N}_.\-]+)`)
+
+// addNoteInput — текст внутренней заметки
+type addNoteInput struct {
+	Content string `json:"content" binding:"required" example:"@petrov глянь, похоже на тот же баг, что вчера"`
+}
+
+// notificationListResponse — конверт ответа со страницей уведомлений
+type notificationListResponse struct {
+	Items  []models.Notification `json:"items"`
+	Total  int64                 `json:"total"`
+	Unread int64                 `json:"unread"`
+}
+
+// AddTicketNote godoc
+// @Summary Добавить внутреннюю заметку к тикету
+// @Description Добавляет в переписку тикета заметку, видимую только сотрудникам: пользователю она не возвращается ни в истории, ни в событиях и не доставляется в его канал. Упомянутые через @username операторы с правом tickets.read_all получают уведомление (см. /operator/notifications/) и событие operator.mentioned — только сам упомянутый оператор, даже если он не подписан на тикет. Требуется право tickets.reply
+// @Tags operator
+// @Accept json
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Param note body addNoteInput true "Текст заметки"
+// @Success 201 {object} models.Message
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/ticket/{ticket_id}/notes/ [post]
+func AddTicketNote(c *gin.Context, db *gorm.DB, permissions *auth.PermissionStore) {
+	var input addNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+	var ticket models.Ticket
+	if err := db.Where("id = ?", c.Param("ticket_id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+
+	mentioned, err := mentionedOperators(db, permissions, input.Content, operator.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions: " + err.Error()})
+		return
+	}
+
+	note := models.Message{
+		TicketID:   ticket.ID,
+		Sender:     "operator",
+		Recipient:  "operator",
+		Content:    input.Content,
+		Internal:   true,
+		OperatorID: &operator.ID,
+	}
+	var notifications []models.Notification
+	// Заметка не меняет last_message_at: это не переписка с пользователем
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&note).Error; err != nil {
+			return err
+		}
+		for _, mention := range mentioned {
+			notifications = append(notifications, models.Notification{
+				OperatorID: mention.ID,
+				Type:       models.NotificationMention,
+				TicketID:   ticket.ID,
+				MessageID:  note.ID,
+				Actor:      operator.Username,
+			})
+		}
+		if len(notifications) == 0 {
+			return nil
+		}
+		return tx.Create(&notifications).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save note: " + err.Error()})
+		return
+	}
+
+	events.PublishStaff(events.TypeNoteCreated, ticket.ID, ticket.UserID, note)
+	for _, notification := range notifications {
+		events.PublishOperator(events.TypeOperatorMentioned, ticket.ID, ticket.UserID, notification.OperatorID, notification)
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// mentionUsernames возвращает имена из упоминаний в тексте без повторов, в порядке появления.
+// Для имени с точкой или дефисом в конце возвращается и вариант без них
+func mentionUsernames(content string) []string {
+	seen := map[string]bool{}
+	var usernames []string
+	for _, match := range mentionRe.FindAllStringSubmatch(content, -1) {
+		// Точка или дефис в конце — скорее знак препинания после имени, чем его часть
+		for _, username := range []string{match[1], strings.TrimRight(match[1], ".-")} {
+			if username != "" && !seen[username] {
+				seen[username] = true
+				usernames = append(usernames, username)
+			}
+		}
+	}
+	return usernames
+}
+
+// mentionedOperators возвращает активных операторов, упомянутых в тексте, кроме автора.
+// Упоминания тех, кому роль не даёт видеть тикеты, пропускаются: открыть заметку они не смогут
+func mentionedOperators(db *gorm.DB, permissions *auth.PermissionStore, content string, authorID uint) ([]models.Operator, error) {
+	usernames := mentionUsernames(content)
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	var operators []models.Operator
+	err := db.Where("username IN ? AND disabled = ? AND id <> ?", usernames, false, authorID).
+		Order("id asc").Find(&operators).Error
+	if err != nil {
+		return nil, err
+	}
+	visible := operators[:0]
+	for _, operator := range operators {
+		if permissions.For(operator.Role).Has(models.PermTicketsReadAll) {
+			visible = append(visible, operator)
+		}
+	}
+	return visible, nil
+}
+
+// ListNotifications godoc
+// @Summary Уведомления текущего оператора
+// @Description Возвращает уведомления оператора, новые сверху, и число непрочитанных
+// @Tags operator
+// @Produce json
+// @Param unread query bool false "Только непрочитанные"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} notificationListResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/notifications/ [get]
+func ListNotifications(c *gin.Context, db *gorm.DB) {
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+	limit, offset := defaultTicketPageSize, 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit: " + raw})
+			return
+		}
+		if n > maxTicketPageSize {
+			n = maxTicketPageSize
+		}
+		limit = n
+	}
+	if raw := c.Query("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset: " + raw})
+			return
+		}
+		offset = n
+	}
+
+	var unread int64
+	if err := db.Model(&models.Notification{}).Where("operator_id = ? AND read_at IS NULL", operator.ID).Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	query := db.Model(&models.Notification{}).Where("operator_id = ?", operator.ID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	notifications := []models.Notification{}
+	if err := query.Order("id desc").Limit(limit).Offset(offset).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, notificationListResponse{Items: notifications, Total: total, Unread: unread})
+}
+
+// MarkNotificationRead godoc
+// @Summary Отметить уведомление прочитанным
+// @Tags operator
+// @Produce json
+// @Param id path string true "ID уведомления"
+// @Success 200 {object} models.Notification
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/notifications/{id}/read [post]
+func MarkNotificationRead(c *gin.Context, db *gorm.DB) {
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+	var notification models.Notification
+	if err := db.Where("id = ? AND operator_id = ?", c.Param("id"), operator.ID).First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+		notification.ReadAt = &now
+	}
+	c.JSON(http.StatusOK, notification)
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Отметить все уведомления прочитанными
+// @Tags operator
+// @Produce json
+// @Success 200 {object} map[string]int64 "Сколько уведомлений отмечено"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/notifications/read-all [post]
+func MarkAllNotificationsRead(c *gin.Context, db *gorm.DB) {
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+	result := db.Model(&models.Notification{}).
+		Where("operator_id = ? AND read_at IS NULL", operator.ID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
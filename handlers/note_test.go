@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestMentionUsernames(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"@petrov глянь", []string{"petrov"}},
+		{"глянь, @petrov.", []string{"petrov.", "petrov"}},
+		{"@ivan.petrov-, посмотри", []string{"ivan.petrov-", "ivan.petrov"}},
+		{"(@petrov) и @sidorov_2", []string{"petrov", "sidorov_2"}},
+		{"пишите на support@petrov.example", nil},
+		{"@@petrov", nil},
+		{"@петров и снова @петров", []string{"петров"}},
+		{"@ одна собака", nil},
+	}
+	for _, tt := range tests {
+		if got := mentionUsernames(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mentionUsernames(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestMentionedOperators(t *testing.T) {
+	db := openTestDB(t)
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	permissions := auth.NewPermissionStore(db, quiet)
+
+	prefix := fmt.Sprintf("t%d", time.Now().UnixNano())
+	t.Cleanup(func() { db.Where("username LIKE ?", prefix+"%").Delete(&models.Operator{}) })
+	create := func(name, role string, disabled bool) models.Operator {
+		operator := models.Operator{Username: prefix + name, Password: "-", Role: role, Disabled: disabled}
+		if err := db.Create(&operator).Error; err != nil {
+			t.Fatalf("create operator: %v", err)
+		}
+		return operator
+	}
+	author := create("author", models.RoleOperator, false)
+	colleague := create("colleague", models.RoleOperator, false)
+	create("disabled", models.RoleOperator, true)
+	create("blind", "test-no-permissions", false)
+
+	content := fmt.Sprintf("@%[1]sauthor, @%[1]scolleague. @%[1]sdisabled @%[1]sblind @%[1]smissing", prefix)
+	operators, err := mentionedOperators(db, permissions, content, author.ID)
+	if err != nil {
+		t.Fatalf("mentionedOperators: %v", err)
+	}
+	if len(operators) != 1 || operators[0].ID != colleague.ID {
+		t.Fatalf("mentioned = %+v, want only the colleague (author, disabled and operators without tickets.read_all skipped)", operators)
+	}
+}
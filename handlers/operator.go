@@ -1 +1,216 @@
 package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"helpdesk-api/events"
+	"helpdesk-api/middleware"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// transferTicketInput структура для входных данных передачи тикета коллеге
+type transferTicketInput struct {
+	OperatorID uint `json:"operator_id" binding:"required" example:"2"` // ID оператора, которому передаётся тикет
+}
+
+// ClaimTicket godoc
+// @Summary Взять тикет в работу
+// @Description Назначает тикет на текущего оператора, если он ещё никому не назначен. При одновременных запросах тикет достаётся только одному оператору
+// @Tags operator
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Success 200 {object} models.Ticket
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/ticket/{ticket_id}/claim/ [post]
+func ClaimTicket(c *gin.Context, db *gorm.DB) {
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+
+	var ticket models.Ticket
+	if err := db.Where("id = ?", c.Param("ticket_id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	if ticket.Status == models.TicketStatusClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Closed ticket cannot be claimed"})
+		return
+	}
+
+	// Условие assignee_id IS NULL делает захват атомарным: из двух одновременных
+	// запросов строку обновит только один
+	now := time.Now()
+	result := db.Model(&models.Ticket{}).
+		Where("id = ? AND assignee_id IS NULL", ticket.ID).
+		Updates(map[string]interface{}{"assignee_id": operator.ID, "assigned_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim ticket"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		if err := db.First(&ticket, ticket.ID).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim ticket"})
+			return
+		}
+		if ticket.AssigneeID == nil || *ticket.AssigneeID != operator.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ticket is already assigned to another operator"})
+			return
+		}
+		// Повторный захват своим же оператором считаем успешным
+		c.JSON(http.StatusOK, gin.H{"message": "Ticket already assigned to you", "ticket": ticket})
+		return
+	}
+
+	ticket.AssigneeID = &operator.ID
+	ticket.AssignedAt = &now
+	events.Publish(events.TypeTicketAssigned, ticket.ID, ticket.UserID, ticket)
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket claimed", "ticket": ticket})
+}
+
+// ReleaseTicket godoc
+// @Summary Освободить тикет
+// @Description Снимает назначение тикета с текущего оператора. Сотрудник с правом tickets.assign_any может освободить тикет, назначенный на любого оператора
+// @Tags operator
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/ticket/{ticket_id}/release/ [post]
+func ReleaseTicket(c *gin.Context, db *gorm.DB) {
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+
+	var ticket models.Ticket
+	if err := db.Where("id = ?", c.Param("ticket_id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+
+	query := db.Model(&models.Ticket{}).Where("id = ?", ticket.ID)
+	if middleware.Permissions(c).Has(models.PermTicketsAssignAny) {
+		query = query.Where("assignee_id IS NOT NULL")
+	} else {
+		query = query.Where("assignee_id = ?", operator.ID)
+	}
+	result := query.Updates(map[string]interface{}{"assignee_id": nil, "assigned_at": nil})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release ticket"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket is not assigned to you"})
+		return
+	}
+
+	ticket.AssigneeID = nil
+	ticket.AssignedAt = nil
+	events.Publish(events.TypeTicketAssigned, ticket.ID, ticket.UserID, ticket)
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket released"})
+}
+
+// TransferTicket godoc
+// @Summary Передать тикет другому оператору
+// @Description Переназначает свой или неназначенный тикет на другого оператора. Сотрудник с правом tickets.assign_any может переназначить любой тикет
+// @Tags operator
+// @Accept json
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Param input body transferTicketInput true "Оператор-получатель"
+// @Success 200 {object} models.Ticket
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/ticket/{ticket_id}/transfer/ [post]
+func TransferTicket(c *gin.Context, db *gorm.DB) {
+	var input transferTicketInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+
+	var target models.Operator
+	if err := db.First(&target, input.OperatorID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target operator not found"})
+		return
+	}
+	if target.Disabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Target operator is disabled"})
+		return
+	}
+
+	var ticket models.Ticket
+	if err := db.Where("id = ?", c.Param("ticket_id")).First(&ticket).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
+		return
+	}
+	if ticket.Status == models.TicketStatusClosed {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Closed ticket cannot be transferred"})
+		return
+	}
+
+	now := time.Now()
+	query := db.Model(&models.Ticket{}).Where("id = ?", ticket.ID)
+	if !middleware.Permissions(c).Has(models.PermTicketsAssignAny) {
+		query = query.Where("assignee_id = ? OR assignee_id IS NULL", operator.ID)
+	}
+	result := query.Updates(map[string]interface{}{"assignee_id": target.ID, "assigned_at": now})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer ticket"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket is assigned to another operator"})
+		return
+	}
+
+	ticket.AssigneeID = &target.ID
+	ticket.AssignedAt = &now
+	events.Publish(events.TypeTicketAssigned, ticket.ID, ticket.UserID, ticket)
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket transferred", "ticket": ticket})
+}
+
+// currentOperator возвращает оператора, от имени которого выполняется запрос.
+// При ошибке ответ клиенту уже отправлен.
+func currentOperator(c *gin.Context, db *gorm.DB) (*models.Operator, bool) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Operator not authenticated"})
+		return nil, false
+	}
+
+	var operator models.Operator
+	if err := db.Where("username = ?", username).First(&operator).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Operator not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load operator"})
+		}
+		return nil, false
+	}
+	return &operator, true
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minOperatorPasswordLength — минимальная длина пароля оператора
+const minOperatorPasswordLength = 8
+
+// createOperatorInput структура для входных данных создания оператора
+type createOperatorInput struct {
+	Username string `json:"username" binding:"required" example:"ivanov"`
+	Password string `json:"password" binding:"required" example:"s3cure-passw0rd"`
+	Role     string `json:"role" binding:"required" example:"operator"`
+}
+
+// operatorPasswordInput структура для входных данных сброса пароля
+type operatorPasswordInput struct {
+	Password string `json:"password" binding:"required" example:"n3w-passw0rd"`
+}
+
+// operatorRoleInput структура для входных данных смены роли
+type operatorRoleInput struct {
+	Role string `json:"role" binding:"required" example:"supervisor"`
+}
+
+// ListOperators godoc
+// @Summary Список операторов
+// @Description Возвращает всех операторов, включая отключённых. Требуется право operators.manage
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Operator
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/ [get]
+func ListOperators(c *gin.Context, db *gorm.DB) {
+	var operators []models.Operator
+	if err := db.Order("id asc").Find(&operators).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch operators"})
+		return
+	}
+	c.JSON(http.StatusOK, operators)
+}
+
+// CreateOperator godoc
+// @Summary Создать оператора
+// @Description Создаёт учётную запись оператора с одной из существующих ролей. Требуется право operators.manage
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body createOperatorInput true "Данные оператора"
+// @Success 201 {object} models.Operator
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/ [post]
+func CreateOperator(c *gin.Context, db *gorm.DB) {
+	var input createOperatorInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !roleExists(c, db, input.Role) {
+		return
+	}
+	hash, ok := hashOperatorPassword(c, input.Password)
+	if !ok {
+		return
+	}
+
+	operator := models.Operator{
+		Username: input.Username,
+		Password: hash,
+		Role:     input.Role,
+	}
+	if err := db.Create(&operator).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Operator with this username already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create operator"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, operator)
+}
+
+// DisableOperator godoc
+// @Summary Отключить оператора
+// @Description Запрещает оператору вход и отзывает все его сессии. Последнего активного сотрудника с правом operators.manage отключить нельзя
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID оператора"
+// @Success 200 {object} models.Operator
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/{id}/disable [post]
+func DisableOperator(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore, permissions *auth.PermissionStore) {
+	setOperatorDisabled(c, db, revocations, permissions, true)
+}
+
+// EnableOperator godoc
+// @Summary Включить оператора
+// @Description Снова разрешает отключённому оператору вход
+// @Tags admin
+// @Produce json
+// @Param id path int true "ID оператора"
+// @Success 200 {object} models.Operator
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/{id}/enable [post]
+func EnableOperator(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore, permissions *auth.PermissionStore) {
+	setOperatorDisabled(c, db, revocations, permissions, false)
+}
+
+func setOperatorDisabled(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore, permissions *auth.PermissionStore, disabled bool) {
+	operator, ok := findOperatorByParam(c, db)
+	if !ok {
+		return
+	}
+	if disabled && permissions.For(operator.Role).Has(models.PermOperatorsManage) && !hasOtherActiveManager(c, db, operator.ID, "") {
+		return
+	}
+
+	if err := db.Model(operator).Update("disabled", disabled).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update operator"})
+		return
+	}
+	if disabled {
+		if err := revocations.RevokeSubject(auth.OperatorSubject(operator.Username)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Operator disabled, but failed to revoke sessions"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, operator)
+}
+
+// ResetOperatorPassword godoc
+// @Summary Сбросить пароль оператора
+// @Description Устанавливает оператору новый пароль и отзывает все его сессии
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID оператора"
+// @Param input body operatorPasswordInput true "Новый пароль"
+// @Success 200 {object} map[string]string "message"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/{id}/password [post]
+func ResetOperatorPassword(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore) {
+	var input operatorPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operator, ok := findOperatorByParam(c, db)
+	if !ok {
+		return
+	}
+	hash, ok := hashOperatorPassword(c, input.Password)
+	if !ok {
+		return
+	}
+
+	if err := db.Model(operator).Update("password", hash).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	if err := revocations.RevokeSubject(auth.OperatorSubject(operator.Username)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed, but failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password updated"})
+}
+
+// ChangeOperatorRole godoc
+// @Summary Сменить роль оператора
+// @Description Назначает оператору одну из существующих ролей и отзывает его сессии, чтобы новая роль попала в токен. Нельзя отобрать право operators.manage у последнего активного сотрудника, у которого оно есть
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "ID оператора"
+// @Param input body operatorRoleInput true "Новая роль"
+// @Success 200 {object} models.Operator
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/operators/{id}/role [put]
+func ChangeOperatorRole(c *gin.Context, db *gorm.DB, revocations *auth.RevocationStore, permissions *auth.PermissionStore) {
+	var input operatorRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	operator, ok := findOperatorByParam(c, db)
+	if !ok {
+		return
+	}
+	if operator.Role == input.Role {
+		c.JSON(http.StatusOK, operator)
+		return
+	}
+	if !roleExists(c, db, input.Role) {
+		return
+	}
+	losesManage := permissions.For(operator.Role).Has(models.PermOperatorsManage) &&
+		!permissions.For(input.Role).Has(models.PermOperatorsManage)
+	if losesManage && !operator.Disabled && !hasOtherActiveManager(c, db, operator.ID, "") {
+		return
+	}
+
+	if err := db.Model(operator).Update("role", input.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	if err := revocations.RevokeSubject(auth.OperatorSubject(operator.Username)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Role changed, but failed to revoke sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, operator)
+}
+
+// findOperatorByParam загружает оператора по :id из пути.
+// При ошибке ответ клиенту уже отправлен.
+func findOperatorByParam(c *gin.Context, db *gorm.DB) (*models.Operator, bool) {
+	var operator models.Operator
+	if err := db.First(&operator, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Operator not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load operator"})
+		}
+		return nil, false
+	}
+	return &operator, true
+}
+
+// hasOtherActiveManager проверяет, что останется ещё хотя бы один активный сотрудник
+// с правом operators.manage, не считая оператора exceptID и роли exceptRole,
+// чтобы система не осталась без администратора
+func hasOtherActiveManager(c *gin.Context, db *gorm.DB, exceptID uint, exceptRole string) bool {
+	var count int64
+	err := db.Model(&models.Operator{}).
+		Where("disabled = ? AND id <> ? AND role <> ?", false, exceptID, exceptRole).
+		Where("role IN (?)", db.Model(&models.RolePermission{}).Select("role").Where("permission = ?", models.PermOperatorsManage)).
+		Count(&count).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check administrators"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot remove the last active administrator"})
+		return false
+	}
+	return true
+}
+
+// roleExists проверяет, что роль заведена в таблице ролей
+func roleExists(c *gin.Context, db *gorm.DB, name string) bool {
+	var count int64
+	if err := db.Model(&models.Role{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load role"})
+		return false
+	}
+	if count == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown role: " + name})
+		return false
+	}
+	return true
+}
+
+// hashOperatorPassword проверяет длину пароля и возвращает его bcrypt-хеш
+func hashOperatorPassword(c *gin.Context, password string) (string, bool) {
+	if len(password) < minOperatorPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters long"})
+		return "", false
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return "", false
+	}
+	return string(hash), true
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// testOperators создаёт операторов с уникальными логинами и удаляет их после теста
+func testOperators(t *testing.T, db *gorm.DB, n int) []models.Operator {
+	t.Helper()
+	prefix := fmt.Sprintf("t%d-", time.Now().UnixNano())
+	t.Cleanup(func() { db.Where("username LIKE ?", prefix+"%").Delete(&models.Operator{}) })
+	operators := make([]models.Operator, n)
+	for i := range operators {
+		operators[i] = models.Operator{Username: fmt.Sprintf("%s%d", prefix, i), Password: "-", Role: models.RoleOperator}
+		if err := db.Create(&operators[i]).Error; err != nil {
+			t.Fatalf("create operator: %v", err)
+		}
+	}
+	return operators
+}
+
+// assignmentRouter возвращает роутер захвата и передачи тикетов; запросы идут от оператора
+// из заголовка X-Test-Operator с правами, которые даёт ему permissions
+func assignmentRouter(db *gorm.DB, permissions map[string]auth.PermissionSet) *gin.Engine {
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		username := c.GetHeader("X-Test-Operator")
+		c.Set("role", models.RoleOperator)
+		c.Set("username", username)
+		c.Set("permissions", permissions[username])
+		c.Next()
+	})
+	router.POST("/operator/ticket/:ticket_id/claim/", func(c *gin.Context) { ClaimTicket(c, db) })
+	router.POST("/operator/ticket/:ticket_id/transfer/", func(c *gin.Context) { TransferTicket(c, db) })
+	return router
+}
+
+func postAs(router *gin.Engine, operator models.Operator, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-Operator", operator.Username)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func createOpenTicket(t *testing.T, db *gorm.DB, stand string, assignee *uint) models.Ticket {
+	t.Helper()
+	ticket := models.Ticket{UserID: 1, Subject: "Вход", Stand: stand, Status: models.TicketStatusOpen, AssigneeID: assignee}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("create ticket: %v", err)
+	}
+	return ticket
+}
+
+func TestClaimTicketConcurrent(t *testing.T) {
+	db := openTestDB(t)
+	stand := testStand(t, db)
+	operators := testOperators(t, db, 2)
+	router := assignmentRouter(db, nil)
+
+	// Повторяем, чтобы запросы действительно пересекались хотя бы в части прогонов
+	for round := 0; round < 10; round++ {
+		ticket := createOpenTicket(t, db, stand, nil)
+		path := fmt.Sprintf("/operator/ticket/%d/claim/", ticket.ID)
+
+		var (
+			start sync.WaitGroup
+			done  sync.WaitGroup
+			codes = make([]int, len(operators))
+		)
+		start.Add(1)
+		for i, operator := range operators {
+			done.Add(1)
+			go func(i int, operator models.Operator) {
+				defer done.Done()
+				start.Wait()
+				codes[i] = postAs(router, operator, path, "").Code
+			}(i, operator)
+		}
+		start.Done()
+		done.Wait()
+
+		winner := 0
+		if codes[1] == http.StatusOK {
+			winner = 1
+		}
+		sorted := append([]int(nil), codes...)
+		sort.Ints(sorted)
+		if sorted[0] != http.StatusOK || sorted[1] != http.StatusConflict {
+			t.Fatalf("round %d: claim statuses = %v, want one 200 and one 409", round, codes)
+		}
+		db.First(&ticket, ticket.ID)
+		if ticket.AssigneeID == nil || *ticket.AssigneeID != operators[winner].ID {
+			t.Fatalf("round %d: assignee = %v, want operator %d who got 200", round, ticket.AssigneeID, operators[winner].ID)
+		}
+	}
+}
+
+func TestTransferTicketRequiresAssignAny(t *testing.T) {
+	db := openTestDB(t)
+	stand := testStand(t, db)
+	operators := testOperators(t, db, 3)
+	owner, colleague, supervisor := operators[0], operators[1], operators[2]
+	router := assignmentRouter(db, map[string]auth.PermissionSet{
+		supervisor.Username: {models.PermTicketsAssignAny: true},
+	})
+	ticket := createOpenTicket(t, db, stand, &owner.ID)
+	path := fmt.Sprintf("/operator/ticket/%d/transfer/", ticket.ID)
+
+	// Коллега без tickets.assign_any не может забрать чужой тикет ни себе, ни третьему
+	for _, target := range []models.Operator{colleague, supervisor} {
+		w := postAs(router, colleague, path, fmt.Sprintf(`{"operator_id":%d}`, target.ID))
+		if w.Code != http.StatusConflict {
+			t.Fatalf("colleague transferring to %s: status = %d, want 409: %s", target.Username, w.Code, w.Body)
+		}
+	}
+	db.First(&ticket, ticket.ID)
+	if ticket.AssigneeID == nil || *ticket.AssigneeID != owner.ID {
+		t.Fatalf("assignee after rejected transfers = %v, want owner %d", ticket.AssigneeID, owner.ID)
+	}
+
+	// Владелец передаёт свой тикет, а супервизор — любой
+	if w := postAs(router, owner, path, fmt.Sprintf(`{"operator_id":%d}`, colleague.ID)); w.Code != http.StatusOK {
+		t.Fatalf("owner transfer: status = %d: %s", w.Code, w.Body)
+	}
+	if w := postAs(router, supervisor, path, fmt.Sprintf(`{"operator_id":%d}`, owner.ID)); w.Code != http.StatusOK {
+		t.Fatalf("supervisor transfer: status = %d: %s", w.Code, w.Body)
+	}
+	db.First(&ticket, ticket.ID)
+	if ticket.AssigneeID == nil || *ticket.AssigneeID != owner.ID {
+		t.Fatalf("assignee = %v, want owner %d after supervisor transfer", ticket.AssigneeID, owner.ID)
+	}
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"helpdesk-api/middleware"
+	"helpdesk-api/models"
+	"helpdesk-api/outbox"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// outboxListResponse — конверт ответа со страницей сообщений outbox
+type outboxListResponse struct {
+	Items []models.OutboxMessage `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// outboxVisibleKinds возвращает типы сообщений outbox, доступные сотруднику; nil — все.
+// Ответы пользователям (ticket.reply, email.reply) содержат переписку и адреса, поэтому
+// без права tickets.read_all видны только уведомления стендов
+func outboxVisibleKinds(c *gin.Context) []string {
+	if middleware.Permissions(c).Has(models.PermTicketsReadAll) {
+		return nil
+	}
+	return []string{OutboxKindStandNotification}
+}
+
+// ListOutbox godoc
+// @Summary Список исходящих уведомлений
+// @Description Возвращает сообщения outbox, по умолчанию — недоставленные (dead), у которых исчерпаны попытки. В last_error — причина последней неудачи. Ответы пользователям (ticket.reply, email.reply) видны только с правом tickets.read_all
+// @Tags outbox
+// @Produce json
+// @Param status query string false "Статус сообщения (по умолчанию dead)" Enums(pending, delivered, dead)
+// @Param stand query string false "Стенд"
+// @Param kind query string false "Тип сообщения"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} outboxListResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/outbox/ [get]
+func ListOutbox(c *gin.Context, db *gorm.DB) {
+	status := c.DefaultQuery("status", models.OutboxStatusDead)
+	switch status {
+	case models.OutboxStatusPending, models.OutboxStatusDelivered, models.OutboxStatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be one of pending, delivered, dead"})
+		return
+	}
+
+	limit, offset := defaultTicketPageSize, 0
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit: " + raw})
+			return
+		}
+		if n > maxTicketPageSize {
+			n = maxTicketPageSize
+		}
+		limit = n
+	}
+	if raw := c.Query("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset: " + raw})
+			return
+		}
+		offset = n
+	}
+
+	query := db.Model(&models.OutboxMessage{}).Where("status = ?", status)
+	if stand := c.Query("stand"); stand != "" {
+		query = query.Where("stand = ?", stand)
+	}
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if kinds := outboxVisibleKinds(c); kinds != nil {
+		query = query.Where("kind IN ?", kinds)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox"})
+		return
+	}
+	items := []models.OutboxMessage{}
+	if err := query.Order("id desc").Limit(limit).Offset(offset).Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox"})
+		return
+	}
+
+	c.JSON(http.StatusOK, outboxListResponse{Items: items, Total: total})
+}
+
+// RetryOutboxMessage godoc
+// @Summary Переотправить уведомление
+// @Description Возвращает недоставленное (dead) сообщение в очередь с обнулённым счётчиком попыток. Ответы пользователям переотправляются только с правом tickets.read_all
+// @Tags outbox
+// @Produce json
+// @Param id path int true "ID сообщения outbox"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/outbox/{id}/retry [post]
+func RetryOutboxMessage(c *gin.Context, db *gorm.DB) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Outbox message not found"})
+		return
+	}
+	if kinds := outboxVisibleKinds(c); kinds != nil {
+		var message models.OutboxMessage
+		if err := db.Select("id").Where("id = ? AND kind IN ?", id, kinds).First(&message).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Outbox message not found"})
+			return
+		}
+	}
+
+	if err := outbox.Retry(db, uint(id)); err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Outbox message not found"})
+		case errors.Is(err, outbox.ErrNotDead):
+			c.JSON(http.StatusConflict, gin.H{"error": "Only dead-lettered messages can be retried"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry outbox message"})
+		}
+		return
+	}
+	if err := requeueFailedTicketReplies(db); err != nil {
+		log.Printf("Failed to requeue ticket replies: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message queued for delivery"})
+}
+
+// RetryOutbox godoc
+// @Summary Переотправить все недоставленные уведомления
+// @Description Возвращает в очередь все dead-сообщения, например после восстановления стенда. Параметр stand ограничивает переотправку одним стендом. Без права tickets.read_all переотправляются только уведомления стендов
+// @Tags outbox
+// @Produce json
+// @Param stand query string false "Стенд"
+// @Success 200 {object} map[string]interface{} "message, count"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/outbox/retry [post]
+func RetryOutbox(c *gin.Context, db *gorm.DB) {
+	count, err := outbox.RetryDead(db, c.Query("stand"), outboxVisibleKinds(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry outbox messages"})
+		return
+	}
+	if err := requeueFailedTicketReplies(db); err != nil {
+		log.Printf("Failed to requeue ticket replies: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Messages queued for delivery", "count": count})
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"helpdesk-api/events"
+	"helpdesk-api/middleware"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+// wsKeepAliveInterval — как часто отправлять служебное сообщение, чтобы прокси не рвали простаивающее соединение
+const wsKeepAliveInterval = 30 * time.Second
+
+// wsCommand — команда клиента WebSocket
+type wsCommand struct {
+	Action   string `json:"action" example:"subscribe"` // subscribe, unsubscribe, subscribe_all, unsubscribe_all
+	TicketID uint   `json:"ticket_id,omitempty" example:"42"`
+}
+
+// wsReply — служебный ответ сервера на команду клиента
+type wsReply struct {
+	Type     string `json:"type"`
+	TicketID uint   `json:"ticket_id,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// TicketEventsWS godoc
+// @Summary Поток событий тикетов по WebSocket
+// @Description Открывает WebSocket-соединение для получения событий ticket.created, message.created, ticket.status_changed и ticket.assigned, а сотрудникам также note.created (внутренние заметки) и адресованные им operator.mentioned. Токен передаётся в заголовке Authorization или параметром access_token. После подключения клиент отправляет команды {"action":"subscribe","ticket_id":42}, {"action":"unsubscribe","ticket_id":42}, а оператор также {"action":"subscribe_all"}. Пользователь может подписаться только на свои тикеты
+// @Tags events
+// @Param access_token query string false "JWT-токен, если заголовок Authorization недоступен"
+// @Success 101 {string} string "Switching Protocols"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Security BearerAuth
+// @Router /ws [get]
+func TicketEventsWS(c *gin.Context, db *gorm.DB) {
+	role := c.GetString("role")
+
+	var userID, operatorID uint
+	if role == models.RoleUser {
+		user, ok := currentUser(c, db)
+		if !ok {
+			return
+		}
+		userID = user.ID
+	} else {
+		if !middleware.Permissions(c).Has(models.PermTicketsReadAll) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReadAll + " required"})
+			return
+		}
+		operator, ok := currentOperator(c, db)
+		if !ok {
+			return
+		}
+		operatorID = operator.ID
+	}
+
+	server := websocket.Server{
+		// Доступ проверен JWT-мидлварой, cookie не используются, поэтому Origin не проверяем
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			serveEventsWS(conn, db, role, userID, operatorID)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+func serveEventsWS(conn *websocket.Conn, db *gorm.DB, role string, userID, operatorID uint) {
+	defer conn.Close()
+
+	hub := events.Default()
+	subscriber := hub.Subscribe(role, userID, operatorID)
+	defer hub.Unsubscribe(subscriber)
+
+	var writeMu sync.Mutex
+	send := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return websocket.JSON.Send(conn, v)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var cmd wsCommand
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			reply := handleWSCommand(db, subscriber, role, userID, cmd)
+			if err := send(reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	keepAlive := time.NewTicker(wsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-subscriber.Events():
+			if !ok {
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if err := send(wsReply{Type: "ping"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleWSCommand применяет команду подписки с теми же правилами доступа, что и REST-обработчики
+func handleWSCommand(db *gorm.DB, subscriber *events.Subscriber, role string, userID uint, cmd wsCommand) wsReply {
+	switch cmd.Action {
+	case "subscribe":
+		var ticket models.Ticket
+		if err := db.Where("id = ?", cmd.TicketID).First(&ticket).Error; err != nil {
+			return wsReply{Type: "error", TicketID: cmd.TicketID, Error: "Ticket not found"}
+		}
+		if role == models.RoleUser && ticket.UserID != userID {
+			return wsReply{Type: "error", TicketID: cmd.TicketID, Error: "You can only subscribe to your own tickets"}
+		}
+		subscriber.Follow(ticket.ID)
+		return wsReply{Type: "subscribed", TicketID: ticket.ID}
+	case "unsubscribe":
+		subscriber.Unfollow(cmd.TicketID)
+		return wsReply{Type: "unsubscribed", TicketID: cmd.TicketID}
+	case "subscribe_all":
+		if !models.IsOperatorRole(role) {
+			return wsReply{Type: "error", Error: "Only operators can subscribe to all tickets"}
+		}
+		subscriber.FollowAll(true)
+		return wsReply{Type: "subscribed_all"}
+	case "unsubscribe_all":
+		subscriber.FollowAll(false)
+		return wsReply{Type: "unsubscribed_all"}
+	default:
+		return wsReply{Type: "error", Error: "Unknown action: " + cmd.Action}
+	}
+}
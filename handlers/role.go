@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// roleNamePattern — допустимые имена ролей: латиница в нижнем регистре, цифры и подчёркивание
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// roleResponse — роль вместе с её правами
+type roleResponse struct {
+	Name        string   `json:"name" example:"auditor"`
+	Description string   `json:"description" example:"Только просмотр тикетов"`
+	Permissions []string `json:"permissions" example:"tickets.read_all"`
+}
+
+// createRoleInput структура для входных данных создания роли
+type createRoleInput struct {
+	Name        string   `json:"name" binding:"required" example:"auditor"`
+	Description string   `json:"description" example:"Только просмотр тикетов"`
+	Permissions []string `json:"permissions" example:"tickets.read_all"`
+}
+
+// updateRoleInput структура для входных данных изменения роли. Набор прав заменяется целиком
+type updateRoleInput struct {
+	Description string   `json:"description" example:"Только просмотр тикетов и whitelist"`
+	Permissions []string `json:"permissions" example:"tickets.read_all,whitelist.read"`
+}
+
+// ListPermissions godoc
+// @Summary Список прав
+// @Description Возвращает все права, которые можно выдать роли. Требуется право operators.manage
+// @Tags admin
+// @Produce json
+// @Success 200 {array} string
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Security BearerAuth
+// @Router /admin/permissions [get]
+func ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.AllPermissions)
+}
+
+// ListRoles godoc
+// @Summary Список ролей
+// @Description Возвращает роли сотрудников вместе с их правами. Требуется право operators.manage
+// @Tags admin
+// @Produce json
+// @Success 200 {array} roleResponse
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/roles/ [get]
+func ListRoles(c *gin.Context, db *gorm.DB) {
+	var roles []models.Role
+	if err := db.Preload("Permissions").Order("name asc").Find(&roles).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch roles"})
+		return
+	}
+
+	response := make([]roleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, newRoleResponse(role))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateRole godoc
+// @Summary Создать роль
+// @Description Создаёт роль с заданным набором прав, например аудитора только с tickets.read_all. Требуется право operators.manage
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param input body createRoleInput true "Роль"
+// @Success 201 {object} roleResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/roles/ [post]
+func CreateRole(c *gin.Context, db *gorm.DB, permissions *auth.PermissionStore) {
+	var input createRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !roleNamePattern.MatchString(input.Name) || input.Name == models.RoleUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role name must be 2-32 lowercase latin letters, digits or underscores"})
+		return
+	}
+	if !validatePermissions(c, input.Permissions) {
+		return
+	}
+
+	var existing int64
+	if err := db.Model(&models.Role{}).Where("name = ?", input.Name).Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role already exists"})
+		return
+	}
+
+	role := models.Role{Name: input.Name, Description: input.Description}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&role).Error; err != nil {
+			return err
+		}
+		return replaceRolePermissions(tx, role.Name, input.Permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create role"})
+		return
+	}
+	reloadPermissions(permissions)
+
+	c.JSON(http.StatusCreated, roleResponse{Name: role.Name, Description: role.Description, Permissions: input.Permissions})
+}
+
+// UpdateRole godoc
+// @Summary Изменить роль
+// @Description Меняет описание роли и заменяет её набор прав целиком. Изменения действуют сразу, без перевыпуска токенов. Нельзя отобрать право operators.manage, если после этого не останется ни одного активного сотрудника с ним. Требуется право operators.manage
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Param input body updateRoleInput true "Роль"
+// @Success 200 {object} roleResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/roles/{name} [put]
+func UpdateRole(c *gin.Context, db *gorm.DB, permissions *auth.PermissionStore) {
+	var input updateRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validatePermissions(c, input.Permissions) {
+		return
+	}
+
+	role, ok := findRoleByParam(c, db)
+	if !ok {
+		return
+	}
+
+	keepsManage := false
+	for _, permission := range input.Permissions {
+		if permission == models.PermOperatorsManage {
+			keepsManage = true
+		}
+	}
+	if permissions.For(role.Name).Has(models.PermOperatorsManage) && !keepsManage && !hasOtherActiveManager(c, db, 0, role.Name) {
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(role).Update("description", input.Description).Error; err != nil {
+			return err
+		}
+		return replaceRolePermissions(tx, role.Name, input.Permissions)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+	reloadPermissions(permissions)
+
+	c.JSON(http.StatusOK, roleResponse{Name: role.Name, Description: input.Description, Permissions: input.Permissions})
+}
+
+// DeleteRole godoc
+// @Summary Удалить роль
+// @Description Удаляет роль, если она не встроенная и ни один оператор её не использует. Требуется право operators.manage
+// @Tags admin
+// @Produce json
+// @Param name path string true "Имя роли"
+// @Success 200 {object} map[string]string "message"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /admin/roles/{name} [delete]
+func DeleteRole(c *gin.Context, db *gorm.DB, permissions *auth.PermissionStore) {
+	role, ok := findRoleByParam(c, db)
+	if !ok {
+		return
+	}
+	if _, builtIn := models.DefaultRolePermissions[role.Name]; builtIn {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Built-in role cannot be deleted"})
+		return
+	}
+
+	var assigned int64
+	if err := db.Model(&models.Operator{}).Where("role = ?", role.Name).Count(&assigned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	if assigned > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Role is assigned to operators"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role = ?", role.Name).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(role).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete role"})
+		return
+	}
+	reloadPermissions(permissions)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role deleted"})
+}
+
+func newRoleResponse(role models.Role) roleResponse {
+	permissions := make([]string, 0, len(role.Permissions))
+	for _, p := range role.Permissions {
+		permissions = append(permissions, p.Permission)
+	}
+	return roleResponse{Name: role.Name, Description: role.Description, Permissions: permissions}
+}
+
+// findRoleByParam загружает роль по :name из пути.
+// При ошибке ответ клиенту уже отправлен.
+func findRoleByParam(c *gin.Context, db *gorm.DB) (*models.Role, bool) {
+	var role models.Role
+	if err := db.Where("name = ?", c.Param("name")).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load role"})
+		}
+		return nil, false
+	}
+	return &role, true
+}
+
+// validatePermissions проверяет, что все права известны системе
+func validatePermissions(c *gin.Context, permissions []string) bool {
+	for _, permission := range permissions {
+		if !models.IsValidPermission(permission) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown permission: " + permission})
+			return false
+		}
+	}
+	return true
+}
+
+// replaceRolePermissions заменяет набор прав роли
+func replaceRolePermissions(tx *gorm.DB, role string, permissions []string) error {
+	if err := tx.Where("role = ?", role).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		if err := tx.Create(&models.RolePermission{Role: role, Permission: permission}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reloadPermissions обновляет кэш прав после изменения ролей.
+// Изменение уже сохранено, поэтому ошибка только логируется: кэш догонит БД при плановом обновлении
+func reloadPermissions(permissions *auth.PermissionStore) {
+	if err := permissions.Reload(); err != nil {
+		log.Printf("Failed to reload role permissions: %v", err)
+	}
+}
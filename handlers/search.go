@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// searchConfig — конфигурация полнотекстового поиска PostgreSQL, с которой построены
+// столбцы search_vector у тикетов и сообщений
+const searchConfig = "russian"
+
+// searchHeadlineOptions — параметры ts_headline для подсвеченных фрагментов
+const searchHeadlineOptions = "StartSel=<b>, StopSel=</b>, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// htmlEscapeSQL оборачивает SQL-выражение с текстом в экранирование HTML. Фрагменты ts_headline
+// отдаются клиенту как HTML, и кроме выделения совпадений в них не должно быть разметки из текста
+func htmlEscapeSQL(expr string) string {
+	return `replace(replace(replace(replace(replace(` + expr + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+}
+
+// searchHit — найденный тикет или сообщение
+type searchHit struct {
+	Type      string    `gorm:"column:kind" json:"type" example:"message"` // "ticket", "message" или "note" (внутренняя заметка)
+	TicketID  uint      `json:"ticket_id"`
+	ShortID   string    `json:"short_id"`
+	MessageID *uint     `json:"message_id,omitempty"`
+	Status    string    `json:"status"`
+	Subject   string    `json:"subject"`
+	Rank      float64   `json:"rank"`
+	Snippet   string    `json:"snippet"` // Фрагмент текста в виде HTML: текст экранирован, совпадения выделены тегами <b></b>
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// searchResponse — конверт ответа поиска
+type searchResponse struct {
+	Items []searchHit `json:"items"`
+	Total int64       `json:"total"`
+}
+
+// Search godoc
+// @Summary Полнотекстовый поиск по тикетам и сообщениям
+// @Description Ищет по теме и описанию тикетов, по тексту сообщений и внутренних заметок (русская морфология PostgreSQL). Запрос q поддерживает синтаксис websearch: "фраза в кавычках", OR, -исключение. Результаты отсортированы по релевантности и фильтруются теми же параметрами, что и список тикетов
+// @Tags operator
+// @Produce json
+// @Param q query string true "Поисковый запрос"
+// @Param status query string false "Статусы через запятую"
+// @Param source query string false "Источник тикета"
+// @Param stand query string false "Стенд" Enums(dev, ift, psi, prom)
+// @Param created_from query string false "Тикет создан не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Тикет создан не позже (RFC3339 или YYYY-MM-DD)"
+// @Param updated_from query string false "Тикет обновлён не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param updated_to query string false "Тикет обновлён не позже (RFC3339 или YYYY-MM-DD)"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} searchResponse
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/search [get]
+func Search(c *gin.Context, db *gorm.DB) {
+	term := strings.TrimSpace(c.Query("q"))
+	if term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	query, err := parseTicketListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("assignee") == "me" {
+		operator, ok := currentOperator(c, db)
+		if !ok {
+			return
+		}
+		query.AssigneeID = &operator.ID
+	}
+
+	offset := 0
+	if raw := c.Query("offset"); raw != "" {
+		if offset, err = strconv.Atoi(raw); err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset: " + raw})
+			return
+		}
+	}
+
+	ticketHits := query.applyFilters(db.Table("tickets").
+		Select(`'ticket' AS kind, tickets.id AS ticket_id, tickets.short_id, NULL::bigint AS message_id,
+			tickets.status, tickets.subject,
+			ts_rank(tickets.search_vector, websearch_to_tsquery(?::regconfig, ?)) AS rank,
+			ts_headline(?::regconfig, `+htmlEscapeSQL(`coalesce(tickets.subject, '') || ' — ' || coalesce(tickets.description, '')`)+`, websearch_to_tsquery(?::regconfig, ?), ?) AS snippet,
+			tickets.created_at`,
+			searchConfig, term, searchConfig, searchConfig, term, searchHeadlineOptions).
+		Where("tickets.search_vector @@ websearch_to_tsquery(?::regconfig, ?)", searchConfig, term))
+
+	messageHits := query.applyFilters(db.Table("messages").
+		Joins("JOIN tickets ON tickets.id = messages.ticket_id").
+		Select(`CASE WHEN messages.internal THEN 'note' ELSE 'message' END AS kind, tickets.id AS ticket_id, tickets.short_id, messages.id AS message_id,
+			tickets.status, tickets.subject,
+			ts_rank(messages.search_vector, websearch_to_tsquery(?::regconfig, ?)) AS rank,
+			ts_headline(?::regconfig, `+htmlEscapeSQL("messages.content")+`, websearch_to_tsquery(?::regconfig, ?), ?) AS snippet,
+			messages.created_at`,
+			searchConfig, term, searchConfig, searchConfig, term, searchHeadlineOptions).
+		Where("messages.deleted_at IS NULL").
+		Where("messages.search_vector @@ websearch_to_tsquery(?::regconfig, ?)", searchConfig, term))
+
+	var total int64
+	if err := db.Raw("SELECT count(*) FROM (? UNION ALL ?) AS hits", ticketHits, messageHits).Scan(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed: " + err.Error()})
+		return
+	}
+
+	hits := []searchHit{}
+	err = db.Raw("SELECT * FROM (? UNION ALL ?) AS hits ORDER BY rank DESC, created_at DESC LIMIT ? OFFSET ?",
+		ticketHits, messageHits, query.Limit, offset).Scan(&hits).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Search failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, searchResponse{Items: hits, Total: total})
+}
+
+// EnsureSearchSchema создаёт столбцы tsvector и GIN-индексы для полнотекстового поиска.
+// Столбцы генерируемые, поэтому GORM о них не знает и AutoMigrate их не трогает.
+func EnsureSearchSchema(db *gorm.DB) error {
+	statements := []string{
+		`ALTER TABLE tickets ADD COLUMN IF NOT EXISTS search_vector tsvector
+            GENERATED ALWAYS AS (
+                setweight(to_tsvector('` + searchConfig + `', coalesce(subject, '')), 'A') ||
+                setweight(to_tsvector('` + searchConfig + `', coalesce(description, '')), 'B')
+            ) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_tickets_search_vector ON tickets USING GIN (search_vector)`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+            GENERATED ALWAYS AS (to_tsvector('` + searchConfig + `', coalesce(content, ''))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_search_vector ON messages USING GIN (search_vector)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
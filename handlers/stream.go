@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/events"
+	"helpdesk-api/middleware"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// sseHeartbeatInterval — период комментариев-пингов, не дающих прокси закрыть поток
+	sseHeartbeatInterval = 15 * time.Second
+	// sseReplayLimit — максимум событий, дочитываемых из журнала за одно подключение
+	sseReplayLimit = 1000
+	// sseRetryMillis — через сколько клиенту EventSource переподключаться после обрыва
+	sseRetryMillis = 3000
+)
+
+// EventStream godoc
+// @Summary Поток событий тикетов (Server-Sent Events)
+// @Description Отдаёт те же события, что и WebSocket (ticket.created, message.created, ticket.status_changed, ticket.assigned; сотрудникам также note.created и адресованные им operator.mentioned), в формате text/event-stream. Пользователь получает события только своих тикетов, оператор — всех. Параметр ticket_id сужает поток до перечисленных тикетов. При переподключении клиент передаёт заголовок Last-Event-ID (или параметр last_event_id) и получает пропущенные события из журнала
+// @Tags events
+// @Produce text/event-stream
+// @Param ticket_id query string false "ID тикетов через запятую"
+// @Param last_event_id query int false "ID последнего полученного события, если нельзя передать заголовок"
+// @Param Last-Event-ID header int false "ID последнего полученного события"
+// @Success 200 {string} string "Поток событий"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Security BearerAuth
+// @Router /events/stream [get]
+func EventStream(c *gin.Context, db *gorm.DB) {
+	role := c.GetString("role")
+
+	var userID, operatorID uint
+	if role == models.RoleUser {
+		user, ok := currentUser(c, db)
+		if !ok {
+			return
+		}
+		userID = user.ID
+	} else {
+		if !middleware.Permissions(c).Has(models.PermTicketsReadAll) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReadAll + " required"})
+			return
+		}
+		operator, ok := currentOperator(c, db)
+		if !ok {
+			return
+		}
+		operatorID = operator.ID
+	}
+
+	var ticketIDs []uint
+	if raw := c.Query("ticket_id"); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid ticket_id: " + part})
+				return
+			}
+			ticketIDs = append(ticketIDs, uint(id))
+		}
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	var afterID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID: " + lastEventID})
+			return
+		}
+		afterID = id
+	}
+
+	hub := events.Default()
+	subscriber := hub.Subscribe(role, userID, operatorID)
+	defer hub.Unsubscribe(subscriber)
+
+	if len(ticketIDs) == 0 {
+		// Для пользователя хаб всё равно отфильтрует события по владельцу тикета
+		subscriber.FollowAll(true)
+	} else {
+		for _, id := range ticketIDs {
+			var ticket models.Ticket
+			if err := db.Where("id = ?", id).First(&ticket).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Ticket %d not found", id)})
+				return
+			}
+			if role == models.RoleUser && ticket.UserID != userID {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only stream your own tickets"})
+				return
+			}
+			subscriber.Follow(ticket.ID)
+		}
+	}
+
+	// Подписка оформлена до чтения журнала, поэтому события, пришедшие во время
+	// дочитывания, не теряются; дубликаты отсекаются по ID
+	var backlog []events.Event
+	if afterID > 0 {
+		var err error
+		backlog, err = hub.Replay(subscriber, afterID, sseReplayLimit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read event log"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetryMillis)
+	for _, event := range backlog {
+		if err := writeSSEEvent(c, event); err != nil {
+			return
+		}
+		afterID = event.ID
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-subscriber.Events():
+			if !ok {
+				return
+			}
+			if event.ID <= afterID {
+				continue
+			}
+			if err := writeSSEEvent(c, event); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeSSEEvent(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
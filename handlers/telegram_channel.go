@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+	"helpdesk-api/telegram"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// telegramChannelInput структура для входных данных подключения бота
+type telegramChannelInput struct {
+	BotToken   string `json:"bot_token" binding:"required" example:"123456789:AAE..."`                                                     // Токен бота от @BotFather
+	WebhookURL string `json:"webhook_url" binding:"required,url" example:"https://helpdesk.example.com/api/channels/telegram/ift/webhook"` // Публичный адрес webhook этого стенда
+}
+
+// ListTelegramChannels godoc
+// @Summary Боты Telegram стендов
+// @Description Возвращает подключённые боты Telegram. Токены ботов и секреты webhook не отдаются
+// @Tags settings
+// @Produce json
+// @Success 200 {array} models.TelegramChannel
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/settings/telegram/ [get]
+func ListTelegramChannels(c *gin.Context, db *gorm.DB) {
+	channels := []models.TelegramChannel{}
+	if err := db.Order("stand").Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch telegram channels"})
+		return
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+// SetTelegramChannel godoc
+// @Summary Подключить бота Telegram к стенду
+// @Description Проверяет токен бота, генерирует новый секрет webhook и регистрирует webhook в Bot API (setWebhook). После этого сообщения боту превращаются в тикеты стенда. Повторный вызов меняет бота или адрес webhook и заодно секрет
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param stand path string true "Стенд"
+// @Param input body telegramChannelInput true "Бот"
+// @Success 200 {object} models.TelegramChannel
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Failure 502 {object} map[string]string "Bot API error"
+// @Security BearerAuth
+// @Router /operator/settings/telegram/{stand} [put]
+func SetTelegramChannel(c *gin.Context, db *gorm.DB, cfg *config.Config) {
+	stand := c.Param("stand")
+	if _, ok := standEndpoint(stand); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown stand: " + stand})
+		return
+	}
+	var input telegramChannelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	client := telegram.NewClient(cfg.TelegramAPIURL, input.BotToken)
+	me, err := client.GetMe(c.Request.Context())
+	if err != nil {
+		var apiErr *telegram.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bot token"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Bot API request failed: " + err.Error()})
+		return
+	}
+
+	secret, err := models.NewEndpointSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate secret"})
+		return
+	}
+	channel := models.TelegramChannel{
+		Stand:       stand,
+		BotUsername: me.Username,
+		WebhookURL:  input.WebhookURL,
+		BotToken:    input.BotToken,
+		SecretToken: secret,
+		UpdatedBy:   c.GetString("username"),
+	}
+	// Секрет сохраняется до setWebhook. Обновления, пришедшие в промежутке со старым секретом,
+	// будут отвергнуты, и Telegram доставит их повторно
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "stand"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bot_username", "webhook_url", "bot_token", "secret_token", "updated_by", "updated_at"}),
+	}).Create(&channel).Error
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save telegram channel"})
+		return
+	}
+	if err := client.SetWebhook(c.Request.Context(), input.WebhookURL, secret); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to set webhook: " + err.Error()})
+		return
+	}
+
+	if err := db.Where("stand = ?", stand).First(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load telegram channel"})
+		return
+	}
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteTelegramChannel godoc
+// @Summary Отключить бота Telegram от стенда
+// @Description Снимает webhook в Bot API (deleteWebhook) и удаляет настройки бота. Если Bot API недоступен, бот всё равно отключается: обновления с его старым секретом больше не принимаются
+// @Tags settings
+// @Produce json
+// @Param stand path string true "Стенд"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/settings/telegram/{stand} [delete]
+func DeleteTelegramChannel(c *gin.Context, db *gorm.DB, cfg *config.Config) {
+	var channel models.TelegramChannel
+	if err := db.Where("stand = ?", c.Param("stand")).First(&channel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Telegram channel not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load telegram channel"})
+		}
+		return
+	}
+
+	client := telegram.NewClient(cfg.TelegramAPIURL, channel.BotToken)
+	if err := client.DeleteWebhook(c.Request.Context()); err != nil {
+		log.Printf("Failed to delete webhook of stand %s: %v", channel.Stand, err)
+	}
+	if err := db.Delete(&channel).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete telegram channel"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Telegram channel deleted"})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/config"
+	"helpdesk-api/events"
+	"helpdesk-api/models"
+	"helpdesk-api/telegram"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TicketSourceTelegram — источник тикетов, открытых сообщением боту стенда
+const TicketSourceTelegram = "Telegram"
+
+// telegramWebhookMaxBody — предел размера одного обновления от Telegram
+const telegramWebhookMaxBody = 1 << 20
+
+// telegramSubjectLength — сколько символов первой строки сообщения попадает в тему нового тикета
+const telegramSubjectLength = 100
+
+// telegramReply — ответ на webhook с вызовом метода Bot API: Telegram выполнит его сам,
+// отдельный запрос к Bot API не нужен
+type telegramReply struct {
+	Method string `json:"method"`
+	ChatID int64  `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+// telegramIngestResult — итог обработки сообщения пользователя
+type telegramIngestResult struct {
+	Duplicate bool            // Обновление уже обработано раньше
+	Ticket    models.Ticket   // Новый или продолженный тикет
+	Message   *models.Message // Сообщение в существующем тикете; nil, если сообщение открыло новый тикет
+}
+
+// TelegramWebhook godoc
+// @Summary Webhook бота Telegram
+// @Description Принимает обновления (Update) от Telegram Bot API для бота стенда. Подлинность проверяется по заголовку X-Telegram-Bot-Api-Secret-Token. Личное сообщение пользователя продолжает его активный тикет на стенде (не resolved и не closed) или открывает новый; пользователь создаётся по from.id. Правки, сообщения в группах и от ботов пропускаются, повтор того же update_id — тоже. При политике whitelist сообщения принимаются только от пользователей с одобренным доступом к стенду
+// @Tags channels
+// @Accept json
+// @Produce json
+// @Param stand path string true "Стенд"
+// @Param X-Telegram-Bot-Api-Secret-Token header string true "Секрет webhook"
+// @Param update body telegram.Update true "Обновление Telegram"
+// @Success 200 {object} telegramReply "Пустой ответ или вызов sendMessage"
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Router /channels/telegram/{stand}/webhook [post]
+func TelegramWebhook(c *gin.Context, db *gorm.DB, cfg *config.Config, blocklist *auth.BlocklistStore) {
+	stand := c.Param("stand")
+	var channel models.TelegramChannel
+	if err := db.Where("stand = ?", stand).First(&channel).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Telegram channel not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load telegram channel"})
+		}
+		return
+	}
+	secret := c.GetHeader(telegram.HeaderSecretToken)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(channel.SecretToken)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret token"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, telegramWebhookMaxBody)
+	var update telegram.Update
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	message := update.Message
+	if message == nil || message.From == nil || message.From.IsBot || message.Chat.Type != telegram.ChatTypePrivate {
+		c.Status(http.StatusOK)
+		return
+	}
+	telegramID := strconv.FormatInt(message.From.ID, 10)
+	if blocklist.IsTelegramIDBlocked(telegramID) {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	reply := func(text string) {
+		c.JSON(http.StatusOK, telegramReply{Method: "sendMessage", ChatID: message.Chat.ID, Text: text})
+	}
+	text := strings.TrimSpace(message.Text)
+	if text == "" {
+		text = strings.TrimSpace(message.Caption)
+	}
+	if text == "" {
+		reply("Пока принимаются только текстовые сообщения. Опишите проблему текстом")
+		return
+	}
+	if text == "/start" || strings.HasPrefix(text, "/start ") {
+		reply("Здравствуйте! Опишите проблему одним сообщением — оно станет обращением в поддержку")
+		return
+	}
+
+	if cfg.ConsumerTokenPolicy == config.ConsumerPolicyWhitelist {
+		approved, err := consumerStandApproved(db, telegramID, stand)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist"})
+			return
+		}
+		if !approved {
+			reply("Доступ к стенду не одобрен, обращение не принято")
+			return
+		}
+	}
+
+	result, err := ingestTelegramMessage(db, stand, update.UpdateID, telegramID, text)
+	if err != nil {
+		// Ошибка отдаётся Telegram, и он доставит обновление повторно
+		log.Printf("Failed to ingest telegram update %d for stand %s: %v", update.UpdateID, stand, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process update"})
+		return
+	}
+	switch {
+	case result.Duplicate:
+		c.Status(http.StatusOK)
+	case result.Message != nil:
+		events.Publish(events.TypeMessageCreated, result.Ticket.ID, result.Ticket.UserID, *result.Message)
+		c.Status(http.StatusOK)
+	default:
+		events.Publish(events.TypeTicketCreated, result.Ticket.ID, result.Ticket.UserID, result.Ticket)
+		reply(fmt.Sprintf("Обращение #%d создано, оператор скоро ответит", result.Ticket.ID))
+	}
+}
+
+// telegramUpdateRetention — сколько хранить отметки об обработанных обновлениях. Telegram
+// перестаёт повторять доставку обновления примерно через сутки; остальное — запас
+const telegramUpdateRetention = 48 * time.Hour
+
+// PruneTelegramUpdates удаляет отметки об обработанных обновлениях старше retention
+func PruneTelegramUpdates(db *gorm.DB, retention time.Duration) error {
+	return db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.TelegramUpdate{}).Error
+}
+
+// ingestTelegramMessage в одной транзакции отмечает обновление обработанным, находит или создаёт
+// пользователя и добавляет текст в его активный тикет на стенде либо открывает новый
+func ingestTelegramMessage(db *gorm.DB, stand string, updateID int64, telegramID, text string) (*telegramIngestResult, error) {
+	result := &telegramIngestResult{}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		mark := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.TelegramUpdate{Stand: stand, UpdateID: updateID})
+		if mark.Error != nil {
+			return mark.Error
+		}
+		if mark.RowsAffected == 0 {
+			result.Duplicate = true
+			return nil
+		}
+
+		var user models.User
+		if err := tx.Where(models.User{TelegramID: telegramID}).FirstOrCreate(&user).Error; err != nil {
+			return err
+		}
+		// Блокировка пользователя не даёт двум его сообщениям одновременно открыть два тикета
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil {
+			return err
+		}
+
+		err := tx.Where("user_id = ? AND stand = ? AND status NOT IN ?", user.ID, stand,
+			[]string{models.TicketStatusResolved, models.TicketStatusClosed}).
+			Order("last_message_at desc").
+			First(&result.Ticket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result.Ticket = models.Ticket{
+				UserID:      user.ID,
+				Subject:     telegramSubject(text),
+				Description: text,
+				Source:      TicketSourceTelegram,
+				Stand:       stand,
+				Status:      models.TicketStatusNew,
+			}
+			return tx.Create(&result.Ticket).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		message := models.Message{
+			TicketID:  result.Ticket.ID,
+			Sender:    "user",
+			Recipient: "operator",
+			Content:   text,
+		}
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&result.Ticket).Update("last_message_at", message.Timestamp).Error; err != nil {
+			return err
+		}
+		result.Message = &message
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// telegramSubject делает тему тикета из первой строки сообщения
+func telegramSubject(text string) string {
+	subject := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	if runes := []rune(subject); len(runes) > telegramSubjectLength {
+		subject = string(runes[:telegramSubjectLength-1]) + "…"
+	}
+	return subject
+}
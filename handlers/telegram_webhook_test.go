@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+	"helpdesk-api/telegram"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const testWebhookSecret = "webhook-secret"
+
+// newTelegramTestChannel подключает к стенду бота с заданным адресом Bot API и возвращает роутер с webhook
+func newTelegramTestChannel(t *testing.T, db *gorm.DB, stand, apiURL string) (*gin.Engine, *config.Config) {
+	t.Helper()
+	channel := models.TelegramChannel{Stand: stand, BotToken: "123:test-token", SecretToken: testWebhookSecret}
+	if err := db.Create(&channel).Error; err != nil {
+		t.Fatalf("create telegram channel: %v", err)
+	}
+	cfg := &config.Config{ConsumerTokenPolicy: config.ConsumerPolicyOpen, TelegramAPIURL: apiURL}
+	router := gin.New()
+	router.POST("/channels/telegram/:stand/webhook", func(c *gin.Context) {
+		TelegramWebhook(c, db, cfg, &auth.BlocklistStore{})
+	})
+	return router, cfg
+}
+
+// postTelegramUpdate отправляет в webhook личное сообщение пользователя telegramID
+func postTelegramUpdate(router *gin.Engine, stand, secret string, updateID, telegramID int64, text string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"update_id":%d,"message":{"message_id":1,"date":1700000000,"from":{"id":%d,"is_bot":false,"first_name":"Test"},"chat":{"id":%d,"type":"private"},"text":%q}}`,
+		updateID, telegramID, telegramID, text)
+	req := httptest.NewRequest(http.MethodPost, "/channels/telegram/"+stand+"/webhook", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(telegram.HeaderSecretToken, secret)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// testTelegramID возвращает уникальный telegram_id и удаляет созданного по нему пользователя после теста
+func testTelegramID(t *testing.T, db *gorm.DB) int64 {
+	t.Helper()
+	id := time.Now().UnixNano() % 1e12
+	t.Cleanup(func() {
+		db.Where("telegram_id = ?", fmt.Sprint(id)).Delete(&models.User{})
+	})
+	return id
+}
+
+func TestTelegramWebhookRejectsInvalidSecret(t *testing.T) {
+	db := openTestDB(t)
+	stand := testStand(t, db)
+	router, _ := newTelegramTestChannel(t, db, stand, "")
+	telegramID := testTelegramID(t, db)
+
+	for _, tc := range []struct {
+		name   string
+		stand  string
+		secret string
+		want   int
+	}{
+		{"missing secret", stand, "", http.StatusUnauthorized},
+		{"wrong secret", stand, "not-the-secret", http.StatusUnauthorized},
+		{"secret prefix", stand, testWebhookSecret[:5], http.StatusUnauthorized},
+		{"unknown stand", stand + "-missing", testWebhookSecret, http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			w := postTelegramUpdate(router, tc.stand, tc.secret, 1, telegramID, "Не работает вход")
+			if w.Code != tc.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tc.want, w.Body)
+			}
+		})
+	}
+
+	var tickets int64
+	db.Model(&models.Ticket{}).Where("stand = ?", stand).Count(&tickets)
+	if tickets != 0 {
+		t.Fatalf("rejected updates created %d tickets", tickets)
+	}
+}
+
+func TestTelegramWebhookDeduplicatesUpdates(t *testing.T) {
+	db := openTestDB(t)
+	stand := testStand(t, db)
+	router, _ := newTelegramTestChannel(t, db, stand, "")
+	telegramID := testTelegramID(t, db)
+
+	first := postTelegramUpdate(router, stand, testWebhookSecret, 100, telegramID, "Не работает вход")
+	if first.Code != http.StatusOK {
+		t.Fatalf("first delivery: status = %d: %s", first.Code, first.Body)
+	}
+	var reply telegramReply
+	if err := json.Unmarshal(first.Body.Bytes(), &reply); err != nil || reply.Method != "sendMessage" || reply.ChatID != telegramID {
+		t.Fatalf("first delivery reply = %s, want sendMessage to chat %d", first.Body, telegramID)
+	}
+
+	// Telegram повторяет обновление, если не дождался ответа
+	repeat := postTelegramUpdate(router, stand, testWebhookSecret, 100, telegramID, "Не работает вход")
+	if repeat.Code != http.StatusOK || repeat.Body.Len() != 0 {
+		t.Fatalf("repeated delivery: status = %d, body %q; want empty 200", repeat.Code, repeat.Body)
+	}
+
+	next := postTelegramUpdate(router, stand, testWebhookSecret, 101, telegramID, "И пароль не сбрасывается")
+	if next.Code != http.StatusOK {
+		t.Fatalf("next update: status = %d: %s", next.Code, next.Body)
+	}
+
+	var tickets []models.Ticket
+	db.Where("stand = ?", stand).Find(&tickets)
+	if len(tickets) != 1 {
+		t.Fatalf("tickets on stand = %d, want 1", len(tickets))
+	}
+	var messages []models.Message
+	db.Where("ticket_id = ?", tickets[0].ID).Find(&messages)
+	if len(messages) != 1 || messages[0].Content != "И пароль не сбрасывается" {
+		t.Fatalf("ticket messages = %+v, want only the follow-up", messages)
+	}
+}
+
+// botAPIRequest — вызов, принятый фейковым Bot API
+type botAPIRequest struct {
+	Path   string
+	Params map[string]interface{}
+}
+
+func TestDeliverTicketReplySendsViaBotAPI(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []botAPIRequest
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var params map[string]interface{}
+		json.Unmarshal(raw, &params)
+		mu.Lock()
+		requests = append(requests, botAPIRequest{Path: r.URL.Path, Params: params})
+		mu.Unlock()
+		io.WriteString(w, `{"ok":true,"result":{"message_id":7,"chat":{"id":555,"type":"private"},"date":1700000000}}`)
+	}))
+	defer api.Close()
+
+	db := openTestDB(t)
+	stand := testStand(t, db)
+	_, cfg := newTelegramTestChannel(t, db, stand, api.URL)
+
+	ticket := models.Ticket{UserID: 1, Subject: "Вход", Stand: stand, Status: models.TicketStatusOpen}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("create ticket: %v", err)
+	}
+	answer := models.Message{TicketID: ticket.ID, Sender: "operator", Recipient: "user", Content: "Попробуйте ещё раз",
+		DeliveryStatus: models.MessageDeliveryQueued}
+	if err := db.Create(&answer).Error; err != nil {
+		t.Fatalf("create message: %v", err)
+	}
+	payload, _ := json.Marshal(ticketReplyPayload{
+		Type: "ticket_reply", TicketID: ticket.ID, MessageID: answer.ID, ChatID: 555, Message: answer.Content,
+	})
+	msg := models.OutboxMessage{ID: 1, Kind: OutboxKindTicketReply, Stand: stand, Payload: string(payload)}
+
+	if err := DeliverTicketReply(db, cfg)(context.Background(), msg); err != nil {
+		t.Fatalf("DeliverTicketReply: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 1 {
+		t.Fatalf("Bot API calls = %d, want 1", len(requests))
+	}
+	got := requests[0]
+	if got.Path != "/bot123:test-token/sendMessage" || got.Params["chat_id"] != float64(555) || got.Params["text"] != "Попробуйте ещё раз" {
+		t.Fatalf("Bot API call = %+v", got)
+	}
+	db.First(&answer, answer.ID)
+	if answer.DeliveryStatus != models.MessageDeliverySent || answer.DeliveryAttempts != 1 {
+		t.Fatalf("delivery status = %s after %d attempts, want sent after 1", answer.DeliveryStatus, answer.DeliveryAttempts)
+	}
+}
+
+func TestPruneTelegramUpdates(t *testing.T) {
+	db := openTestDB(t)
+	stand := testStand(t, db)
+
+	old := models.TelegramUpdate{Stand: stand, UpdateID: 1, CreatedAt: time.Now().Add(-telegramUpdateRetention - time.Hour)}
+	fresh := models.TelegramUpdate{Stand: stand, UpdateID: 2, CreatedAt: time.Now().Add(-time.Hour)}
+	for _, update := range []*models.TelegramUpdate{&old, &fresh} {
+		if err := db.Create(update).Error; err != nil {
+			t.Fatalf("create update: %v", err)
+		}
+	}
+
+	if err := PruneTelegramUpdates(db, telegramUpdateRetention); err != nil {
+		t.Fatalf("PruneTelegramUpdates: %v", err)
+	}
+	var left []models.TelegramUpdate
+	db.Where("stand = ?", stand).Find(&left)
+	if len(left) != 1 || left[0].UpdateID != fresh.UpdateID {
+		t.Fatalf("updates left = %+v, want only the fresh one", left)
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDSNEnv — переменная с DSN тестовой базы Postgres. Без неё тесты, которым нужна база, пропускаются
+const testDSNEnv = "HELPDESK_TEST_DSN"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// openTestDB подключается к тестовой базе и мигрирует модели. Данные тестов не пересекаются:
+// каждый тест работает со своим стендом (см. testStand) и убирает его за собой
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	err = db.AutoMigrate(&models.User{}, &models.Operator{}, &models.Role{}, &models.RolePermission{},
+		&models.Ticket{}, &models.Message{}, &models.Attachment{}, &models.Whitelist{},
+		&models.OutboxMessage{}, &models.TelegramChannel{}, &models.TelegramUpdate{})
+	if err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+// testStand возвращает уникальное имя стенда и удаляет его данные после теста
+func testStand(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	stand := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		tickets := db.Model(&models.Ticket{}).Select("id").Where("stand = ?", stand)
+		db.Where("ticket_id IN (?)", tickets).Delete(&models.Attachment{})
+		db.Where("ticket_id IN (?)", tickets).Delete(&models.Message{})
+		db.Where("stand = ?", stand).Delete(&models.Ticket{})
+		db.Where("stand = ?", stand).Delete(&models.TelegramUpdate{})
+		db.Where("stand = ?", stand).Delete(&models.TelegramChannel{})
+		db.Where("stand = ?", stand).Delete(&models.OutboxMessage{})
+	})
+	return stand
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
-	"time"
 
+	"helpdesk-api/config"
+	"helpdesk-api/events"
+	"helpdesk-api/middleware"
 	"helpdesk-api/models"
+	"helpdesk-api/storage"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -12,14 +16,15 @@ import (
 
 // createTicketInput структура для входных данных создания тикета
 type createTicketInput struct {
-	Subject     string `json:"subject" binding:"required" example:"Проблема с продуктом"`     // Тема тикета
-	Description string `json:"description" binding:"required" example:"Описание проблемы..."` // Описание проблемы
-	Source      string `json:"source" binding:"required" example:"Telegram"`                  // Источник тикета
+	Subject     string `json:"subject" binding:"required" example:"Проблема с продуктом"`      // Тема тикета
+	Description string `json:"description" binding:"required" example:"Описание проблемы..."`  // Описание проблемы
+	Source      string `json:"source" binding:"required" example:"Telegram"`                   // Источник тикета
+	Stand       string `json:"stand" binding:"omitempty,oneof=dev ift psi prom" example:"ift"` // Стенд, с которого пришёл пользователь
 }
 
 // CreateTicket godoc
 // @Summary Создать новый тикет
-// @Description Создает тикет от текущего пользователя
+// @Description Создает тикет от текущего пользователя. Если токен выдан для стенда, тикет создаётся для этого стенда, и поле stand можно не передавать
 // @Tags tickets
 // @Accept json
 // @Produce json
@@ -27,6 +32,7 @@ type createTicketInput struct {
 // @Success 201 {object} models.Ticket
 // @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Security BearerAuth
 // @Router /tickets/create [post]
@@ -54,82 +60,120 @@ func CreateTicket(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	// Стенд из токена подтверждён whitelist и важнее переданного клиентом
+	if stand := c.GetString("stand"); stand != "" {
+		if input.Stand != "" && input.Stand != stand {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token was issued for stand " + stand})
+			return
+		}
+		input.Stand = stand
+	}
+
 	ticket := models.Ticket{
 		UserID:      user.ID,
 		Subject:     input.Subject,
 		Description: input.Description,
 		Source:      input.Source,
-		Status:      "OPEN",
+		Stand:       input.Stand,
+		Status:      models.TicketStatusNew,
 	}
 	if err := db.Create(&ticket).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	events.Publish(events.TypeTicketCreated, ticket.ID, ticket.UserID, ticket)
 
 	c.JSON(http.StatusCreated, ticket)
 }
 
 // ListTickets godoc
 // @Summary Получить список тикетов
-// @Description Возвращает все тикеты для оператора или тикеты текущего пользователя
+// @Description Возвращает страницу тикетов: все тикеты для оператора или тикеты текущего пользователя. Поддерживает фильтры, сортировку и курсорную пагинацию; следующая страница запрашивается с параметром cursor из поля next_cursor. Фильтры user_id и assignee доступны только операторам
 // @Tags tickets
 // @Produce json
-// @Success 200 {array} models.Ticket
+// @Param status query string false "Статусы через запятую, например open,pending_user"
+// @Param source query string false "Источник тикета"
+// @Param user_id query int false "ID пользователя (только для операторов)"
+// @Param stand query string false "Стенд" Enums(dev, ift, psi, prom)
+// @Param created_from query string false "Создан не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Создан не позже (RFC3339 или YYYY-MM-DD)"
+// @Param updated_from query string false "Обновлён не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param updated_to query string false "Обновлён не позже (RFC3339 или YYYY-MM-DD)"
+// @Param assignee query string false "Назначение: me, none или ID оператора (только для операторов)"
+// @Param sort query string false "Поле сортировки" Enums(created_at, updated_at, last_message_at)
+// @Param order query string false "Направление сортировки" Enums(asc, desc)
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param cursor query string false "Курсор следующей страницы; действует только с теми же sort и order"
+// @Success 200 {object} ticketListResponse
+// @Failure 400 {object} map[string]string "Bad Request"
 // @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Security BearerAuth
 // @Router /tickets/ [get]
 func ListTickets(c *gin.Context, db *gorm.DB) {
-	role, _ := c.Get("role")
-	if role == "operator" {
-		var tickets []models.Ticket
-		if err := db.Find(&tickets).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tickets"})
-			return
-		}
-		c.JSON(http.StatusOK, tickets)
+	query, err := parseTicketListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Логика для пользователей
-	telegramIDInterface, exists := c.Get("telegram_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-	telegramID, ok := telegramIDInterface.(string)
-	if !ok {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid telegram_id in token"})
-		return
-	}
+	if models.IsOperatorRole(c.GetString("role")) {
+		if !middleware.Permissions(c).Has(models.PermTicketsReadAll) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReadAll + " required"})
+			return
+		}
+		if c.Query("assignee") == "me" {
+			operator, ok := currentOperator(c, db)
+			if !ok {
+				return
+			}
+			query.AssigneeID = &operator.ID
+		}
+	} else {
+		// Пользователь видит только свои тикеты, фильтры по чужим данным игнорируются
+		telegramIDInterface, exists := c.Get("telegram_id")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+			return
+		}
+		telegramID, ok := telegramIDInterface.(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid telegram_id in token"})
+			return
+		}
 
-	var user models.User
-	if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-		return
+		var user models.User
+		if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+			return
+		}
+		query.UserID = user.ID
+		query.AssigneeID = nil
+		query.Unassigned = false
 	}
 
-	var tickets []models.Ticket
-	if err := db.Where("user_id = ?", user.ID).Find(&tickets).Error; err != nil {
+	response, err := query.fetch(db)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tickets"})
 		return
 	}
-
-	c.JSON(http.StatusOK, tickets)
+	c.JSON(http.StatusOK, response)
 }
 
 // addMessageInput структура для входных данных сообщения
+// (в JSON или в полях multipart-формы вместе с файлами)
 type addMessageInput struct {
-	Sender    string `json:"sender" binding:"required" example:"user"`
-	Recipient string `json:"recipient" binding:"required" example:"operator"`
-	Content   string `json:"content" binding:"required" example:"Сообщение"`
+	Sender    string `json:"sender" form:"sender" binding:"required" example:"user"`
+	Recipient string `json:"recipient" form:"recipient" binding:"required" example:"operator"`
+	Content   string `json:"content" form:"content" example:"Сообщение"` // Может быть пустым, если приложены файлы
 }
 
 // AddMessage godoc
 // @Summary Добавить сообщение в тикет
-// @Description Добавляет сообщение в указанный тикет
+// @Description Добавляет сообщение в указанный тикет. Принимает JSON или multipart/form-data с полями sender, recipient, content и файлами в поле files; число, размер и типы файлов ограничены настройками ATTACHMENT_*, тип определяется по содержимому. Вложения не пересылаются в чат или на почту пользователя, поэтому ответ, уходящий пользователю, должен содержать текст. Ответ оператора пользователю (sender=operator, recipient=user) ставится в очередь доставки: в чат пользователя через бота стенда или на URL стенда, а по тикетам из почты — письмом в ту же цепочку; ход доставки виден в полях delivery_status (queued, sent, failed), delivery_attempts и delivery_error сообщения
 // @Tags messages
-// @Accept json
+// @Accept json,mpfd
 // @Produce json
 // @Param ticket_id path string true "ID тикета"
 // @Param message body addMessageInput true "Данные сообщения"
@@ -138,11 +182,13 @@ type addMessageInput struct {
 // @Failure 401 {object} map[string]string "Unauthorized"
 // @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 404 {object} map[string]string "Not Found"
+// @Failure 413 {object} map[string]string "Request Entity Too Large"
+// @Failure 415 {object} map[string]string "Unsupported Media Type"
 // @Failure 500 {object} map[string]string "Internal Server Error"
 // @Security BearerAuth
 // @Router /tickets/{ticket_id}/messages/ [post]
-func AddMessage(c *gin.Context, db *gorm.DB) {
-	role, _ := c.Get("role")
+func AddMessage(c *gin.Context, db *gorm.DB, cfg *config.Config, store storage.Storage) {
+	role := c.GetString("role")
 	ticketID := c.Param("ticket_id")
 	var ticket models.Ticket
 	if err := db.Where("id = ?", ticketID).First(&ticket).Error; err != nil {
@@ -151,17 +197,36 @@ func AddMessage(c *gin.Context, db *gorm.DB) {
 	}
 
 	var input addMessageInput
-	if err := c.ShouldBindJSON(&input); err != nil {
+	limitMessageBody(c, cfg)
+	if err := c.ShouldBind(&input); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Request body too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	files, err := attachmentUploads(c, cfg)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+	if input.Content == "" && len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message must have content or files"})
+		return
+	}
 
 	if input.Sender != "user" && input.Sender != "operator" || input.Recipient != "user" && input.Recipient != "operator" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Sender and Recipient must be 'user' or 'operator'"})
 		return
 	}
 
-	if role == "operator" {
+	if models.IsOperatorRole(role) {
+		if !middleware.Permissions(c).Has(models.PermTicketsReply) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReply + " required"})
+			return
+		}
 		if input.Sender != "operator" {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Operators can only send as 'operator'"})
 			return
@@ -193,23 +258,55 @@ func AddMessage(c *gin.Context, db *gorm.DB) {
 		}
 	}
 
+	// Ответ оператора отправляется пользователю, если есть куда: в чат через стенд тикета или на почту
+	deliver := input.Sender == "operator" && input.Recipient == "user" && ticketReplyDeliverable(ticket)
+	if deliver && input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply to the user must have content: attachments are not forwarded to the user's channel"})
+		return
+	}
+
+	attachments, err := storeAttachments(c.Request.Context(), store, cfg, ticket.ID, files)
+	if err != nil {
+		respondAttachmentError(c, err)
+		return
+	}
+
 	message := models.Message{
-		TicketID:  ticket.ID,
-		Sender:    input.Sender,
-		Recipient: input.Recipient,
-		Content:   input.Content,
+		TicketID:    ticket.ID,
+		Sender:      input.Sender,
+		Recipient:   input.Recipient,
+		Content:     input.Content,
+		Attachments: attachments,
 	}
-	if err := db.Create(&message).Error; err != nil {
+	if deliver {
+		message.DeliveryStatus = models.MessageDeliveryQueued
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		if deliver {
+			return enqueueTicketReply(tx, ticket, message)
+		}
+		return nil
+	})
+	if err != nil {
+		deleteStoredAttachments(store, attachments)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if err := db.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Update("last_message_at", message.Timestamp).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket: " + err.Error()})
+		return
+	}
+	events.Publish(events.TypeMessageCreated, ticket.ID, ticket.UserID, message)
 
 	c.JSON(http.StatusCreated, message)
 }
 
 // GetTicketHistory godoc
 // @Summary Получить историю сообщений тикета
-// @Description Возвращает все сообщения для указанного тикета
+// @Description Возвращает все сообщения для указанного тикета вместе с описанием вложений. Сотрудникам возвращаются и внутренние заметки (internal=true) вперемешку с перепиской, в порядке времени; пользователю — только переписка. сами файлы скачиваются через /tickets/{ticket_id}/attachments/{attachment_id}
 // @Tags messages
 // @Produce json
 // @Param ticket_id path string true "ID тикета"
@@ -221,45 +318,63 @@ func AddMessage(c *gin.Context, db *gorm.DB) {
 // @Security BearerAuth
 // @Router /tickets/{ticket_id}/messages/ [get]
 func GetTicketHistory(c *gin.Context, db *gorm.DB) {
-	role, _ := c.Get("role")
 	ticketID := c.Param("ticket_id")
 	var ticket models.Ticket
 	if err := db.Where("id = ?", ticketID).First(&ticket).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
 		return
 	}
-
-	if role != "operator" {
-		telegramIDVal, exists := c.Get("telegram_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-			return
-		}
-		telegramID, ok := telegramIDVal.(string)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid telegram_id type"})
-			return
-		}
-
-		var user models.User
-		if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
-			return
-		}
-		if ticket.UserID != user.ID {
-			c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own tickets"})
-			return
-		}
+	if !authorizeTicketHistory(c, db, ticket) {
+		return
 	}
 
+	query := db.Preload("Attachments").Where("ticket_id = ?", ticket.ID)
+	if !models.IsOperatorRole(c.GetString("role")) {
+		// Внутренние заметки операторов пользователю не показываются
+		query = query.Where("internal = ?", false)
+	}
 	var messages []models.Message
-	if err := db.Where("ticket_id = ?", ticket.ID).Order("timestamp asc").Find(&messages).Error; err != nil {
+	if err := query.Order("timestamp asc").Find(&messages).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching messages"})
 		return
 	}
 	c.JSON(http.StatusOK, messages)
 }
 
+// authorizeTicketHistory проверяет доступ к переписке тикета и его вложениям: сотруднику нужно
+// право tickets.read_all, пользователю — быть владельцем тикета. При отказе ответ клиенту уже отправлен.
+func authorizeTicketHistory(c *gin.Context, db *gorm.DB, ticket models.Ticket) bool {
+	if models.IsOperatorRole(c.GetString("role")) {
+		if !middleware.Permissions(c).Has(models.PermTicketsReadAll) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReadAll + " required"})
+			return false
+		}
+		return true
+	}
+
+	telegramIDVal, exists := c.Get("telegram_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return false
+	}
+	telegramID, ok := telegramIDVal.(string)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid telegram_id type"})
+		return false
+	}
+
+	var user models.User
+	if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return false
+	}
+	if ticket.UserID != user.ID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own tickets"})
+		return false
+	}
+	return true
+}
+
 // CloseTicket godoc
 // @Summary Закрыть тикет
 // @Description Закрывает указанный тикет
@@ -275,46 +390,167 @@ func GetTicketHistory(c *gin.Context, db *gorm.DB) {
 // @Security BearerAuth
 // @Router /tickets/{ticket_id}/close/ [post]
 func CloseTicket(c *gin.Context, db *gorm.DB) {
+	ticket, ok := findTicketForActor(c, db)
+	if !ok {
+		return
+	}
+	if !applyTicketTransition(c, db, ticket, models.TicketStatusClosed) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket closed successfully", "ticket": ticket})
+}
+
+// ReopenTicket godoc
+// @Summary Переоткрыть тикет
+// @Description Переоткрывает решённый или закрытый тикет. Пользователь может переоткрыть только свой тикет
+// @Tags tickets
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Success 200 {object} models.Ticket
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /tickets/{ticket_id}/reopen/ [post]
+func ReopenTicket(c *gin.Context, db *gorm.DB) {
+	ticket, ok := findTicketForActor(c, db)
+	if !ok {
+		return
+	}
+	if !applyTicketTransition(c, db, ticket, models.TicketStatusReopened) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket reopened successfully", "ticket": ticket})
+}
+
+// transitionTicketInput структура для входных данных смены статуса тикета
+type transitionTicketInput struct {
+	Status string `json:"status" binding:"required" example:"pending_user"` // Новый статус тикета
+}
+
+// TransitionTicket godoc
+// @Summary Сменить статус тикета
+// @Description Переводит тикет в новый статус согласно таблице допустимых переходов (new, open, pending_user, pending_operator, resolved, closed, reopened). Пользователь может только закрыть или переоткрыть свой тикет
+// @Tags tickets
+// @Accept json
+// @Produce json
+// @Param ticket_id path string true "ID тикета"
+// @Param input body transitionTicketInput true "Новый статус"
+// @Success 200 {object} models.Ticket
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 409 {object} map[string]string "Conflict"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /tickets/{ticket_id}/transition [post]
+func TransitionTicket(c *gin.Context, db *gorm.DB) {
+	var input transitionTicketInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ticket, ok := findTicketForActor(c, db)
+	if !ok {
+		return
+	}
+	if !applyTicketTransition(c, db, ticket, input.Status) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Ticket status changed", "ticket": ticket})
+}
+
+// findTicketForActor загружает тикет из пути запроса с учётом роли:
+// оператор видит любой тикет, пользователь — только свои
+func findTicketForActor(c *gin.Context, db *gorm.DB) (*models.Ticket, bool) {
 	ticketID := c.Param("ticket_id")
 
-	role, exists := c.Get("role")
-	if !exists {
+	role := c.GetString("role")
+	if role == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
+		return nil, false
 	}
 
 	var ticket models.Ticket
 	var err error
 
-	switch role {
-	case "user":
+	switch {
+	case role == models.RoleUser:
 		telegramID, _ := c.Get("telegram_id")
 		err = db.Where("id = ? AND user_id = (SELECT id FROM users WHERE telegram_id = ?)", ticketID, telegramID).First(&ticket).Error
-	case "operator":
-		// Оператор может закрывать любой тикет
+	case middleware.Permissions(c).Has(models.PermTicketsReadAll):
+		// Сотрудник с правом просмотра всех тикетов может работать с любым тикетом
 		err = db.Where("id = ?", ticketID).First(&ticket).Error
 	default:
-		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid role"})
-		return
+		c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + models.PermTicketsReadAll + " required"})
+		return nil, false
 	}
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket not found"})
-		return
+		return nil, false
 	}
+	return &ticket, true
+}
 
-	if ticket.Status == "CLOSED" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ticket already closed"})
-		return
+// applyTicketTransition проводит тикет через машину состояний и сохраняет результат.
+// При ошибке ответ клиенту уже отправлен.
+func applyTicketTransition(c *gin.Context, db *gorm.DB, ticket *models.Ticket, to string) bool {
+	role := c.GetString("role")
+	if models.IsOperatorRole(role) {
+		required := models.PermTicketsReply
+		if to == models.TicketStatusClosed {
+			required = models.PermTicketsCloseAny
+		}
+		if !middleware.Permissions(c).Has(required) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + required + " required"})
+			return false
+		}
 	}
 
-	ticket.Status = "CLOSED"
-	ticket.ClosedBy = role.(string) // Сохраняем, кто закрыл тикет
-	ticket.ClosedAt = time.Now()
-	if err := db.Save(&ticket).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to close ticket"})
-		return
+	from := ticket.Status
+	if err := ticket.Transition(to, role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ticket closed successfully", "ticket": ticket})
+	// Обновляем только поля статуса и только если тикет не успели перевести параллельно
+	result := db.Model(&models.Ticket{}).
+		Where("id = ? AND status = ?", ticket.ID, from).
+		Updates(map[string]interface{}{
+			"status":    ticket.Status,
+			"closed_at": ticket.ClosedAt,
+			"closed_by": ticket.ClosedBy,
+		})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket status"})
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket status was changed concurrently, reload and retry"})
+		return false
+	}
+	events.Publish(events.TypeTicketStatusChanged, ticket.ID, ticket.UserID, gin.H{"previous_status": from, "ticket": ticket})
+	return true
+}
+
+// currentUser возвращает пользователя, от имени которого выполняется запрос.
+// При ошибке ответ клиенту уже отправлен.
+func currentUser(c *gin.Context, db *gorm.DB) (*models.User, bool) {
+	telegramID := c.GetString("telegram_id")
+	if telegramID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	var user models.User
+	if err := db.Where("telegram_id = ?", telegramID).First(&user).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return &user, true
 }
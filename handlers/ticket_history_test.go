@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+	"helpdesk-api/storage"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// withActor кладёт в контекст запроса то же, что JWTMiddleware для токена с ролью role
+func withActor(role, telegramID string, permissions auth.PermissionSet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("role", role)
+		if telegramID != "" {
+			c.Set("telegram_id", telegramID)
+		}
+		c.Set("permissions", permissions)
+		c.Next()
+	}
+}
+
+// historyRouter возвращает роутер с историей тикета и скачиванием вложений для заданного актора
+func historyRouter(db *gorm.DB, store storage.Storage, actor gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(actor)
+	router.GET("/tickets/:ticket_id/messages/", func(c *gin.Context) { GetTicketHistory(c, db) })
+	router.GET("/tickets/:ticket_id/attachments/:attachment_id", func(c *gin.Context) { DownloadAttachment(c, db, store) })
+	return router
+}
+
+// ticketWithNote — тикет пользователя с ответом оператора и внутренней заметкой, у каждого по вложению
+type ticketWithNote struct {
+	telegramID     string
+	ticket         models.Ticket
+	reply, note    models.Message
+	replyFile      models.Attachment
+	noteFile       models.Attachment
+	replyFileBytes string
+}
+
+func createTicketWithNote(t *testing.T, db *gorm.DB, store storage.Storage) ticketWithNote {
+	t.Helper()
+	stand := testStand(t, db)
+	telegramID := fmt.Sprint(testTelegramID(t, db))
+	user := models.User{TelegramID: telegramID}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	f := ticketWithNote{telegramID: telegramID, replyFileBytes: "reply attachment"}
+	f.ticket = models.Ticket{UserID: user.ID, Subject: "Вход", Stand: stand, Status: models.TicketStatusOpen}
+	if err := db.Create(&f.ticket).Error; err != nil {
+		t.Fatalf("create ticket: %v", err)
+	}
+	f.reply = models.Message{TicketID: f.ticket.ID, Sender: "operator", Recipient: "user", Content: "Попробуйте ещё раз"}
+	f.note = models.Message{TicketID: f.ticket.ID, Sender: "operator", Recipient: "operator", Content: "@petrov это опять SSO", Internal: true}
+	for _, message := range []*models.Message{&f.reply, &f.note} {
+		if err := db.Create(message).Error; err != nil {
+			t.Fatalf("create message: %v", err)
+		}
+	}
+
+	for _, a := range []struct {
+		file    *models.Attachment
+		message models.Message
+		content string
+	}{
+		{&f.replyFile, f.reply, f.replyFileBytes},
+		{&f.noteFile, f.note, "note attachment"},
+	} {
+		key := fmt.Sprintf("tickets/%d/%d", f.ticket.ID, a.message.ID)
+		if err := store.Put(context.Background(), key, strings.NewReader(a.content), int64(len(a.content)), "text/plain"); err != nil {
+			t.Fatalf("store attachment: %v", err)
+		}
+		*a.file = models.Attachment{MessageID: a.message.ID, TicketID: f.ticket.ID, Filename: "log.txt",
+			ContentType: "text/plain", Size: int64(len(a.content)), SHA256: "-", StorageKey: key}
+		if err := db.Create(a.file).Error; err != nil {
+			t.Fatalf("create attachment: %v", err)
+		}
+	}
+	return f
+}
+
+func getHistory(t *testing.T, router *gin.Engine, ticketID uint) []models.Message {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tickets/%d/messages/", ticketID), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("history: status = %d: %s", w.Code, w.Body)
+	}
+	var messages []models.Message
+	if err := json.Unmarshal(w.Body.Bytes(), &messages); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	return messages
+}
+
+func download(router *gin.Engine, attachment models.Attachment) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/tickets/%d/attachments/%d", attachment.TicketID, attachment.ID), nil))
+	return w
+}
+
+func TestTicketHistoryHidesNotesFromUser(t *testing.T) {
+	db := openTestDB(t)
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	f := createTicketWithNote(t, db, store)
+
+	user := historyRouter(db, store, withActor(models.RoleUser, f.telegramID, nil))
+	messages := getHistory(t, user, f.ticket.ID)
+	if len(messages) != 1 || messages[0].ID != f.reply.ID || messages[0].Internal {
+		t.Fatalf("user history = %+v, want only the reply", messages)
+	}
+
+	staff := historyRouter(db, store, withActor(models.RoleOperator, "", auth.PermissionSet{models.PermTicketsReadAll: true}))
+	messages = getHistory(t, staff, f.ticket.ID)
+	if len(messages) != 2 {
+		t.Fatalf("staff history has %d messages, want reply and note", len(messages))
+	}
+}
+
+func TestDownloadAttachmentHidesNoteFilesFromUser(t *testing.T) {
+	db := openTestDB(t)
+	store, err := storage.NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	f := createTicketWithNote(t, db, store)
+
+	user := historyRouter(db, store, withActor(models.RoleUser, f.telegramID, nil))
+	if w := download(user, f.replyFile); w.Code != http.StatusOK || w.Body.String() != f.replyFileBytes {
+		t.Fatalf("user downloading reply attachment: status = %d, body %q", w.Code, w.Body)
+	}
+	if w := download(user, f.noteFile); w.Code != http.StatusNotFound {
+		t.Fatalf("user downloading note attachment: status = %d, want 404", w.Code)
+	}
+
+	staff := historyRouter(db, store, withActor(models.RoleOperator, "", auth.PermissionSet{models.PermTicketsReadAll: true}))
+	if w := download(staff, f.noteFile); w.Code != http.StatusOK {
+		t.Fatalf("staff downloading note attachment: status = %d, want 200", w.Code)
+	}
+}
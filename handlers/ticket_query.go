@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTicketPageSize = 50
+	maxTicketPageSize     = 200
+)
+
+// ticketSortColumns — поля, по которым разрешена сортировка списка тикетов
+var ticketSortColumns = map[string]string{
+	"created_at":      "created_at",
+	"updated_at":      "updated_at",
+	"last_message_at": "last_message_at",
+}
+
+// ticketListResponse — конверт ответа со страницей тикетов
+type ticketListResponse struct {
+	Items      []models.Ticket `json:"items"`
+	NextCursor string          `json:"next_cursor"` // Пустой, если страниц больше нет
+	Total      int64           `json:"total"`       // Общее число тикетов, подходящих под фильтры
+}
+
+// ticketCursor — позиция в отсортированной выборке: значение поля сортировки и ID последнего тикета.
+// Сортировка, для которой выдан курсор, хранится в нём же: с другой сортировкой позиция не имеет смысла
+type ticketCursor struct {
+	Sort       string    `json:"s"`
+	Descending bool      `json:"d"`
+	Value      time.Time `json:"v"`
+	ID         uint      `json:"id"`
+}
+
+// encodeTicketCursor кодирует курсор для параметра cursor
+func encodeTicketCursor(cursor ticketCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeTicketCursor разбирает параметр cursor
+func decodeTicketCursor(value string) (*ticketCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	var cursor ticketCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Sort == "" {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// ticketListQuery — разобранные параметры выборки тикетов: фильтры, сортировка и курсор
+type ticketListQuery struct {
+	Statuses    []string
+	Source      string
+	UserID      uint
+	Stand       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	AssigneeID  *uint
+	Unassigned  bool
+	SortColumn  string
+	Descending  bool
+	Limit       int
+	Cursor      *ticketCursor
+}
+
+// parseTicketListQuery разбирает query-параметры списка тикетов.
+// Фильтр assignee=me разрешается вызывающим кодом, т.к. требует знать текущего оператора.
+func parseTicketListQuery(c *gin.Context) (*ticketListQuery, error) {
+	q := &ticketListQuery{
+		Source:     c.Query("source"),
+		Stand:      c.Query("stand"),
+		SortColumn: "created_at",
+		Descending: true,
+		Limit:      defaultTicketPageSize,
+	}
+
+	if statuses := c.Query("status"); statuses != "" {
+		for _, status := range strings.Split(statuses, ",") {
+			status = strings.TrimSpace(status)
+			if !models.IsValidTicketStatus(status) {
+				return nil, fmt.Errorf("unknown status: %s", status)
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user_id: %s", userID)
+		}
+		q.UserID = uint(id)
+	}
+
+	switch assignee := c.Query("assignee"); assignee {
+	case "", "me":
+	case "none":
+		q.Unassigned = true
+	default:
+		id, err := strconv.ParseUint(assignee, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("assignee must be 'me', 'none' or operator ID")
+		}
+		operatorID := uint(id)
+		q.AssigneeID = &operatorID
+	}
+
+	var err error
+	if q.CreatedFrom, err = parseTimeParam(c, "created_from", false); err != nil {
+		return nil, err
+	}
+	if q.CreatedTo, err = parseTimeParam(c, "created_to", true); err != nil {
+		return nil, err
+	}
+	if q.UpdatedFrom, err = parseTimeParam(c, "updated_from", false); err != nil {
+		return nil, err
+	}
+	if q.UpdatedTo, err = parseTimeParam(c, "updated_to", true); err != nil {
+		return nil, err
+	}
+
+	if sort := c.Query("sort"); sort != "" {
+		column, ok := ticketSortColumns[sort]
+		if !ok {
+			return nil, fmt.Errorf("sort must be one of created_at, updated_at, last_message_at")
+		}
+		q.SortColumn = column
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "desc":
+	case "asc":
+		q.Descending = false
+	default:
+		return nil, fmt.Errorf("order must be 'asc' or 'desc'")
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if n > maxTicketPageSize {
+			n = maxTicketPageSize
+		}
+		q.Limit = n
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if q.Cursor, err = decodeTicketCursor(cursor); err != nil {
+			return nil, err
+		}
+		if q.Cursor.Sort != q.SortColumn || q.Cursor.Descending != q.Descending {
+			return nil, fmt.Errorf("cursor was issued for a different sort or order")
+		}
+	}
+
+	return q, nil
+}
+
+// parseTimeParam разбирает дату в формате RFC3339 или YYYY-MM-DD.
+// Для верхней границы дата без времени трактуется как конец дня.
+func parseTimeParam(c *gin.Context, name string, upper bool) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: expected RFC3339 or YYYY-MM-DD", name)
+	}
+	if upper {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// applyFilters накладывает на запрос фильтры без курсора и сортировки.
+// Используется и для подсчёта total, и для поиска.
+func (q *ticketListQuery) applyFilters(query *gorm.DB) *gorm.DB {
+	if len(q.Statuses) > 0 {
+		query = query.Where("tickets.status IN ?", q.Statuses)
+	}
+	if q.Source != "" {
+		query = query.Where("tickets.source = ?", q.Source)
+	}
+	if q.UserID != 0 {
+		query = query.Where("tickets.user_id = ?", q.UserID)
+	}
+	if q.Stand != "" {
+		query = query.Where("tickets.stand = ?", q.Stand)
+	}
+	if q.CreatedFrom != nil {
+		query = query.Where("tickets.created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		query = query.Where("tickets.created_at <= ?", *q.CreatedTo)
+	}
+	if q.UpdatedFrom != nil {
+		query = query.Where("tickets.updated_at >= ?", *q.UpdatedFrom)
+	}
+	if q.UpdatedTo != nil {
+		query = query.Where("tickets.updated_at <= ?", *q.UpdatedTo)
+	}
+	if q.AssigneeID != nil {
+		query = query.Where("tickets.assignee_id = ?", *q.AssigneeID)
+	}
+	if q.Unassigned {
+		query = query.Where("tickets.assignee_id IS NULL")
+	}
+	return query
+}
+
+// fetch возвращает страницу тикетов, курсор следующей страницы и общее количество
+func (q *ticketListQuery) fetch(db *gorm.DB) (*ticketListResponse, error) {
+	var total int64
+	if err := q.applyFilters(db.Model(&models.Ticket{})).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	direction, comparison := "DESC", "<"
+	if !q.Descending {
+		direction, comparison = "ASC", ">"
+	}
+
+	query := q.applyFilters(db.Model(&models.Ticket{}))
+	if q.Cursor != nil {
+		query = query.Where(fmt.Sprintf("(tickets.%s, tickets.id) %s (?, ?)", q.SortColumn, comparison), q.Cursor.Value, q.Cursor.ID)
+	}
+
+	tickets := []models.Ticket{}
+	err := query.
+		Order(fmt.Sprintf("tickets.%s %s, tickets.id %s", q.SortColumn, direction, direction)).
+		Limit(q.Limit + 1).
+		Find(&tickets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := &ticketListResponse{Items: tickets, Total: total}
+	if len(tickets) > q.Limit {
+		response.Items = tickets[:q.Limit]
+		last := response.Items[q.Limit-1]
+		response.NextCursor = encodeTicketCursor(ticketCursor{
+			Sort:       q.SortColumn,
+			Descending: q.Descending,
+			Value:      ticketSortValue(last, q.SortColumn),
+			ID:         last.ID,
+		})
+	}
+	return response, nil
+}
+
+func ticketSortValue(ticket models.Ticket, column string) time.Time {
+	switch column {
+	case "updated_at":
+		return ticket.UpdatedAt
+	case "last_message_at":
+		return ticket.LastMessageAt
+	default:
+		return ticket.CreatedAt
+	}
+}
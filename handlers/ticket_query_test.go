@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryContext возвращает контекст gin с запросом GET и заданной строкой query-параметров
+func queryContext(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/tickets/?"+rawQuery, nil)
+	return c
+}
+
+func TestTicketCursorRoundTrip(t *testing.T) {
+	cursor := ticketCursor{
+		Sort:       "last_message_at",
+		Descending: false,
+		Value:      time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC),
+		ID:         42,
+	}
+	decoded, err := decodeTicketCursor(encodeTicketCursor(cursor))
+	if err != nil {
+		t.Fatalf("decodeTicketCursor: %v", err)
+	}
+	if decoded.Sort != cursor.Sort || decoded.Descending != cursor.Descending || !decoded.Value.Equal(cursor.Value) || decoded.ID != cursor.ID {
+		t.Fatalf("decoded = %+v, want %+v", decoded, cursor)
+	}
+}
+
+func TestDecodeTicketCursorRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value string
+	}{
+		{"not base64", "!!!"},
+		{"not json", "bm90IGpzb24"},
+		// {"v":"2024-01-01T00:00:00Z","id":1} — курсор, выданный до того, как в нём появилась сортировка
+		{"without sort", "eyJ2IjoiMjAyNC0wMS0wMVQwMDowMDowMFoiLCJpZCI6MX0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := decodeTicketCursor(tc.value); err == nil {
+				t.Fatalf("decodeTicketCursor(%q) accepted", tc.value)
+			}
+		})
+	}
+}
+
+func TestParseTicketListQueryCursor(t *testing.T) {
+	issued := encodeTicketCursor(ticketCursor{Sort: "updated_at", Descending: true, Value: time.Now(), ID: 7})
+	for _, tc := range []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{"same sort and order", "sort=updated_at&order=desc&cursor=" + issued, false},
+		{"default order", "sort=updated_at&cursor=" + issued, false},
+		{"other sort", "sort=created_at&cursor=" + issued, true},
+		{"default sort", "cursor=" + issued, true},
+		{"other order", "sort=updated_at&order=asc&cursor=" + issued, true},
+		{"garbage", "cursor=abc", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := parseTicketListQuery(queryContext(tc.query))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("accepted cursor with %s", tc.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTicketListQuery: %v", err)
+			}
+			if q.Cursor == nil || q.Cursor.ID != 7 {
+				t.Fatalf("Cursor = %+v", q.Cursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"helpdesk-api/config"
+	"helpdesk-api/events"
+	"helpdesk-api/models"
+	"helpdesk-api/outbox"
+	"helpdesk-api/telegram"
+
+	"gorm.io/gorm"
+)
+
+// OutboxKindTicketReply — тип сообщения outbox с ответом оператора, который нужно доставить в чат пользователя
+const OutboxKindTicketReply = "ticket.reply"
+
+// ticketReplyPayload — ответ оператора в outbox. Он же уходит на стенд, если у стенда нет
+// своего бота: поля chatId и message совпадают с уведомлением о решении по whitelist,
+// так что бот стенда может пересылать оба одинаково
+type ticketReplyPayload struct {
+	Type       string `json:"type"`
+	TicketID   uint   `json:"ticketId"`
+	MessageID  uint   `json:"messageId"`
+	TelegramID string `json:"telegramId"`
+	ChatID     int64  `json:"chatId"`
+	Message    string `json:"message"`
+}
+
+// ticketReplyDeliverable сообщает, есть ли куда доставить ответ по тикету: в чат пользователя
+// через стенд тикета или на почту, если тикет пришёл письмом
+func ticketReplyDeliverable(ticket models.Ticket) bool {
+	return ticket.Stand != "" || ticket.Source == TicketSourceEmail
+}
+
+// enqueueTicketReply ставит ответ оператора в очередь доставки пользователю. Вызывается в транзакции,
+// в которой создано сообщение; сообщение уже должно иметь статус MessageDeliveryQueued
+func enqueueTicketReply(tx *gorm.DB, ticket models.Ticket, message models.Message) error {
+	var user models.User
+	if err := tx.First(&user, ticket.UserID).Error; err != nil {
+		return err
+	}
+	if ticket.Source == TicketSourceEmail {
+		payload := emailReplyPayload{
+			TicketID:  ticket.ID,
+			MessageID: message.ID,
+			To:        user.Email,
+		}
+		return outbox.Enqueue(tx, OutboxKindEmailReply, ticket.Stand, payload)
+	}
+
+	chatID, err := userChatID(tx, user.TelegramID, ticket.Stand)
+	if err != nil {
+		return err
+	}
+
+	payload := ticketReplyPayload{
+		Type:       OutboxKindTicketReply,
+		TicketID:   ticket.ID,
+		MessageID:  message.ID,
+		TelegramID: user.TelegramID,
+		ChatID:     chatID,
+		Message:    message.Content,
+	}
+	return outbox.Enqueue(tx, OutboxKindTicketReply, ticket.Stand, payload)
+}
+
+// userChatID возвращает чат пользователя с ботом стенда. Берётся из заявки whitelist,
+// а если её нет — telegram_id: у личного чата с ботом тот же ID, что и у пользователя
+func userChatID(db *gorm.DB, telegramID, stand string) (int64, error) {
+	var whitelist models.Whitelist
+	err := db.Select("chat_id").Where("telegram_id = ? AND \"from\" = ?", telegramID, stand).First(&whitelist).Error
+	if err == nil && whitelist.ChatID != 0 {
+		return whitelist.ChatID, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	chatID, err := strconv.ParseInt(telegramID, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("no chat for telegram_id %s on stand %s", telegramID, stand)
+	}
+	return chatID, nil
+}
+
+// DeliverTicketReply возвращает обработчик outbox, доставляющий ответ оператора: через бота стенда,
+// если он подключён (см. SetTelegramChannel), иначе — на URL стенда, как уведомления whitelist.
+// Результат каждой попытки записывается в сообщение
+func DeliverTicketReply(db *gorm.DB, cfg *config.Config) outbox.Handler {
+	return func(ctx context.Context, msg models.OutboxMessage) error {
+		var payload ticketReplyPayload
+		if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid ticket reply payload: %w", err)
+		}
+
+		err := sendTicketReply(ctx, db, cfg, msg, payload)
+		recordReplyAttempt(db, payload.MessageID, msg.Attempts+1, err)
+		return err
+	}
+}
+
+// recordReplyAttempt записывает в сообщение результат попытки доставки
+func recordReplyAttempt(db *gorm.DB, messageID uint, attempts int, err error) {
+	updates := map[string]interface{}{"delivery_attempts": attempts}
+	if err != nil {
+		updates["delivery_error"] = err.Error()
+	} else {
+		updates["delivery_status"] = models.MessageDeliverySent
+		updates["delivery_error"] = ""
+		updates["delivered_at"] = time.Now()
+	}
+	updateReplyDelivery(db, messageID, updates)
+}
+
+// sendTicketReply выполняет одну попытку доставки ответа
+func sendTicketReply(ctx context.Context, db *gorm.DB, cfg *config.Config, msg models.OutboxMessage, payload ticketReplyPayload) error {
+	var channel models.TelegramChannel
+	err := db.WithContext(ctx).Where("stand = ?", msg.Stand).First(&channel).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return postToStand(ctx, msg.Stand, fmt.Sprintf("outbox-%d", msg.ID), []byte(msg.Payload))
+	}
+	if err != nil {
+		return err
+	}
+	client := telegram.NewClient(cfg.TelegramAPIURL, channel.BotToken)
+	_, err = client.SendMessage(ctx, payload.ChatID, payload.Message)
+	return err
+}
+
+// TicketReplyDead — обработчик outbox для ответов, исчерпавших попытки доставки,
+// в чат (OutboxKindTicketReply) или на почту (OutboxKindEmailReply)
+func TicketReplyDead(db *gorm.DB) outbox.DeadHandler {
+	return func(msg models.OutboxMessage, err error) {
+		var payload struct {
+			MessageID uint `json:"messageId"`
+		}
+		if jsonErr := json.Unmarshal([]byte(msg.Payload), &payload); jsonErr != nil {
+			log.Printf("Invalid ticket reply payload in outbox message %d: %v", msg.ID, jsonErr)
+			return
+		}
+		updateReplyDelivery(db, payload.MessageID, map[string]interface{}{
+			"delivery_status": models.MessageDeliveryFailed,
+			"delivery_error":  err.Error(),
+		})
+	}
+}
+
+// updateReplyDelivery сохраняет состояние доставки ответа и оповещает подписчиков тикета
+func updateReplyDelivery(db *gorm.DB, messageID uint, updates map[string]interface{}) {
+	if err := db.Model(&models.Message{}).Where("id = ?", messageID).Updates(updates).Error; err != nil {
+		log.Printf("Failed to update delivery status of message %d: %v", messageID, err)
+		return
+	}
+
+	var message models.Message
+	var ticket models.Ticket
+	if err := db.First(&message, messageID).Error; err != nil {
+		return
+	}
+	if err := db.Select("id", "user_id").First(&ticket, message.TicketID).Error; err != nil {
+		return
+	}
+	events.Publish(events.TypeMessageDelivery, ticket.ID, ticket.UserID, message)
+}
+
+// requeueFailedTicketReplies возвращает в статус queued ответы, чьи сообщения outbox
+// снова поставлены в очередь ручной переотправкой
+func requeueFailedTicketReplies(db *gorm.DB) error {
+	return db.Exec(`UPDATE messages SET delivery_status = ?, delivery_attempts = 0
+		WHERE delivery_status = ? AND id IN (
+			SELECT (payload->>'messageId')::bigint FROM outbox_messages WHERE kind IN ? AND status = ?
+		)`, models.MessageDeliveryQueued, models.MessageDeliveryFailed,
+		[]string{OutboxKindTicketReply, OutboxKindEmailReply}, models.OutboxStatusPending).Error
+}
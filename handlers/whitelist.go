@@ -2,20 +2,29 @@ package handlers
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"helpdesk-api/models"
+	"helpdesk-api/standauth"
 	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// OutboxKindStandNotification — тип сообщения outbox с уведомлением стенда о решении по заявке
+const OutboxKindStandNotification = "stand.notification"
+
 type StandConfig struct {
-	Stands map[string]string
+	mu      sync.RWMutex
+	Stands  map[string]string
+	secrets map[string]string // Секреты стендов для подписи запросов
 }
 
 var standEndpoints StandConfig
@@ -27,21 +36,33 @@ func LoadEndpoints(db *gorm.DB) {
 		log.Fatalf("Failed to load endpoints from database: %v", err)
 	}
 
-	standEndpoints.Stands = make(map[string]string)
+	stands := make(map[string]string)
+	secrets := make(map[string]string)
 	for _, endpoint := range endpoints {
-		standEndpoints.Stands[endpoint.Name] = endpoint.URL
+		stands[endpoint.Name] = endpoint.URL
+		secrets[endpoint.Name] = endpoint.Secret
 	}
-	log.Println("Loaded stand endpoints:", standEndpoints)
+	// Карту читает и диспетчер outbox, поэтому подменяем её целиком под блокировкой
+	standEndpoints.mu.Lock()
+	standEndpoints.Stands = stands
+	standEndpoints.secrets = secrets
+	standEndpoints.mu.Unlock()
+	log.Println("Loaded stand endpoints:", stands)
 }
 
-// Пример использования в обработчике
-func SomeHandler(db *gorm.DB) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Доступ к endpoints
-		for name, url := range standEndpoints.Stands {
-			c.JSON(200, gin.H{"name": name, "url": url})
-		}
-	}
+// standEndpoint возвращает URL стенда по имени
+func standEndpoint(stand string) (string, bool) {
+	standEndpoints.mu.RLock()
+	defer standEndpoints.mu.RUnlock()
+	url, ok := standEndpoints.Stands[stand]
+	return url, ok
+}
+
+// standSecret возвращает секрет стенда для подписи запросов
+func standSecret(stand string) string {
+	standEndpoints.mu.RLock()
+	defer standEndpoints.mu.RUnlock()
+	return standEndpoints.secrets[stand]
 }
 
 type WhitelistRequestInput struct {
@@ -59,17 +80,19 @@ type WhitelistRequestInput struct {
 }
 
 type WhitelistEditInput struct {
-	Permission string `json:"permission" binding:"required,oneof=approve deny"` // "approve" или "deny"
+	Permission string     `json:"permission" binding:"required,oneof=approve deny revoke"` // "approve", "deny" или "revoke"
+	Comment    string     `json:"comment" example:"Согласовано с руководителем"`           // Причина решения, попадает в историю
+	ExpiresAt  *time.Time `json:"expires_at" example:"2026-12-31T23:59:59Z"`               // Срок действия одобрения; после него доступ отзывается автоматически
 }
 
 // AddWhitelistRequest godoc
 // @Summary Создать новую заявку в whitelist
-// @Description Добавляет новую заявку в whitelist со статусом "pending". Если заявка уже существует (по telegram_id и from), возвращает 200 OK.
+// @Description Добавляет новую заявку в whitelist со статусом "pending" и проверяет её по правилам автоматического решения: если сработало правило, заявка сразу одобряется или отклоняется, итог — в поле permission. Если заявка уже существует (по telegram_id и from), возвращает 200 OK.
 // @Tags whitelist
 // @Accept json
 // @Produce json
 // @Param request body WhitelistRequestInput true "Данные заявки"
-// @Success 201 {object} map[string]interface{} "message: Запрос создан, id: <id>"
+// @Success 201 {object} map[string]interface{} "message: Запрос создан, id: <id>, permission: <итоговый статус>"
 // @Success 200 {object} map[string]string "message: Запрос уже существует"
 // @Failure 400 {object} map[string]string "error"
 // @Failure 500 {object} map[string]string "error"
@@ -91,7 +114,7 @@ func AddWhitelistRequest(c *gin.Context, db *gorm.DB) {
 		LastName:     input.User.LastName,
 		Username:     input.User.Username,
 		LanguageCode: input.User.LanguageCode,
-		Permission:   "pending", // Дефолтное значение
+		Permission:   models.WhitelistPending, // Дефолтное значение
 	}
 
 	// Попытка создать запись
@@ -106,28 +129,61 @@ func AddWhitelistRequest(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
+	applyWhitelistRules(db, &whitelist)
+
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Запрос создан",
-		"id":      whitelist.ID,
+		"message":    "Запрос создан",
+		"id":         whitelist.ID,
+		"permission": whitelist.Permission,
 	})
 }
 
 // EditWhitelist godoc
 // @Summary Изменить статус заявки в whitelist
-// @Description Обновляет статус заявки в whitelist ("approve" или "deny") и уведомляет стенд при одобрении
+// @Description Обновляет статус заявки в whitelist по её ID: "approve" (при желании со сроком expires_at), "deny" или "revoke" для ранее одобренной заявки. Решение с комментарием записывается в историю, уведомление стенда с текстом из шаблона сообщений ставится в outbox и доставляется в фоне с повторами
 // @Tags whitelist
 // @Accept json
 // @Produce json
-// @Param telegram_id path string true "Telegram ID пользователя"
+// @Param id path int true "ID заявки whitelist"
 // @Param request body WhitelistEditInput true "Новое значение permission"
 // @Success 200 {object} map[string]string "message: OK"
 // @Failure 400 {object} map[string]string "error"
 // @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
 // @Failure 500 {object} map[string]string "error"
 // @Security BearerAuth
-// @Router /operator/whitelist/{telegram_id}/edit [post]
+// @Router /operator/whitelist/{id}/edit [post]
 func EditWhitelist(c *gin.Context, db *gorm.DB) {
-	telegramID := c.Param("telegram_id")
+	var input WhitelistEditInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	whitelist, ok := findWhitelistByParam(c, db)
+	if !ok {
+		return
+	}
+	decideWhitelist(c, db, whitelist, input)
+}
+
+// EditUserWhitelist godoc
+// @Summary Изменить статус заявки пользователя на стенде
+// @Description То же, что /operator/whitelist/{id}/edit, но заявка выбирается по паре telegram_id и стенд. У пользователя может быть по заявке на каждый стенд, решение затрагивает только указанный
+// @Tags whitelist
+// @Accept json
+// @Produce json
+// @Param telegram_id path string true "Telegram ID пользователя"
+// @Param stand path string true "Стенд" Enums(dev, ift, psi, prom)
+// @Param request body WhitelistEditInput true "Новое значение permission"
+// @Success 200 {object} map[string]string "message: OK"
+// @Failure 400 {object} map[string]string "error"
+// @Failure 404 {object} map[string]string "error"
+// @Failure 409 {object} map[string]string "error"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /operator/whitelist/user/{telegram_id}/{stand}/edit [post]
+func EditUserWhitelist(c *gin.Context, db *gorm.DB) {
 	var input WhitelistEditInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -135,57 +191,89 @@ func EditWhitelist(c *gin.Context, db *gorm.DB) {
 	}
 
 	var whitelist models.Whitelist
-	if err := db.Where("telegram_id = ?", telegramID).First(&whitelist).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Пользователь не найден в whitelist"})
+	err := db.Where("telegram_id = ? AND \"from\" = ?", c.Param("telegram_id"), c.Param("stand")).First(&whitelist).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Заявка пользователя на этот стенд не найдена"})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist: " + err.Error()})
 		}
 		return
 	}
+	decideWhitelist(c, db, &whitelist, input)
+}
 
-	// Обновляем статус
-	whitelist.Permission = input.Permission
-	if err := db.Save(&whitelist).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить доступ: " + err.Error()})
+// GetUserWhitelist godoc
+// @Summary Заявки пользователя на все стенды
+// @Description Возвращает все заявки whitelist пользователя с указанным telegram_id, по одной на стенд
+// @Tags whitelist
+// @Produce json
+// @Param telegram_id path string true "Telegram ID пользователя"
+// @Success 200 {array} models.Whitelist
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /operator/whitelist/user/{telegram_id} [get]
+func GetUserWhitelist(c *gin.Context, db *gorm.DB) {
+	entries := []models.Whitelist{}
+	if err := db.Where("telegram_id = ?", c.Param("telegram_id")).Order("\"from\" asc").Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить заявки пользователя: " + err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, entries)
+}
 
-	// Если статус "approve", уведомляем стенд
-	if input.Permission == "approve" {
-		standEndpoint, exists := standEndpoints.Stands[whitelist.From]
-		if !exists {
-			log.Printf("No endpoint found for stand: %s", whitelist.From)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Неизвестный стенд: %s", whitelist.From)})
-			return
-		}
-
-		// Формируем новый payload согласно требованиям
-		payload := map[string]interface{}{
-			"chatId":  whitelist.ChatID,
-			"message": "Тестовое сообщение2",
-		}
-		payloadBytes, err := json.Marshal(payload)
-		if err != nil {
-			log.Printf("Failed to marshal payload for stand %s: %v", whitelist.From, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare request to stand"})
-			return
-		}
+// findWhitelistByParam ищет заявку по ID из параметра маршрута :id. При ошибке сам отправляет ответ
+func findWhitelistByParam(c *gin.Context, db *gorm.DB) (*models.Whitelist, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Заявка не найдена"})
+		return nil, false
+	}
 
-		// Отправляем запрос с тайм-аутом
-		client := &http.Client{Timeout: 10 * time.Second}
-		resp, err := client.Post(standEndpoint, "application/json", bytes.NewBuffer(payloadBytes))
-		if err != nil {
-			log.Printf("Failed to notify stand %s at %s: %v", whitelist.From, standEndpoint, err)
-			// Не прерываем выполнение, просто логируем ошибку
-		} else if resp.StatusCode != http.StatusOK {
-			log.Printf("Stand %s at %s returned status %d", whitelist.From, standEndpoint, resp.StatusCode)
+	var whitelist models.Whitelist
+	if err := db.First(&whitelist, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Заявка не найдена"})
 		} else {
-			log.Printf("Successfully notified stand %s at %s", whitelist.From, standEndpoint)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist: " + err.Error()})
 		}
-		if resp != nil {
-			resp.Body.Close()
+		return nil, false
+	}
+	return &whitelist, true
+}
+
+// decideWhitelist применяет решение оператора к найденной заявке и отправляет ответ
+func decideWhitelist(c *gin.Context, db *gorm.DB, whitelist *models.Whitelist, input WhitelistEditInput) {
+	if _, exists := standEndpoint(whitelist.From); !exists {
+		log.Printf("No endpoint found for stand: %s", whitelist.From)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Неизвестный стенд: %s", whitelist.From)})
+		return
+	}
+
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+
+	// Статус, запись в историю и уведомление стенда сохраняются в одной транзакции: уведомление
+	// доставит диспетчер outbox с повторами, и решение не потеряется, если стенд недоступен
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Перечитываем заявку под блокировкой, чтобы параллельные решения не затёрли друг друга в истории
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(whitelist, whitelist.ID).Error; err != nil {
+			return err
 		}
+		return applyWhitelistDecision(tx, whitelist, whitelistDecision{
+			Permission: input.Permission,
+			Operator:   operator,
+			Comment:    input.Comment,
+			ExpiresAt:  input.ExpiresAt,
+		})
+	})
+	if err != nil {
+		respondWhitelistDecisionError(c, err)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "OK"})
@@ -193,36 +281,102 @@ func EditWhitelist(c *gin.Context, db *gorm.DB) {
 
 // GetWhitelistPending godoc
 // @Summary Получить список ожидающих заявок whitelist
-// @Description Возвращает все записи whitelist со статусом "pending"
+// @Description Возвращает страницу заявок whitelist со статусом "pending", новые сверху. Фильтры и поиск — как у /operator/whitelist/all, параметр permission игнорируется
 // @Tags whitelist
 // @Produce json
-// @Success 200 {array} models.Whitelist
+// @Param stand query string false "Стенды через запятую, например ift,prom"
+// @Param created_from query string false "Создана не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Создана не позже (RFC3339 или YYYY-MM-DD)"
+// @Param q query string false "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} whitelistListResponse
+// @Failure 400 {object} map[string]string "error"
 // @Failure 500 {object} map[string]string "error"
 // @Security BearerAuth
 // @Router /operator/whitelist [get]
 func GetWhitelistPending(c *gin.Context, db *gorm.DB) {
-	var pendingUsers []models.Whitelist
-	if err := db.Where("permission = ?", "pending").Find(&pendingUsers).Error; err != nil {
+	query, err := parseWhitelistListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Permissions = []string{models.WhitelistPending}
+
+	response, err := query.fetch(db)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список ожидания: " + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, pendingUsers)
+	c.JSON(http.StatusOK, response)
 }
 
 // GetWhitelistAll godoc
 // @Summary Получить все записи whitelist
-// @Description Возвращает все записи whitelist независимо от статуса
+// @Description Возвращает страницу заявок whitelist, новые сверху, с фильтрами по стенду, статусу и дате создания и поиском по тексту
 // @Tags whitelist
 // @Produce json
-// @Success 200 {array} models.Whitelist
+// @Param stand query string false "Стенды через запятую, например ift,prom"
+// @Param permission query string false "Статусы через запятую, например approve,revoke"
+// @Param created_from query string false "Создана не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Создана не позже (RFC3339 или YYYY-MM-DD)"
+// @Param q query string false "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 200)"
+// @Param offset query int false "Смещение"
+// @Success 200 {object} whitelistListResponse
+// @Failure 400 {object} map[string]string "error"
 // @Failure 500 {object} map[string]string "error"
 // @Security BearerAuth
 // @Router /operator/whitelist/all [get]
 func GetWhitelistAll(c *gin.Context, db *gorm.DB) {
-	var allUsers []models.Whitelist
-	if err := db.Find(&allUsers).Error; err != nil {
+	query, err := parseWhitelistListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := query.fetch(db)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить список whitelist: " + err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, allUsers)
+	c.JSON(http.StatusOK, response)
+}
+
+// DeliverStandNotification — обработчик outbox: отправляет уведомление на URL стенда,
+// подписав его секретом стенда (см. пакет standauth). URL и секрет берутся в момент доставки,
+// поэтому после исправления настроек стенда достаточно переотправить сообщение
+func DeliverStandNotification(ctx context.Context, msg models.OutboxMessage) error {
+	// ID доставки одинаков у всех попыток одного сообщения, чтобы стенд мог отбросить дубликат
+	return postToStand(ctx, msg.Stand, fmt.Sprintf("outbox-%d", msg.ID), []byte(msg.Payload))
+}
+
+// postToStand отправляет подписанный JSON на URL стенда
+func postToStand(ctx context.Context, stand, deliveryID string, body []byte) error {
+	url, ok := standEndpoint(stand)
+	if !ok {
+		return fmt.Errorf("unknown stand: %s", stand)
+	}
+	secret := standSecret(stand)
+	if secret == "" {
+		return fmt.Errorf("stand %s has no signing secret", stand)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	standauth.SignRequest(req, secret, deliveryID, body)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stand %s returned status %d", stand, resp.StatusCode)
+	}
+	log.Printf("Successfully notified stand %s at %s", stand, url)
+	return nil
 }
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxBulkWhitelistItems — сколько заявок можно обработать одним запросом
+const maxBulkWhitelistItems = 1000
+
+// Статусы обработки заявки в массовом решении
+const (
+	bulkItemUpdated     = "updated"
+	bulkItemWouldUpdate = "would_update"
+	bulkItemSkipped     = "skipped"
+	bulkItemFailed      = "failed"
+)
+
+// WhitelistBulkFilter — отбор заявок для массового решения. Пустые поля не ограничивают выборку
+type WhitelistBulkFilter struct {
+	Stand         string     `json:"stand" example:"ift"`
+	LanguageCode  string     `json:"language_code" example:"ru"`
+	CreatedBefore *time.Time `json:"created_before" example:"2026-10-01T00:00:00Z"`
+	Permission    string     `json:"permission" binding:"omitempty,oneof=pending approve deny revoke" example:"pending"` // Текущий статус заявок, по умолчанию pending
+}
+
+// WhitelistBulkInput — массовое решение по списку ID или по фильтру (ровно одно из двух)
+type WhitelistBulkInput struct {
+	IDs        []uint               `json:"ids"`
+	Filter     *WhitelistBulkFilter `json:"filter"`
+	Permission string               `json:"permission" binding:"required,oneof=approve deny revoke"`
+	Comment    string               `json:"comment"`
+	ExpiresAt  *time.Time           `json:"expires_at"`
+	DryRun     bool                 `json:"dry_run"` // Только показать, что изменится
+}
+
+// whitelistBulkItem — результат по одной заявке
+type whitelistBulkItem struct {
+	ID            uint   `json:"id"`
+	TelegramID    string `json:"telegram_id,omitempty"`
+	Stand         string `json:"stand,omitempty"`
+	OldPermission string `json:"old_permission,omitempty"`
+	NewPermission string `json:"new_permission,omitempty"`
+	Status        string `json:"status"` // updated, would_update, skipped или failed
+	Error         string `json:"error,omitempty"`
+}
+
+// whitelistBulkResponse — сводка массового решения
+type whitelistBulkResponse struct {
+	DryRun  bool                `json:"dry_run"`
+	Matched int                 `json:"matched"`
+	Updated int                 `json:"updated"` // При dry_run — сколько заявок было бы изменено
+	Skipped int                 `json:"skipped"`
+	Failed  int                 `json:"failed"`
+	Results []whitelistBulkItem `json:"results"`
+}
+
+// BulkEditWhitelist godoc
+// @Summary Массовое решение по заявкам whitelist
+// @Description Одобряет, отклоняет или отзывает доступ по списку ID заявок либо по всем заявкам, подходящим под фильтр (стенд, language_code, создана до). Каждая заявка обрабатывается в своей транзакции, в ответе — результат по каждой. Уведомления стендов ставятся в outbox и доставляются в фоне. С dry_run=true ничего не меняется, а в ответе видно, что было бы изменено
+// @Tags whitelist
+// @Accept json
+// @Produce json
+// @Param request body WhitelistBulkInput true "Решение и отбор заявок"
+// @Success 200 {object} whitelistBulkResponse
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "error"
+// @Security BearerAuth
+// @Router /operator/whitelist/bulk [post]
+func BulkEditWhitelist(c *gin.Context, db *gorm.DB) {
+	var input WhitelistBulkInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (len(input.IDs) == 0) == (input.Filter == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите либо ids, либо filter"})
+		return
+	}
+	if len(input.IDs) > maxBulkWhitelistItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Не больше %d заявок за запрос", maxBulkWhitelistItems)})
+		return
+	}
+
+	decision := whitelistDecision{
+		Permission: input.Permission,
+		Comment:    input.Comment,
+		ExpiresAt:  input.ExpiresAt,
+	}
+	// Ошибки, не зависящие от конкретной заявки, сообщаем сразу, а не в каждой строке результата
+	if errors.Is(checkWhitelistDecision(models.WhitelistPending, decision), errExpiryInPast) {
+		respondWhitelistDecisionError(c, errExpiryInPast)
+		return
+	}
+	if input.ExpiresAt != nil && input.Permission != models.WhitelistApprove {
+		respondWhitelistDecisionError(c, errExpiryRequiresGrant)
+		return
+	}
+
+	var entries []models.Whitelist
+	query := db.Order("id asc")
+	if input.Filter != nil {
+		query = bulkWhitelistQuery(query, *input.Filter).Limit(maxBulkWhitelistItems + 1)
+	} else {
+		query = query.Where("id IN ?", input.IDs)
+	}
+	if err := query.Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка проверки whitelist: " + err.Error()})
+		return
+	}
+	if len(entries) > maxBulkWhitelistItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Под фильтр подходит больше %d заявок, сузьте его", maxBulkWhitelistItems)})
+		return
+	}
+
+	operator, ok := currentOperator(c, db)
+	if !ok {
+		return
+	}
+	decision.Operator = operator
+
+	response := whitelistBulkResponse{DryRun: input.DryRun, Results: []whitelistBulkItem{}}
+	found := make(map[uint]bool, len(entries))
+	for i := range entries {
+		found[entries[i].ID] = true
+		item := bulkDecideWhitelist(db, &entries[i], decision, input.DryRun)
+		switch item.Status {
+		case bulkItemUpdated, bulkItemWouldUpdate:
+			response.Updated++
+		case bulkItemSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+		response.Results = append(response.Results, item)
+	}
+	response.Matched = len(entries)
+	for _, id := range input.IDs {
+		if !found[id] {
+			found[id] = true
+			response.Failed++
+			response.Results = append(response.Results, whitelistBulkItem{ID: id, Status: bulkItemFailed, Error: "Заявка не найдена"})
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// bulkWhitelistQuery добавляет к запросу условия фильтра
+func bulkWhitelistQuery(query *gorm.DB, filter WhitelistBulkFilter) *gorm.DB {
+	permission := filter.Permission
+	if permission == "" {
+		permission = models.WhitelistPending
+	}
+	query = query.Where("permission = ?", permission)
+	if filter.Stand != "" {
+		query = query.Where("\"from\" = ?", filter.Stand)
+	}
+	if filter.LanguageCode != "" {
+		query = query.Where("language_code = ?", filter.LanguageCode)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	return query
+}
+
+// bulkDecideWhitelist применяет решение к одной заявке в отдельной транзакции, чтобы ошибка
+// в одной заявке не откатывала остальные
+func bulkDecideWhitelist(db *gorm.DB, whitelist *models.Whitelist, decision whitelistDecision, dryRun bool) whitelistBulkItem {
+	item := whitelistBulkItem{
+		ID:            whitelist.ID,
+		TelegramID:    whitelist.TelegramID,
+		Stand:         whitelist.From,
+		OldPermission: whitelist.Permission,
+		NewPermission: decision.Permission,
+	}
+	if _, exists := standEndpoint(whitelist.From); !exists {
+		item.Status = bulkItemFailed
+		item.Error = fmt.Sprintf("Неизвестный стенд: %s", whitelist.From)
+		return item
+	}
+
+	if dryRun {
+		item.Status = bulkItemWouldUpdate
+		if unchangedByDecision(*whitelist, decision) {
+			item.Status = bulkItemSkipped
+		} else if err := checkWhitelistDecision(whitelist.Permission, decision); err != nil {
+			item.Status = bulkItemFailed
+			item.Error = err.Error()
+		}
+		return item
+	}
+
+	item.Status = bulkItemUpdated
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(whitelist, whitelist.ID).Error; err != nil {
+			return err
+		}
+		item.OldPermission = whitelist.Permission
+		// Повторное решение с тем же значением ничего не меняет и не шлёт стенду второе уведомление
+		if unchangedByDecision(*whitelist, decision) {
+			item.Status = bulkItemSkipped
+			return nil
+		}
+		return applyWhitelistDecision(tx, whitelist, decision)
+	})
+	if err != nil {
+		item.Status = bulkItemFailed
+		item.Error = err.Error()
+	}
+	return item
+}
+
+// unchangedByDecision сообщает, что заявка уже в том состоянии, к которому привело бы решение
+func unchangedByDecision(whitelist models.Whitelist, decision whitelistDecision) bool {
+	if whitelist.Permission != decision.Permission {
+		return false
+	}
+	if whitelist.ExpiresAt == nil || decision.ExpiresAt == nil {
+		return whitelist.ExpiresAt == nil && decision.ExpiresAt == nil
+	}
+	return whitelist.ExpiresAt.Equal(*decision.ExpiresAt)
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+	"helpdesk-api/outbox"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// systemActor — имя, под которым в журнал пишутся автоматические решения
+const systemActor = "system"
+
+var (
+	errDecisionNotAllowed  = errors.New("decision not allowed")
+	errExpiryInPast        = errors.New("expires_at must be in the future")
+	errExpiryRequiresGrant = errors.New("expires_at can only be set when approving")
+)
+
+// whitelistDecision — решение по заявке whitelist
+type whitelistDecision struct {
+	Permission string                // approve, deny или revoke
+	Operator   *models.Operator      // nil для автоматических решений
+	Rule       *models.WhitelistRule // Правило, принявшее решение автоматически
+	Comment    string
+	ExpiresAt  *time.Time // Только для approve
+}
+
+// checkWhitelistDecision проверяет, допустимо ли решение для заявки с текущим значением current
+func checkWhitelistDecision(current string, decision whitelistDecision) error {
+	switch decision.Permission {
+	case models.WhitelistApprove:
+		if decision.ExpiresAt != nil && !decision.ExpiresAt.After(time.Now()) {
+			return errExpiryInPast
+		}
+	case models.WhitelistDeny:
+		if current == models.WhitelistApprove {
+			return fmt.Errorf("%w: approved access must be revoked, not denied", errDecisionNotAllowed)
+		}
+	case models.WhitelistRevoke:
+		if current != models.WhitelistApprove {
+			return fmt.Errorf("%w: only approved access can be revoked", errDecisionNotAllowed)
+		}
+	default:
+		return fmt.Errorf("%w: %s", errDecisionNotAllowed, decision.Permission)
+	}
+	if decision.ExpiresAt != nil && decision.Permission != models.WhitelistApprove {
+		return errExpiryRequiresGrant
+	}
+	return nil
+}
+
+// applyWhitelistDecision — единственное место, где меняется доступ по заявке: проверяет переход,
+// сохраняет новое значение, пишет журнал решений и ставит уведомление стенда в outbox.
+// Вызывается внутри транзакции tx
+func applyWhitelistDecision(tx *gorm.DB, whitelist *models.Whitelist, decision whitelistDecision) error {
+	if err := checkWhitelistDecision(whitelist.Permission, decision); err != nil {
+		return err
+	}
+
+	record := models.WhitelistDecision{
+		WhitelistID:      whitelist.ID,
+		OperatorUsername: systemActor,
+		OldPermission:    whitelist.Permission,
+		NewPermission:    decision.Permission,
+		Comment:          decision.Comment,
+		ExpiresAt:        decision.ExpiresAt,
+	}
+	if decision.Operator != nil {
+		record.OperatorID = &decision.Operator.ID
+		record.OperatorUsername = decision.Operator.Username
+	}
+	if decision.Rule != nil {
+		record.RuleID = &decision.Rule.ID
+	}
+
+	whitelist.Permission = decision.Permission
+	whitelist.ExpiresAt = decision.ExpiresAt
+	err := tx.Model(whitelist).Updates(map[string]interface{}{
+		"permission": whitelist.Permission,
+		"expires_at": whitelist.ExpiresAt,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := tx.Create(&record).Error; err != nil {
+		return err
+	}
+	// Отклонение и отзыв закрывают и уже выданные пользователю токены этого стенда
+	if decision.Permission == models.WhitelistDeny || decision.Permission == models.WhitelistRevoke {
+		if err := auth.RevokeSubjectTx(tx, auth.UserSubject(whitelist.TelegramID, whitelist.From)); err != nil {
+			return err
+		}
+	}
+
+	message, _, err := renderWhitelistMessage(tx, *whitelist, decision.Permission)
+	if err != nil {
+		return err
+	}
+	payload := map[string]interface{}{
+		"chatId":   whitelist.ChatID,
+		"message":  message,
+		"decision": decision.Permission,
+	}
+	return outbox.Enqueue(tx, OutboxKindStandNotification, whitelist.From, payload)
+}
+
+// respondWhitelistDecisionError переводит ошибку applyWhitelistDecision в HTTP-ответ
+func respondWhitelistDecisionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errDecisionNotAllowed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, errExpiryInPast), errors.Is(err, errExpiryRequiresGrant):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось обновить доступ: " + err.Error()})
+	}
+}
+
+// GetWhitelistHistory godoc
+// @Summary История решений по заявке whitelist
+// @Description Возвращает журнал решений по заявке: кто и когда одобрил, отклонил или отозвал доступ, с комментарием. Решения, принятые автоматически по истечении срока, записаны от имени "system"
+// @Tags whitelist
+// @Produce json
+// @Param id path int true "ID заявки whitelist"
+// @Success 200 {array} models.WhitelistDecision
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/whitelist/{id}/history [get]
+func GetWhitelistHistory(c *gin.Context, db *gorm.DB) {
+	whitelist, ok := findWhitelistByParam(c, db)
+	if !ok {
+		return
+	}
+
+	decisions := []models.WhitelistDecision{}
+	if err := db.Where("whitelist_id = ?", whitelist.ID).Order("created_at asc, id asc").Find(&decisions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Не удалось получить историю: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, decisions)
+}
+
+// StartMaintenance запускает фоновые работы: отзыв одобрений с истёкшим сроком
+// и очистку журнала обработанных обновлений Telegram
+func StartMaintenance(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := expireWhitelist(db); err != nil {
+				log.Printf("Failed to expire whitelist approvals: %v", err)
+			} else if n > 0 {
+				log.Printf("Expired %d whitelist approvals", n)
+			}
+			if err := PruneTelegramUpdates(db, telegramUpdateRetention); err != nil {
+				log.Printf("Failed to prune processed telegram updates: %v", err)
+			}
+		}
+	}()
+}
+
+// expireWhitelist отзывает одобрения, срок которых истёк, по одному в транзакции.
+// SKIP LOCKED не даёт двум экземплярам сервиса отозвать одну заявку дважды
+func expireWhitelist(db *gorm.DB) (int, error) {
+	expired := 0
+	for {
+		found := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var whitelist models.Whitelist
+			result := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("permission = ? AND expires_at <= ?", models.WhitelistApprove, time.Now()).
+				Order("expires_at asc").
+				Limit(1).
+				Find(&whitelist)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			found = true
+			return applyWhitelistDecision(tx, &whitelist, whitelistDecision{
+				Permission: models.WhitelistRevoke,
+				Comment:    "Срок действия доступа истёк",
+			})
+		})
+		if err != nil {
+			return expired, err
+		}
+		if !found {
+			return expired, nil
+		}
+		expired++
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/models"
+	"helpdesk-api/xlsx"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// whitelistExportBatchSize — сколько заявок выгрузка читает из базы за один запрос
+const whitelistExportBatchSize = 500
+
+// whitelistExportColumns — заголовок выгрузки whitelist
+var whitelistExportColumns = []string{
+	"id", "telegram_id", "stand", "username", "first_name", "last_name", "language_code", "text",
+	"permission", "created_at", "updated_at", "expires_at", "decided_by", "decided_at", "decision_comment",
+}
+
+// rowWriter — общий интерфейс CSV- и XLSX-выгрузки
+type rowWriter interface {
+	WriteRow(values []string) error
+	Close() error
+}
+
+// csvRowWriter пишет строки CSV. Значения, которые табличный редактор принял бы за формулу,
+// экранируются апострофом
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (w csvRowWriter) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+			value = "'" + value
+		}
+		escaped[i] = value
+	}
+	return w.w.Write(escaped)
+}
+
+func (w csvRowWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// ExportWhitelist godoc
+// @Summary Выгрузка whitelist в CSV или XLSX
+// @Description Выгружает все заявки whitelist, подходящие под фильтры (те же, что у /operator/whitelist/all, без пагинации), вместе с последним решением по каждой: кто и когда его принял и с каким комментарием
+// @Tags whitelist
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "Формат файла (по умолчанию csv)" Enums(csv, xlsx)
+// @Param stand query string false "Стенды через запятую, например ift,prom"
+// @Param permission query string false "Статусы через запятую, например approve,revoke"
+// @Param created_from query string false "Создана не раньше (RFC3339 или YYYY-MM-DD)"
+// @Param created_to query string false "Создана не позже (RFC3339 или YYYY-MM-DD)"
+// @Param q query string false "Поиск по username, имени, фамилии и тексту заявки; точное совпадение по telegram_id"
+// @Success 200 {file} file
+// @Failure 400 {object} map[string]string "error"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Security BearerAuth
+// @Router /operator/whitelist/export [get]
+func ExportWhitelist(c *gin.Context, db *gorm.DB) {
+	query, err := parseWhitelistListQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	filename := "whitelist-" + time.Now().Format("20060102-150405") + "." + format
+	var writer rowWriter
+	switch format {
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		// BOM, чтобы Excel открыл файл в UTF-8
+		c.Writer.WriteString("\uFEFF")
+		writer = csvRowWriter{w: csv.NewWriter(c.Writer)}
+	case "xlsx":
+		c.Header("Content-Type", xlsx.ContentType)
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		if writer, err = xlsx.NewWriter(c.Writer, "Whitelist"); err != nil {
+			log.Printf("Failed to start whitelist export: %v", err)
+			return
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be 'csv' or 'xlsx'"})
+		return
+	}
+
+	// Ответ уже начат, поэтому ошибки дальше можно только записать в лог
+	if err := writeWhitelistExport(db, query, writer); err != nil {
+		log.Printf("Whitelist export by %s failed: %v", c.GetString("username"), err)
+		return
+	}
+	if err := writer.Close(); err != nil {
+		log.Printf("Whitelist export by %s failed: %v", c.GetString("username"), err)
+	}
+}
+
+// writeWhitelistExport пишет заголовок и заявки порциями по возрастанию ID
+func writeWhitelistExport(db *gorm.DB, query *whitelistListQuery, writer rowWriter) error {
+	if err := writer.WriteRow(whitelistExportColumns); err != nil {
+		return err
+	}
+
+	var lastID uint
+	for {
+		var batch []models.Whitelist
+		err := query.applyFilters(db.Model(&models.Whitelist{})).
+			Where("id > ?", lastID).
+			Order("id asc").
+			Limit(whitelistExportBatchSize).
+			Find(&batch).Error
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		decisions, err := latestWhitelistDecisions(db, batch)
+		if err != nil {
+			return err
+		}
+		for _, entry := range batch {
+			if err := writer.WriteRow(whitelistExportRow(entry, decisions[entry.ID])); err != nil {
+				return err
+			}
+		}
+		lastID = batch[len(batch)-1].ID
+	}
+}
+
+// latestWhitelistDecisions возвращает последнее решение по каждой заявке из entries
+func latestWhitelistDecisions(db *gorm.DB, entries []models.Whitelist) (map[uint]models.WhitelistDecision, error) {
+	ids := make([]uint, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+
+	var decisions []models.WhitelistDecision
+	err := db.Raw(`SELECT DISTINCT ON (whitelist_id) * FROM whitelist_decisions
+		WHERE whitelist_id IN ? ORDER BY whitelist_id, created_at DESC, id DESC`, ids).
+		Scan(&decisions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byEntry := make(map[uint]models.WhitelistDecision, len(decisions))
+	for _, decision := range decisions {
+		byEntry[decision.WhitelistID] = decision
+	}
+	return byEntry, nil
+}
+
+func whitelistExportRow(entry models.Whitelist, decision models.WhitelistDecision) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	row := []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.TelegramID,
+		entry.From,
+		entry.Username,
+		entry.FirstName,
+		entry.LastName,
+		entry.LanguageCode,
+		entry.Text,
+		entry.Permission,
+		formatTime(&entry.CreatedAt),
+		formatTime(&entry.UpdatedAt),
+		formatTime(entry.ExpiresAt),
+		"", "", "",
+	}
+	if decision.ID != 0 {
+		row[12] = decision.OperatorUsername
+		if decision.RuleID != nil {
+			row[12] = fmt.Sprintf("%s (rule #%d)", decision.OperatorUsername, *decision.RuleID)
+		}
+		row[13] = formatTime(&decision.CreatedAt)
+		row[14] = decision.Comment
+	}
+	return row
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	"helpdesk-api/models"
+)
+
+func TestCSVRowWriterEscapesFormulas(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"ivanov", "ivanov"},
+		{"", ""},
+		{"=1+1", "'=1+1"},
+		{"=HYPERLINK(\"http://evil\",\"click\")", "'=HYPERLINK(\"http://evil\",\"click\")"},
+		{"+79001234567", "'+79001234567"},
+		{"-1", "'-1"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+		{"\r=1", "'\r=1"},
+		{"a=1", "a=1"},
+		{" =1", " =1"},
+		{"Прошу доступ", "Прошу доступ"},
+	} {
+		var buf bytes.Buffer
+		w := csvRowWriter{w: csv.NewWriter(&buf)}
+		if err := w.WriteRow([]string{tc.in}); err != nil {
+			t.Fatalf("WriteRow(%q): %v", tc.in, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		records, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("read back %q: %v", buf.String(), err)
+		}
+		// Пустая строка CSV читается как отсутствие записей
+		got := ""
+		if len(records) > 0 {
+			got = records[0][0]
+		}
+		if got != tc.want {
+			t.Errorf("%q written as %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWhitelistExportRow(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	entry := models.Whitelist{
+		ID:         7,
+		TelegramID: "123456",
+		From:       "ift",
+		Username:   "ivanov",
+		Text:       "Прошу доступ",
+		Permission: models.WhitelistApprove,
+		CreatedAt:  created,
+		UpdatedAt:  created,
+	}
+	ruleID := uint(3)
+
+	for _, tc := range []struct {
+		name     string
+		decision models.WhitelistDecision
+		want     []string
+	}{
+		{"no decision", models.WhitelistDecision{}, []string{"", "", ""}},
+		{
+			"operator decision",
+			models.WhitelistDecision{ID: 1, OperatorUsername: "petrov", Comment: "ок", CreatedAt: created},
+			[]string{"petrov", "2024-03-01T06:00:00Z", "ок"},
+		},
+		{
+			"rule decision",
+			models.WhitelistDecision{ID: 2, OperatorUsername: "admin", RuleID: &ruleID, CreatedAt: created},
+			[]string{"admin (rule #3)", "2024-03-01T06:00:00Z", ""},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			row := whitelistExportRow(entry, tc.decision)
+			if len(row) != len(whitelistExportColumns) {
+				t.Fatalf("row has %d values, header has %d", len(row), len(whitelistExportColumns))
+			}
+			want := append([]string{"7", "123456", "ift", "ivanov", "", "", "", "Прошу доступ", models.WhitelistApprove,
+				"2024-03-01T06:00:00Z", "2024-03-01T06:00:00Z", ""}, tc.want...)
+			if !reflect.DeepEqual(row, want) {
+				t.Fatalf("row = %q, want %q", row, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// whitelistListResponse — конверт ответа со страницей заявок whitelist
+type whitelistListResponse struct {
+	Items []models.Whitelist `json:"items"`
+	Total int64              `json:"total"` // Общее число заявок, подходящих под фильтры
+}
+
+// whitelistListQuery — разобранные параметры выборки заявок whitelist
+type whitelistListQuery struct {
+	Stands      []string
+	Permissions []string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Search      string
+	Limit       int
+	Offset      int
+}
+
+// likeEscaper экранирует спецсимволы LIKE в поисковой строке
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// parseWhitelistListQuery разбирает query-параметры списка и выгрузки whitelist
+func parseWhitelistListQuery(c *gin.Context) (*whitelistListQuery, error) {
+	q := &whitelistListQuery{
+		Search: strings.TrimSpace(c.Query("q")),
+		Limit:  defaultTicketPageSize,
+	}
+
+	if stands := c.Query("stand"); stands != "" {
+		for _, stand := range strings.Split(stands, ",") {
+			if stand = strings.TrimSpace(stand); stand != "" {
+				q.Stands = append(q.Stands, stand)
+			}
+		}
+	}
+
+	if permissions := c.Query("permission"); permissions != "" {
+		for _, permission := range strings.Split(permissions, ",") {
+			permission = strings.TrimSpace(permission)
+			switch permission {
+			case models.WhitelistPending, models.WhitelistApprove, models.WhitelistDeny, models.WhitelistRevoke:
+			default:
+				return nil, fmt.Errorf("unknown permission: %s", permission)
+			}
+			q.Permissions = append(q.Permissions, permission)
+		}
+	}
+
+	var err error
+	if q.CreatedFrom, err = parseTimeParam(c, "created_from", false); err != nil {
+		return nil, err
+	}
+	if q.CreatedTo, err = parseTimeParam(c, "created_to", true); err != nil {
+		return nil, err
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid limit: %s", limit)
+		}
+		if n > maxTicketPageSize {
+			n = maxTicketPageSize
+		}
+		q.Limit = n
+	}
+	if offset := c.Query("offset"); offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid offset: %s", offset)
+		}
+		q.Offset = n
+	}
+
+	return q, nil
+}
+
+// applyFilters накладывает на запрос фильтры без пагинации.
+// Используется и для страницы списка, и для выгрузки
+func (q *whitelistListQuery) applyFilters(query *gorm.DB) *gorm.DB {
+	if len(q.Stands) > 0 {
+		query = query.Where("\"from\" IN ?", q.Stands)
+	}
+	if len(q.Permissions) > 0 {
+		query = query.Where("permission IN ?", q.Permissions)
+	}
+	if q.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		query = query.Where("created_at <= ?", *q.CreatedTo)
+	}
+	if q.Search != "" {
+		pattern := "%" + likeEscaper.Replace(q.Search) + "%"
+		query = query.Where(
+			"username ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ? OR text ILIKE ? OR telegram_id = ?",
+			pattern, pattern, pattern, pattern, q.Search)
+	}
+	return query
+}
+
+// fetch возвращает страницу заявок, новые сверху, и общее количество
+func (q *whitelistListQuery) fetch(db *gorm.DB) (*whitelistListResponse, error) {
+	var total int64
+	if err := q.applyFilters(db.Model(&models.Whitelist{})).Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	items := []models.Whitelist{}
+	err := q.applyFilters(db.Model(&models.Whitelist{})).
+		Order("created_at desc, id desc").
+		Limit(q.Limit).
+		Offset(q.Offset).
+		Find(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return &whitelistListResponse{Items: items, Total: total}, nil
+}
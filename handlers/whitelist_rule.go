@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// whitelistRuleInput структура для входных данных правила. При изменении правило заменяется целиком
+type whitelistRuleInput struct {
+	Name              string   `json:"name" binding:"required" example:"dev для всех"`
+	Priority          int      `json:"priority" example:"10"`
+	Enabled           *bool    `json:"enabled"` // По умолчанию true
+	Action            string   `json:"action" binding:"required,oneof=approve deny" example:"approve"`
+	Stand             string   `json:"stand" binding:"omitempty,oneof=dev ift psi prom" example:"dev"`
+	UsernamePattern   string   `json:"username_pattern" example:"^qa_"`
+	TelegramIDs       []string `json:"telegram_ids" example:"123456789"`
+	LanguageCode      string   `json:"language_code" example:"ru"`
+	ApprovedElsewhere bool     `json:"approved_elsewhere"`
+}
+
+// ListWhitelistRules godoc
+// @Summary Список правил автоматического решения
+// @Description Возвращает правила в порядке проверки: по возрастанию priority, затем по ID
+// @Tags whitelist-rules
+// @Produce json
+// @Success 200 {array} models.WhitelistRule
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/whitelist-rules/ [get]
+func ListWhitelistRules(c *gin.Context, db *gorm.DB) {
+	rules := []models.WhitelistRule{}
+	if err := db.Order("priority asc, id asc").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch rules"})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// CreateWhitelistRule godoc
+// @Summary Создать правило автоматического решения
+// @Description Создаёт правило, которое одобряет или отклоняет новые заявки whitelist. Условия: стенд, регулярное выражение для username, список Telegram ID, language_code, наличие действующего одобренного доступа к другому стенду. Заданные условия объединяются по И, нужно хотя бы одно
+// @Tags whitelist-rules
+// @Accept json
+// @Produce json
+// @Param input body whitelistRuleInput true "Правило"
+// @Success 201 {object} models.WhitelistRule
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/whitelist-rules/ [post]
+func CreateWhitelistRule(c *gin.Context, db *gorm.DB) {
+	var input whitelistRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.WhitelistRule{CreatedBy: c.GetString("username")}
+	if err := input.applyTo(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create rule"})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateWhitelistRule godoc
+// @Summary Изменить правило автоматического решения
+// @Description Заменяет правило целиком. Уже принятые по нему решения не пересматриваются
+// @Tags whitelist-rules
+// @Accept json
+// @Produce json
+// @Param id path int true "ID правила"
+// @Param input body whitelistRuleInput true "Правило"
+// @Success 200 {object} models.WhitelistRule
+// @Failure 400 {object} map[string]string "Bad Request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/whitelist-rules/{id} [put]
+func UpdateWhitelistRule(c *gin.Context, db *gorm.DB) {
+	var input whitelistRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule, ok := findWhitelistRuleByParam(c, db)
+	if !ok {
+		return
+	}
+	if err := input.applyTo(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Save(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update rule"})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteWhitelistRule godoc
+// @Summary Удалить правило автоматического решения
+// @Description Удаляет правило. В истории решений остаётся его ID
+// @Tags whitelist-rules
+// @Produce json
+// @Param id path int true "ID правила"
+// @Success 200 {object} map[string]string "message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Not Found"
+// @Failure 500 {object} map[string]string "Internal Server Error"
+// @Security BearerAuth
+// @Router /operator/whitelist-rules/{id} [delete]
+func DeleteWhitelistRule(c *gin.Context, db *gorm.DB) {
+	rule, ok := findWhitelistRuleByParam(c, db)
+	if !ok {
+		return
+	}
+	if err := db.Delete(rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete rule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted"})
+}
+
+// applyTo переносит поля ввода в правило и проверяет его
+func (input whitelistRuleInput) applyTo(rule *models.WhitelistRule) error {
+	if input.UsernamePattern != "" {
+		if _, err := models.CompileUsernamePattern(input.UsernamePattern); err != nil {
+			return fmt.Errorf("invalid username_pattern: %w", err)
+		}
+	}
+
+	rule.Name = input.Name
+	rule.Priority = input.Priority
+	rule.Enabled = input.Enabled == nil || *input.Enabled
+	rule.Action = input.Action
+	rule.Stand = input.Stand
+	rule.UsernamePattern = input.UsernamePattern
+	rule.TelegramIDs = input.TelegramIDs
+	rule.LanguageCode = input.LanguageCode
+	rule.ApprovedElsewhere = input.ApprovedElsewhere
+	if !rule.HasConditions() {
+		return errors.New("rule must have at least one condition")
+	}
+	return nil
+}
+
+// findWhitelistRuleByParam загружает правило по :id из пути.
+// При ошибке ответ клиенту уже отправлен.
+func findWhitelistRuleByParam(c *gin.Context, db *gorm.DB) (*models.WhitelistRule, bool) {
+	var rule models.WhitelistRule
+	if err := db.First(&rule, c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Rule not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load rule"})
+		}
+		return nil, false
+	}
+	return &rule, true
+}
+
+// applyWhitelistRules проверяет новую заявку по включённым правилам и применяет первое
+// подходящее. Ошибка не мешает созданию заявки: она остаётся pending и ждёт оператора
+func applyWhitelistRules(db *gorm.DB, whitelist *models.Whitelist) {
+	var rules []models.WhitelistRule
+	if err := db.Where("enabled = ?", true).Order("priority asc, id asc").Find(&rules).Error; err != nil {
+		log.Printf("Failed to load whitelist rules: %v", err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+
+	var approvedElsewhere int64
+	err := db.Model(&models.Whitelist{}).
+		Where("telegram_id = ? AND id <> ? AND permission = ?", whitelist.TelegramID, whitelist.ID, models.WhitelistApprove).
+		Where("expires_at IS NULL OR expires_at > now()").
+		Count(&approvedElsewhere).Error
+	if err != nil {
+		log.Printf("Failed to check whitelist approvals of %s: %v", whitelist.TelegramID, err)
+		return
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Matches(*whitelist, approvedElsewhere > 0) {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(whitelist, whitelist.ID).Error; err != nil {
+				return err
+			}
+			// Оператор мог успеть принять решение раньше правила
+			if whitelist.Permission != models.WhitelistPending {
+				return nil
+			}
+			return applyWhitelistDecision(tx, whitelist, whitelistDecision{
+				Permission: rule.Action,
+				Rule:       rule,
+				Comment:    fmt.Sprintf("Автоматически по правилу #%d «%s»", rule.ID, rule.Name),
+			})
+		})
+		if err != nil {
+			log.Printf("Failed to apply whitelist rule %d to entry %d: %v", rule.ID, whitelist.ID, err)
+		}
+		return
+	}
+}
@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-contrib/cors"
 	"golang.org/x/crypto/bcrypt"
 	"helpdesk-api/config"
+	"helpdesk-api/email"
+	"helpdesk-api/events"
+	"helpdesk-api/handlers"
+	"helpdesk-api/middleware"
 	"helpdesk-api/models"
+	"helpdesk-api/outbox"
 	"helpdesk-api/routes"
+	"helpdesk-api/storage"
 	"helpdesk-api/utils"
 	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"gorm.io/driver/postgres"
@@ -30,6 +39,15 @@ func main() {
 	cfg := config.LoadConfig()
 	logger := utils.InitLogger()
 
+	switch cfg.ConsumerTokenPolicy {
+	case config.ConsumerPolicyOpen, config.ConsumerPolicyWhitelist:
+	default:
+		logger.Fatalf("Неизвестная политика CONSUMER_TOKEN_POLICY: %s", cfg.ConsumerTokenPolicy)
+	}
+	if cfg.BotAPIKey == "" {
+		logger.Warn("BOT_API_KEY не задан: публичные эндпоинты whitelist и токена пользователя принимают запросы без ключа")
+	}
+
 	dsn := "host=" + cfg.DBHost + " user=" + cfg.DBUser + " password=" + cfg.DBPassword + " dbname=" + cfg.DBName + " port=" + cfg.DBPort + " sslmode=disable TimeZone=UTC"
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
@@ -38,7 +56,12 @@ func main() {
 
 	// Базовая миграция моделей
 	err = db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.Message{}, &models.Operator{},
-		&models.Whitelist{}, &models.Endpoint{})
+		&models.Whitelist{}, &models.Endpoint{}, &models.EventLog{},
+		&models.RevokedToken{}, &models.SubjectRevocation{}, &models.RefreshToken{},
+		&models.Role{}, &models.RolePermission{}, &models.OutboxMessage{}, &models.MessageTemplate{},
+		&models.WhitelistDecision{}, &models.WhitelistRule{}, &models.BlocklistEntry{}, &models.AuditLog{},
+		&models.TelegramChannel{}, &models.TelegramUpdate{}, &models.Attachment{},
+		&models.Notification{})
 	if err != nil {
 		logger.Fatal("Ошибка миграции: ", err)
 	}
@@ -84,28 +107,99 @@ func main() {
 		logger.Info("Unique index idx_telegram_from already exists")
 	}
 
-	// Создание тестового оператора
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("securepassword"), bcrypt.DefaultCost)
-	testOperator := models.Operator{
-		Username: "operator1",
-		Password: string(hashedPassword),
-		Role:     "operator",
+	// Приводим статусы тикетов, созданных до появления машины состояний ("OPEN"/"CLOSED"), к новому виду
+	err = db.Exec(`UPDATE tickets SET status = lower(status) WHERE status <> lower(status)`).Error
+	if err != nil {
+		logger.Fatal("Failed to normalize ticket statuses: ", err)
+	}
+
+	// Заполняем время последнего сообщения для тикетов, созданных до появления сортировки по нему
+	err = db.Exec(`
+        UPDATE tickets SET last_message_at = COALESCE(
+            (SELECT MAX(messages.timestamp) FROM messages WHERE messages.ticket_id = tickets.id),
+            tickets.created_at)
+        WHERE last_message_at IS NULL`).Error
+	if err != nil {
+		logger.Fatal("Failed to backfill tickets.last_message_at: ", err)
 	}
-	db.FirstOrCreate(&testOperator, models.Operator{Username: "operator1"})
 
-	router := gin.Default()
+	// Столбцы и индексы полнотекстового поиска
+	if err := handlers.EnsureSearchSchema(db); err != nil {
+		logger.Fatal("Failed to prepare full-text search schema: ", err)
+	}
+
+	// Стендам, заведённым до появления подписи запросов, выдаём секреты
+	var unsignedEndpoints []models.Endpoint
+	if err := db.Where("secret = ''").Find(&unsignedEndpoints).Error; err != nil {
+		logger.Fatal("Failed to load endpoints without secrets: ", err)
+	}
+	for _, endpoint := range unsignedEndpoints {
+		secret, err := models.NewEndpointSecret()
+		if err != nil {
+			logger.Fatal("Failed to generate endpoint secret: ", err)
+		}
+		if err := db.Model(&endpoint).Update("secret", secret).Error; err != nil {
+			logger.Fatal("Failed to save endpoint secret: ", err)
+		}
+		logger.Warnf("Generated signing secret for stand %s; rotate it via /operator/settings/%d/secret to obtain it", endpoint.Name, endpoint.ID)
+	}
+
+	// Первый администратор берётся из конфигурации
+	if err := bootstrapAdmin(db, cfg, logger); err != nil {
+		logger.Fatal("Failed to bootstrap admin: ", err)
+	}
+
+	// Журнал событий для SSE-клиентов, дочитывающих пропущенное по Last-Event-ID
+	events.Init(db, cfg.EventLogRetention)
+
+	// Вместо gin.Default: стандартный журнал запросов записал бы access_token из URL WebSocket
+	router := gin.New()
+	router.Use(middleware.RequestLogger(), gin.Recovery())
+	// IP клиента для лимитов и блоклиста берётся из X-Forwarded-For только от доверенных прокси
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logger.Fatal("Некорректный TRUSTED_PROXIES: ", err)
+	}
 
 	// Настройка CORS
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:8001", "http://localhost:8000", "http://admin.wallet.shaneque.ru"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Last-Event-ID", middleware.HeaderBotAPIKey},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
-	routes.SetupRoutes(router, db, cfg, logger)
+	store, err := newAttachmentStorage(cfg)
+	if err != nil {
+		logger.Fatal("Не удалось подключить хранилище вложений: ", err)
+	}
+
+	routes.SetupRoutes(router, db, cfg, logger, store)
+
+	// Ответы по почтовому каналу; без SMTP_ADDR они копятся в outbox и уходят в dead
+	mailer, err := email.NewSender(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom)
+	if err != nil {
+		logger.Fatal("Некорректный EMAIL_FROM: ", err)
+	}
+	if !mailer.Configured() {
+		logger.Warn("SMTP_ADDR не задан: ответы на тикеты из почты не будут отправляться")
+	}
+
+	// Фоновая доставка уведомлений из outbox; стартует после загрузки URL стендов в SetupRoutes
+	dispatcher := outbox.NewDispatcher(db, logger, outbox.Options{
+		MaxAttempts:      cfg.OutboxMaxAttempts,
+		StandConcurrency: cfg.OutboxStandConcurrency,
+	})
+	dispatcher.Register(handlers.OutboxKindStandNotification, handlers.DeliverStandNotification)
+	dispatcher.Register(handlers.OutboxKindTicketReply, handlers.DeliverTicketReply(db, cfg))
+	dispatcher.OnDead(handlers.OutboxKindTicketReply, handlers.TicketReplyDead(db))
+	dispatcher.Register(handlers.OutboxKindEmailReply, handlers.DeliverEmailReply(db, mailer))
+	dispatcher.OnDead(handlers.OutboxKindEmailReply, handlers.TicketReplyDead(db))
+	dispatcher.Start()
+
+	// Автоматический отзыв одобрений whitelist с истёкшим сроком и очистка обработанных обновлений Telegram
+	handlers.StartMaintenance(db, time.Minute)
 
 	// Swagger
 	router.GET("/swagger/*any", func(c *gin.Context) {
@@ -120,3 +214,75 @@ func main() {
 		log.Fatal("Ошибка запуска сервера: ", err)
 	}
 }
+
+// newAttachmentStorage подключает хранилище вложений, выбранное в STORAGE_BACKEND
+func newAttachmentStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case config.StorageLocal:
+		return storage.NewLocal(cfg.StorageDir)
+	case config.StorageS3:
+		store, err := storage.NewS3(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := store.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND %q", cfg.StorageBackend)
+	}
+}
+
+// bootstrapAdmin создаёт администратора из BOOTSTRAP_ADMIN_USERNAME/BOOTSTRAP_ADMIN_PASSWORD,
+// если в базе нет ни одного администратора. Заодно отключает тестовую учётку operator1,
+// которую раньше создавал сервер, если у неё остался пароль по умолчанию.
+func bootstrapAdmin(db *gorm.DB, cfg *config.Config, logger *logrus.Logger) error {
+	var legacy models.Operator
+	if err := db.Where("username = ? AND disabled = ?", "operator1", false).First(&legacy).Error; err == nil {
+		if bcrypt.CompareHashAndPassword([]byte(legacy.Password), []byte("securepassword")) == nil {
+			if err := db.Model(&legacy).Update("disabled", true).Error; err != nil {
+				return err
+			}
+			logger.Warn("Disabled legacy operator1 account with the default password")
+		}
+	}
+
+	var admins int64
+	if err := db.Model(&models.Operator{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+	if cfg.BootstrapAdminUsername == "" || cfg.BootstrapAdminPassword == "" {
+		logger.Warn("No admin account exists; set BOOTSTRAP_ADMIN_USERNAME and BOOTSTRAP_ADMIN_PASSWORD to create one")
+		return nil
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(cfg.BootstrapAdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	admin := models.Operator{
+		Username: cfg.BootstrapAdminUsername,
+		Password: string(hashedPassword),
+		Role:     models.RoleAdmin,
+	}
+	// Если оператор с таким именем уже есть, повышаем его до администратора
+	result := db.Where(models.Operator{Username: admin.Username}).
+		Assign(models.Operator{Password: admin.Password, Role: admin.Role}).
+		FirstOrCreate(&admin)
+	if result.Error != nil {
+		return result.Error
+	}
+	if admin.Disabled {
+		if err := db.Model(&admin).Update("disabled", false).Error; err != nil {
+			return err
+		}
+	}
+	logger.Infof("Bootstrapped admin account %s", admin.Username)
+	return nil
+}
@@ -4,16 +4,21 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
 	"net/http"
 	"strings"
 )
 
 var logger = logrus.New()
 
-func JWTMiddleware(secret string) gin.HandlerFunc {
+func JWTMiddleware(secret string, revocations *auth.RevocationStore, permissions *auth.PermissionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
-		logger.Infof("Received Authorization header: %s", authHeader)
+		// Браузерный WebSocket не умеет передавать заголовки, поэтому для него токен принимается в query
+		if authHeader == "" && c.IsWebsocket() && c.Query("access_token") != "" {
+			authHeader = "Bearer " + c.Query("access_token")
+		}
 		if authHeader == "" {
 			logger.Warn("No Authorization header provided")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Требуется токен авторизации"})
@@ -22,7 +27,7 @@ func JWTMiddleware(secret string) gin.HandlerFunc {
 
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			logger.Warnf("Invalid Authorization header format: %s", authHeader)
+			logger.Warn("Invalid Authorization header format")
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Неверный формат токена"})
 			return
 		}
@@ -46,6 +51,19 @@ func JWTMiddleware(secret string) gin.HandlerFunc {
 			return
 		}
 
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			// Токены без jti выданы до появления отзыва и не могут быть отозваны
+			logger.Warn("Token without jti rejected")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Токен устарел, получите новый"})
+			return
+		}
+		if revocations.IsRevoked(jti, auth.Subject(claims), auth.IssuedAt(claims)) {
+			logger.Warnf("Revoked token used: %s", jti)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Токен отозван"})
+			return
+		}
+
 		role, _ := claims["role"].(string)
 		logger.Infof("Extracted role: %s", role)
 		if role == "" {
@@ -54,7 +72,7 @@ func JWTMiddleware(secret string) gin.HandlerFunc {
 			return
 		}
 
-		if role == "user" {
+		if role == models.RoleUser {
 			telegramID, ok := claims["telegram_id"].(string)
 			if !ok || telegramID == "" {
 				logger.Warn("No telegram_id in user token")
@@ -62,7 +80,10 @@ func JWTMiddleware(secret string) gin.HandlerFunc {
 				return
 			}
 			c.Set("telegram_id", telegramID)
-		} else if role == "operator" {
+			// Стенд, для которого выдан токен; пуст у токенов, выданных без стенда
+			stand, _ := claims["stand"].(string)
+			c.Set("stand", stand)
+		} else {
 			username, ok := claims["username"].(string)
 			if !ok || username == "" {
 				logger.Warn("No username in operator token")
@@ -73,7 +94,9 @@ func JWTMiddleware(secret string) gin.HandlerFunc {
 		}
 
 		c.Set("role", role)
-		c.Set("claims", claims) // Устанавливаем claims для operatorMiddleware
+		c.Set("permissions", permissions.For(role))
+		c.Set("jti", jti)
+		c.Set("claims", claims) // Устанавливаем claims для проверки ролей и логаута
 		logger.Info("JWT middleware passed successfully")
 		c.Next()
 	}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"helpdesk-api/auth"
+	"helpdesk-api/models"
+	"net/http"
+	"strings"
+)
+
+// RequireStaff пропускает только сотрудников поддержки, без проверки конкретных прав.
+// Ставится после JWTMiddleware.
+func RequireStaff() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !models.IsOperatorRole(c.GetString("role")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: Operator access required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePermission пропускает только тех, у чьей роли есть все перечисленные права.
+// Ставится после JWTMiddleware, который кладёт права роли в контекст.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		granted := Permissions(c)
+		for _, permission := range permissions {
+			if !granted.Has(permission) {
+				logger.Warnf("Role %s denied, permissions %s required", role, strings.Join(permissions, ", "))
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: permission " + permission + " required"})
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// Permissions возвращает права текущего запроса, положенные JWTMiddleware
+func Permissions(c *gin.Context) auth.PermissionSet {
+	if value, ok := c.Get("permissions"); ok {
+		if set, ok := value.(auth.PermissionSet); ok {
+			return set
+		}
+	}
+	return auth.PermissionSet{}
+}
@@ -0,0 +1,200 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"helpdesk-api/audit"
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+	"helpdesk-api/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// HeaderBotAPIKey — заголовок с общим ключом бота для публичных эндпоинтов
+const HeaderBotAPIKey = "X-Bot-Api-Key"
+
+// maxGuardedBodySize — максимальный размер тела запроса к публичному эндпоинту
+const maxGuardedBodySize = 64 << 10
+
+// TelegramIDExtractor достаёт telegram_id из тела запроса; пустая строка — не найден
+type TelegramIDExtractor func(body []byte) string
+
+// Blocklist — проверки блоклиста, которые нужны защите; реализуется auth.BlocklistStore
+type Blocklist interface {
+	IsIPBlocked(ip string) bool
+	IsTelegramIDBlocked(telegramID string) bool
+}
+
+// PublicGuard защищает неаутентифицированные эндпоинты (заявка в whitelist, токен пользователя):
+// ключ бота, блоклист и ограничение частоты по IP и по telegram_id. Отказы пишутся в журнал аудита
+type PublicGuard struct {
+	apiKey    string
+	blocklist Blocklist
+	record    func(entry models.AuditLog)
+
+	byIP         *ratelimit.Limiter
+	byTelegramID *ratelimit.Limiter
+	// auditThrottle пропускает в журнал одну запись об отказе на ключ за окно,
+	// чтобы поток отклонённых запросов не превратился в поток записей аудита
+	auditThrottle *ratelimit.Limiter
+}
+
+// NewPublicGuard создаёт защиту публичных эндпоинтов по настройкам из cfg
+func NewPublicGuard(db *gorm.DB, cfg *config.Config, blocklist Blocklist) *PublicGuard {
+	return &PublicGuard{
+		apiKey:        cfg.BotAPIKey,
+		blocklist:     blocklist,
+		record:        func(entry models.AuditLog) { audit.Record(db, entry) },
+		byIP:          ratelimit.New(cfg.RateLimitPerIP, cfg.RateLimitWindow),
+		byTelegramID:  ratelimit.New(cfg.RateLimitPerTelegram, cfg.RateLimitWindow),
+		auditThrottle: ratelimit.New(1, cfg.RateLimitWindow),
+	}
+}
+
+// Middleware возвращает обработчик для одного эндпоинта. extract достаёт из тела telegram_id
+// для блоклиста и лимита по пользователю; тело после чтения восстанавливается. Запрос, в котором
+// telegram_id не найден, отклоняется: иначе он обошёл бы блоклист и лимит по пользователю
+func (g *PublicGuard) Middleware(extract TelegramIDExtractor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+
+		if g.blocklist.IsIPBlocked(ip) {
+			g.reject(c, http.StatusForbidden, models.AuditPublicBlocked, "", "ip is blocklisted")
+			return
+		}
+		if ok, retryAfter := g.byIP.Allow(ip); !ok {
+			g.rejectRateLimited(c, "", retryAfter, "ip rate limit exceeded")
+			return
+		}
+
+		if g.apiKey != "" {
+			key := c.GetHeader(HeaderBotAPIKey)
+			if subtle.ConstantTimeCompare([]byte(key), []byte(g.apiKey)) != 1 {
+				detail := "invalid bot api key"
+				if key == "" {
+					detail = "missing bot api key"
+				}
+				g.reject(c, http.StatusUnauthorized, models.AuditPublicBadAPIKey, "", detail)
+				return
+			}
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGuardedBodySize+1))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать запрос"})
+			return
+		}
+		if len(body) > maxGuardedBodySize {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Слишком большой запрос"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		telegramID := extract(body)
+		if telegramID == "" {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Не указан telegram_id"})
+			return
+		}
+		if g.blocklist.IsTelegramIDBlocked(telegramID) {
+			g.reject(c, http.StatusForbidden, models.AuditPublicBlocked, telegramID, "telegram_id is blocklisted")
+			return
+		}
+		if ok, retryAfter := g.byTelegramID.Allow(telegramID); !ok {
+			g.rejectRateLimited(c, telegramID, retryAfter, "telegram_id rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+func (g *PublicGuard) rejectRateLimited(c *gin.Context, telegramID string, retryAfter time.Duration, detail string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	g.reject(c, http.StatusTooManyRequests, models.AuditPublicRateLimit, telegramID, detail)
+}
+
+// reject отвечает отказом и пишет его в журнал аудита, если по этому ключу в текущем окне ещё не писали
+func (g *PublicGuard) reject(c *gin.Context, status int, action, telegramID, detail string) {
+	entry := audit.FromRequest(c, action)
+	entry.TelegramID = telegramID
+	entry.Detail = detail
+	if ok, _ := g.auditThrottle.Allow(fmt.Sprintf("%s|%s|%s", action, entry.IP, telegramID)); ok {
+		g.record(entry)
+	}
+
+	message := "Доступ запрещён"
+	switch status {
+	case http.StatusTooManyRequests:
+		message = "Слишком много запросов, повторите позже"
+	case http.StatusUnauthorized:
+		message = "Неверный ключ API"
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// JSONField возвращает TelegramIDExtractor, который берёт значение по пути полей JSON,
+// например JSONField("user", "id"). Числа и строки возвращаются как строка. Поля ищутся так же,
+// как их находит encoding/json при привязке тела в обработчике: имя без учёта регистра,
+// из повторяющихся ключей побеждает последний
+func JSONField(path ...string) TelegramIDExtractor {
+	return func(body []byte) string {
+		raw := json.RawMessage(body)
+		for _, field := range path {
+			var ok bool
+			if raw, ok = jsonObjectField(raw, field); !ok {
+				return ""
+			}
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return ""
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		default:
+			return ""
+		}
+	}
+}
+
+// jsonObjectField возвращает значение поля name объекта JSON raw
+func jsonObjectField(raw []byte, name string) (json.RawMessage, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, false
+	}
+	var (
+		value json.RawMessage
+		found bool
+	)
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, false
+		}
+		key, _ := token.(string)
+		var field json.RawMessage
+		if err := decoder.Decode(&field); err != nil {
+			return nil, false
+		}
+		if strings.EqualFold(key, name) {
+			value, found = field, true
+		}
+	}
+	return value, found
+}
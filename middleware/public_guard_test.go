@@ -0,0 +1,227 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"helpdesk-api/config"
+	"helpdesk-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testBotAPIKey = "bot-key"
+
+// fakeBlocklist — блоклист из фиксированных IP и telegram_id
+type fakeBlocklist struct {
+	ips         map[string]bool
+	telegramIDs map[string]bool
+}
+
+func (b fakeBlocklist) IsIPBlocked(ip string) bool                 { return b.ips[ip] }
+func (b fakeBlocklist) IsTelegramIDBlocked(telegramID string) bool { return b.telegramIDs[telegramID] }
+
+// guardTest — роутер с защищёнными эндпоинтами, как в routes, и записанный журнал аудита
+type guardTest struct {
+	router *gin.Engine
+
+	mu      sync.Mutex
+	audited []models.AuditLog
+}
+
+func newGuardTest(t *testing.T, cfg *config.Config, blocklist Blocklist) *guardTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	gt := &guardTest{router: gin.New()}
+	guard := NewPublicGuard(nil, cfg, blocklist)
+	guard.record = func(entry models.AuditLog) {
+		gt.mu.Lock()
+		gt.audited = append(gt.audited, entry)
+		gt.mu.Unlock()
+	}
+
+	// Обработчики привязывают тело так же, как настоящие, и возвращают то, что получили
+	gt.router.POST("/consumers/token/", guard.Middleware(JSONField("telegram_id")), func(c *gin.Context) {
+		var input struct {
+			TelegramID string `json:"telegram_id" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"telegram_id": input.TelegramID})
+	})
+	gt.router.POST("/whitelist", guard.Middleware(JSONField("user", "id")), func(c *gin.Context) {
+		var input struct {
+			User struct {
+				ID int64 `json:"id" binding:"required"`
+			} `json:"user" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"id": input.User.ID})
+	})
+	return gt
+}
+
+func (gt *guardTest) post(path, ip, apiKey, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.RemoteAddr = ip + ":40000"
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set(HeaderBotAPIKey, apiKey)
+	}
+	w := httptest.NewRecorder()
+	gt.router.ServeHTTP(w, req)
+	return w
+}
+
+func (gt *guardTest) auditCount() int {
+	gt.mu.Lock()
+	defer gt.mu.Unlock()
+	return len(gt.audited)
+}
+
+func testGuardConfig() *config.Config {
+	return &config.Config{
+		BotAPIKey:            testBotAPIKey,
+		RateLimitPerIP:       100,
+		RateLimitPerTelegram: 100,
+		RateLimitWindow:      time.Minute,
+	}
+}
+
+func TestPublicGuardResponses(t *testing.T) {
+	blocklist := fakeBlocklist{
+		ips:         map[string]bool{"203.0.113.9": true},
+		telegramIDs: map[string]bool{"666": true},
+	}
+	tests := []struct {
+		name   string
+		path   string
+		ip     string
+		apiKey string
+		body   string
+		want   int
+	}{
+		{"allowed", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"100"}`, http.StatusOK},
+		{"missing api key", "/consumers/token/", "192.0.2.1", "", `{"telegram_id":"100"}`, http.StatusUnauthorized},
+		{"wrong api key", "/consumers/token/", "192.0.2.1", "bot-kez", `{"telegram_id":"100"}`, http.StatusUnauthorized},
+		{"blocked ip", "/consumers/token/", "203.0.113.9", testBotAPIKey, `{"telegram_id":"100"}`, http.StatusForbidden},
+		{"blocked telegram_id", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"666"}`, http.StatusForbidden},
+		{"blocked telegram_id, other case", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"Telegram_ID":"666"}`, http.StatusForbidden},
+		{"blocked telegram_id, last duplicate wins", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"100","TELEGRAM_ID":"666"}`, http.StatusForbidden},
+		{"blocked user id", "/whitelist", "192.0.2.1", testBotAPIKey, `{"user":{"id":666}}`, http.StatusForbidden},
+		{"blocked user id, other case", "/whitelist", "192.0.2.1", testBotAPIKey, `{"USER":{"Id":666}}`, http.StatusForbidden},
+		{"allowed user id", "/whitelist", "192.0.2.1", testBotAPIKey, `{"user":{"id":100}}`, http.StatusOK},
+		{"no telegram_id", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"stand":"ift"}`, http.StatusBadRequest},
+		{"no user", "/whitelist", "192.0.2.1", testBotAPIKey, `{"text":"hi"}`, http.StatusBadRequest},
+		{"not json", "/consumers/token/", "192.0.2.1", testBotAPIKey, `telegram_id=666`, http.StatusBadRequest},
+		{"body too large", "/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"100","pad":"` + strings.Repeat("x", maxGuardedBodySize) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gt := newGuardTest(t, testGuardConfig(), blocklist)
+			if w := gt.post(tt.path, tt.ip, tt.apiKey, tt.body); w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestPublicGuardRateLimit(t *testing.T) {
+	cfg := testGuardConfig()
+	cfg.RateLimitPerTelegram = 2
+	gt := newGuardTest(t, cfg, fakeBlocklist{})
+
+	// Лимит по telegram_id общий для любых IP и любого написания поля
+	bodies := []string{`{"telegram_id":"100"}`, `{"Telegram_Id":"100"}`, `{"TELEGRAM_ID":"100"}`}
+	for i, body := range bodies {
+		w := gt.post("/consumers/token/", "192.0.2."+string(rune('1'+i)), testBotAPIKey, body)
+		want := http.StatusOK
+		if i == len(bodies)-1 {
+			want = http.StatusTooManyRequests
+		}
+		if w.Code != want {
+			t.Fatalf("request %d: status = %d, want %d: %s", i, w.Code, want, w.Body)
+		}
+		if want == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "60" {
+			t.Fatalf("Retry-After = %q, want 60", w.Header().Get("Retry-After"))
+		}
+	}
+	if w := gt.post("/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"200"}`); w.Code != http.StatusOK {
+		t.Fatalf("other telegram_id: status = %d, want 200", w.Code)
+	}
+
+	cfg = testGuardConfig()
+	cfg.RateLimitPerIP = 1
+	gt = newGuardTest(t, cfg, fakeBlocklist{})
+	gt.post("/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"100"}`)
+	w := gt.post("/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"200"}`)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("ip limit: status = %d, Retry-After %q; want 429 with Retry-After", w.Code, w.Header().Get("Retry-After"))
+	}
+}
+
+func TestPublicGuardAuditThrottle(t *testing.T) {
+	gt := newGuardTest(t, testGuardConfig(), fakeBlocklist{telegramIDs: map[string]bool{"666": true}})
+
+	for i := 0; i < 5; i++ {
+		if w := gt.post("/consumers/token/", "192.0.2.1", "wrong", `{"telegram_id":"100"}`); w.Code != http.StatusUnauthorized {
+			t.Fatalf("status = %d, want 401", w.Code)
+		}
+	}
+	if got := gt.auditCount(); got != 1 {
+		t.Fatalf("audit rows after repeated rejections = %d, want 1", got)
+	}
+
+	// Другой IP, другое действие или другой telegram_id — новый ключ и новая запись
+	gt.post("/consumers/token/", "192.0.2.2", "wrong", `{"telegram_id":"100"}`)
+	gt.post("/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"666"}`)
+	gt.post("/consumers/token/", "192.0.2.1", testBotAPIKey, `{"telegram_id":"666"}`)
+	if got := gt.auditCount(); got != 3 {
+		t.Fatalf("audit rows = %d, want 3", got)
+	}
+
+	gt.mu.Lock()
+	defer gt.mu.Unlock()
+	first, blocked := gt.audited[0], gt.audited[2]
+	if first.Action != models.AuditPublicBadAPIKey || first.IP != "192.0.2.1" || first.Detail != "invalid bot api key" {
+		t.Fatalf("bad key audit = %+v", first)
+	}
+	if blocked.Action != models.AuditPublicBlocked || blocked.TelegramID != "666" {
+		t.Fatalf("blocked audit = %+v", blocked)
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	tests := []struct {
+		body string
+		path []string
+		want string
+	}{
+		{`{"telegram_id":"100"}`, []string{"telegram_id"}, "100"},
+		{`{"telegram_id":100}`, []string{"telegram_id"}, "100"},
+		{`{"TeLeGrAm_Id":"100"}`, []string{"telegram_id"}, "100"},
+		{`{"telegram_id":"1","telegram_id":"2"}`, []string{"telegram_id"}, "2"},
+		{`{"user":{"id":5}}`, []string{"user", "id"}, "5"},
+		{`{"User":{"ID":5},"x":[1,{"id":6}]}`, []string{"user", "id"}, "5"},
+		{`{"user":{"id":5},"user":{"id":7}}`, []string{"user", "id"}, "7"},
+		{`{"user":null}`, []string{"user", "id"}, ""},
+		{`{"user":{"id":true}}`, []string{"user", "id"}, ""},
+		{`{"telegram_id":{"id":"1"}}`, []string{"telegram_id"}, ""},
+		{`["telegram_id"]`, []string{"telegram_id"}, ""},
+		{`{"telegram_id":`, []string{"telegram_id"}, ""},
+	}
+	for _, tt := range tests {
+		if got := JSONField(tt.path...)([]byte(tt.body)); got != tt.want {
+			t.Errorf("JSONField(%v)(%s) = %q, want %q", tt.path, tt.body, got, tt.want)
+		}
+	}
+}
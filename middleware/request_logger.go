@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// secretQueryRe находит в строке запроса параметры с токенами. access_token передаёт
+// браузерный WebSocket (см. JWTMiddleware), и в журнал он попадать не должен
+var secretQueryRe = regexp.MustCompile(`(?i)([?&](?:access_token|token)=)[^&]*`)
+
+// RequestLogger — журнал запросов в формате gin.Logger, но с вычеркнутыми токенами в URL
+func RequestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
+		}
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			RedactURL(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
+
+// RedactURL заменяет значения параметров с токенами в пути запроса на REDACTED
+func RedactURL(path string) string {
+	return secretQueryRe.ReplaceAllString(path, "${1}REDACTED")
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import "testing"
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/ws", "/api/ws"},
+		{"/api/ws?access_token=eyJ.abc.def", "/api/ws?access_token=REDACTED"},
+		{"/api/ws?ticket_id=1&access_token=eyJ.abc.def&x=2", "/api/ws?ticket_id=1&access_token=REDACTED&x=2"},
+		{"/api/events/stream?Access_Token=a&token=b", "/api/events/stream?Access_Token=REDACTED&token=REDACTED"},
+		{"/api/tickets/?my_access_token=keep", "/api/tickets/?my_access_token=keep"},
+	}
+	for _, tt := range tests {
+		if got := RedactURL(tt.path); got != tt.want {
+			t.Errorf("RedactURL(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
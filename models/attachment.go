@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Attachment — файл, приложенный к сообщению тикета. Сам файл лежит в хранилище вложений
+// (storage.Storage) под ключом StorageKey, в базе — только его описание
+type Attachment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	MessageID uint      `gorm:"index;not null" json:"message_id"`
+	TicketID  uint      `gorm:"index;not null" json:"ticket_id"`
+	Filename  string    `gorm:"not null" json:"filename" example:"screenshot.png"`
+	// ContentType определяется по содержимому файла, а не по заявленному клиентом
+	ContentType string `gorm:"not null" json:"content_type" example:"image/png"`
+	Size        int64  `gorm:"not null" json:"size" example:"48213"`
+	SHA256      string `gorm:"column:sha256;not null" json:"sha256"`
+	StorageKey  string `gorm:"not null" json:"-"`
+}
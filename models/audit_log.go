@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Действия, записываемые в журнал аудита
+const (
+	AuditPublicBadAPIKey  = "public.bad_api_key"
+	AuditPublicBlocked    = "public.blocked"
+	AuditPublicRateLimit  = "public.rate_limited"
+	AuditBlocklistAdded   = "blocklist.added"
+	AuditBlocklistRemoved = "blocklist.removed"
+)
+
+// AuditLog — запись журнала аудита: отказы публичных эндпоинтов и изменения блоклиста
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	Action     string    `gorm:"index;not null" json:"action"`
+	Actor      string    `gorm:"not null;default:''" json:"actor"` // Оператор; пусто для анонимных запросов
+	IP         string    `gorm:"index;not null;default:''" json:"ip"`
+	TelegramID string    `gorm:"index;not null;default:''" json:"telegram_id"`
+	Method     string    `gorm:"not null;default:''" json:"method"`
+	Path       string    `gorm:"not null;default:''" json:"path"`
+	Detail     string    `gorm:"type:text;not null;default:''" json:"detail"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// Виды записей блоклиста
+const (
+	BlockKindTelegramID = "telegram_id"
+	BlockKindIP         = "ip"
+)
+
+// BlocklistEntry — заблокированный telegram_id или IP (адрес либо подсеть в нотации CIDR).
+// Заблокированным отказывается в публичных эндпоинтах: заявке в whitelist и получении токена
+type BlocklistEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Kind      string     `gorm:"uniqueIndex:idx_blocklist_kind_value;not null" json:"kind" example:"telegram_id"` // telegram_id или ip
+	Value     string     `gorm:"uniqueIndex:idx_blocklist_kind_value;not null" json:"value" example:"88376478"`
+	Reason    string     `gorm:"type:text;not null;default:''" json:"reason" example:"Спам заявками"`
+	CreatedBy string     `gorm:"not null;default:''" json:"created_by"`
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"` // Пусто — бессрочно
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"gorm.io/gorm"
 )
@@ -10,6 +12,9 @@ type Endpoint struct {
 	ID   uint   `gorm:"primaryKey" json:"id"`
 	Name string `json:"name"`
 	URL  string `json:"url"`
+	// Secret — общий со стендом секрет для HMAC-подписи запросов. Наружу не отдаётся,
+	// показывается только один раз при ротации
+	Secret string `gorm:"not null;default:''" json:"-"`
 }
 
 // BeforeCreate хук для валидации перед созданием
@@ -17,5 +22,21 @@ func (e *Endpoint) BeforeCreate(tx *gorm.DB) error {
 	if e.Name == "" || e.URL == "" {
 		return fmt.Errorf("name and URL cannot be empty")
 	}
+	if e.Secret == "" {
+		secret, err := NewEndpointSecret()
+		if err != nil {
+			return err
+		}
+		e.Secret = secret
+	}
 	return nil
 }
+
+// NewEndpointSecret генерирует случайный секрет стенда (256 бит в hex)
+func NewEndpointSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
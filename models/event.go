@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+)
+
+// EventLog — журнал событий тикетов. ID служит идентификатором события в SSE (Last-Event-ID),
+// поэтому переподключившийся клиент может дочитать пропущенное.
+type EventLog struct {
+	ID         uint64    `gorm:"primaryKey" json:"id"`
+	Type       string    `gorm:"not null" json:"type"`
+	TicketID   uint      `gorm:"index;not null" json:"ticket_id"`
+	UserID     uint      `gorm:"index;not null" json:"user_id"` // Владелец тикета
+	StaffOnly  bool      `gorm:"not null;default:false" json:"staff_only"`
+	OperatorID uint      `gorm:"index;not null;default:0" json:"operator_id"` // Адресат события; 0 — все, кому виден тикет
+	Payload    string    `gorm:"type:jsonb;not null" json:"payload"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
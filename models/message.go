@@ -4,6 +4,13 @@ import (
 	"time"
 )
 
+// Статусы доставки ответа оператора пользователю
+const (
+	MessageDeliveryQueued = "queued" // В очереди outbox, в том числе между повторными попытками
+	MessageDeliverySent   = "sent"   // Передано боту стенда или стенду
+	MessageDeliveryFailed = "failed" // Попытки исчерпаны
+)
+
 type Message struct {
 	ID        uint       `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time  `json:"created_at"`
@@ -14,4 +21,20 @@ type Message struct {
 	Recipient string     `gorm:"not null" json:"recipient"`
 	Content   string     `gorm:"not null" json:"content"`
 	Timestamp time.Time  `gorm:"autoCreateTime" json:"timestamp"`
+	// Internal — внутренняя заметка операторов: пользователю не показывается и никуда не доставляется.
+	// Автор заметки — OperatorID
+	Internal   bool  `gorm:"index;not null;default:false" json:"internal,omitempty"`
+	OperatorID *uint `json:"operator_id,omitempty"`
+
+	// Доставка пользователю: заполняется только для ответов оператора, которые отправляются
+	// в чат пользователя. Пустой статус — сообщение наружу не отправлялось
+	DeliveryStatus   string     `gorm:"not null;default:''" json:"delivery_status,omitempty"`
+	DeliveryAttempts int        `gorm:"not null;default:0" json:"delivery_attempts,omitempty"`
+	DeliveryError    string     `gorm:"not null;default:''" json:"delivery_error,omitempty"`
+	DeliveredAt      *time.Time `json:"delivered_at,omitempty"`
+	// ExternalID — Message-ID письма (почтовый канал), входящего или отправленного; по нему
+	// ответы пользователя находят свой тикет
+	ExternalID string `gorm:"index;not null;default:''" json:"-"`
+
+	Attachments []Attachment `gorm:"foreignKey:MessageID" json:"attachments,omitempty"`
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// MessageTemplate — шаблон сообщения пользователю о решении по заявке whitelist.
+// Текст — шаблон text/template, в котором доступны поля заявки (.FirstName, .Username, .From …).
+// Шаблон с пустым Stand действует для стендов, у которых нет своего.
+type MessageTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Stand     string    `gorm:"uniqueIndex:idx_template_stand_decision;not null;default:''" json:"stand" example:"ift"`
+	Decision  string    `gorm:"uniqueIndex:idx_template_stand_decision;not null" json:"decision" example:"approve"` // approve, deny или revoke
+	Body      string    `gorm:"type:text;not null" json:"body" example:"{{.FirstName}}, доступ к стенду {{.From}} открыт"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// Типы уведомлений операторов
+const (
+	NotificationMention = "mention" // Оператора упомянули (@username) во внутренней заметке
+)
+
+// Notification — уведомление оператора о событии в тикете
+type Notification struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	OperatorID uint       `gorm:"index;not null" json:"operator_id"` // Кому адресовано уведомление
+	Type       string     `gorm:"not null" json:"type" example:"mention"`
+	TicketID   uint       `gorm:"not null" json:"ticket_id"`
+	MessageID  uint       `gorm:"not null" json:"message_id"`
+	Actor      string     `gorm:"not null" json:"actor" example:"ivanov"` // Кто упомянул
+	ReadAt     *time.Time `json:"read_at"`
+}
@@ -4,12 +4,29 @@ import (
 	"time"
 )
 
+// RoleUser — роль пользователя (клиента поддержки) в токене
+const RoleUser = "user"
+
+// Встроенные роли сотрудников поддержки. Права ролей хранятся в БД (см. RolePermission),
+// поэтому можно заводить и собственные роли
+const (
+	RoleOperator   = "operator"
+	RoleSupervisor = "supervisor"
+	RoleAdmin      = "admin"
+)
+
 type Operator struct {
 	ID        uint       `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `gorm:"index" json:"deleted_at"`
 	Username  string     `gorm:"unique;not null" json:"username"`
-	Password  string     `gorm:"not null" json:"password"` // Хеш пароля
+	Password  string     `gorm:"not null" json:"-"` // Хеш пароля, наружу не отдаётся
 	Role      string     `gorm:"not null;default:'operator'" json:"role"`
+	Disabled  bool       `gorm:"not null;default:false" json:"disabled"` // Отключённый оператор не может войти
+}
+
+// IsOperatorRole сообщает, что роль принадлежит сотруднику поддержки, а не пользователю
+func IsOperatorRole(role string) bool {
+	return role != "" && role != RoleUser
 }
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Статусы сообщения в outbox
+const (
+	OutboxStatusPending   = "pending"   // Ждёт отправки или повторной попытки
+	OutboxStatusDelivered = "delivered" // Доставлено
+	OutboxStatusDead      = "dead"      // Попытки исчерпаны, нужна ручная переотправка
+)
+
+// OutboxMessage — исходящее уведомление, записанное в той же транзакции, что и изменение,
+// о котором оно сообщает. Доставкой занимается диспетчер outbox в фоне.
+type OutboxMessage struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Kind          string     `gorm:"index;not null" json:"kind"`             // Тип уведомления, по нему выбирается обработчик доставки
+	Stand         string     `gorm:"index;not null;default:''" json:"stand"` // Стенд-получатель, для ограничения параллелизма
+	Payload       string     `gorm:"type:jsonb;not null" json:"payload"`
+	Status        string     `gorm:"index:idx_outbox_due,priority:1;not null;default:'pending'" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"index:idx_outbox_due,priority:2;not null" json:"next_attempt_at"`
+	LastError     string     `gorm:"not null;default:''" json:"last_error"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// Права доступа сотрудников поддержки. Роль — это просто именованный набор прав
+const (
+	PermTicketsReadAll   = "tickets.read_all"   // Просмотр любых тикетов, истории и поиска
+	PermTicketsReply     = "tickets.reply"      // Ответы в тикетах и смена их статуса
+	PermTicketsAssign    = "tickets.assign"     // Взять тикет в работу, освободить или передать свой
+	PermTicketsAssignAny = "tickets.assign_any" // Освободить или переназначить чужой тикет
+	PermTicketsCloseAny  = "tickets.close_any"  // Закрыть любой тикет
+	PermWhitelistRead    = "whitelist.read"     // Просмотр заявок в whitelist
+	PermWhitelistApprove = "whitelist.approve"  // Одобрение и отклонение заявок
+	PermSettingsManage   = "settings.manage"    // Управление настройками стендов
+	PermOperatorsManage  = "operators.manage"   // Управление операторами и ролями
+)
+
+// AllPermissions — все известные права; назначить роли можно только их
+var AllPermissions = []string{
+	PermTicketsReadAll,
+	PermTicketsReply,
+	PermTicketsAssign,
+	PermTicketsAssignAny,
+	PermTicketsCloseAny,
+	PermWhitelistRead,
+	PermWhitelistApprove,
+	PermSettingsManage,
+	PermOperatorsManage,
+}
+
+// DefaultRolePermissions — права встроенных ролей, создаваемых при первом запуске.
+// Дальше они редактируются через API и в коде не меняются
+var DefaultRolePermissions = map[string][]string{
+	RoleOperator: {
+		PermTicketsReadAll, PermTicketsReply, PermTicketsAssign, PermTicketsCloseAny,
+		PermWhitelistRead, PermWhitelistApprove,
+	},
+	RoleSupervisor: {
+		PermTicketsReadAll, PermTicketsReply, PermTicketsAssign, PermTicketsAssignAny, PermTicketsCloseAny,
+		PermWhitelistRead, PermWhitelistApprove, PermSettingsManage,
+	},
+	RoleAdmin: AllPermissions,
+}
+
+// IsValidPermission сообщает, что право известно системе
+func IsValidPermission(permission string) bool {
+	for _, p := range AllPermissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Role — роль сотрудника поддержки
+type Role struct {
+	Name        string           `gorm:"primaryKey" json:"name" example:"auditor"`
+	Description string           `json:"description" example:"Только просмотр тикетов"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	Permissions []RolePermission `gorm:"foreignKey:Role;references:Name;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// RolePermission — право, выданное роли
+type RolePermission struct {
+	ID         uint   `gorm:"primaryKey" json:"-"`
+	Role       string `gorm:"uniqueIndex:idx_role_permission;not null" json:"role"`
+	Permission string `gorm:"uniqueIndex:idx_role_permission;not null" json:"permission"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// RefreshToken — выданный refresh-токен. Хранится только SHA-256 от значения.
+// Токены одной цепочки ротации объединены FamilyID: повторное предъявление
+// уже использованного токена отзывает всю цепочку.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	FamilyID  string     `gorm:"index;not null" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	Subject   string     `gorm:"index;not null" json:"subject"` // "operator:<username>", "user:<telegram_id>" или "user:<telegram_id>@<stand>"
+	ExpiresAt time.Time  `gorm:"index;not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`    // Момент ротации; повторное использование — признак кражи
+	RevokedAt *time.Time `json:"revoked_at"` // Момент отзыва (логаут, отзыв сессий, обнаружение повтора)
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// RevokedToken — отозванный до истечения срока JWT (например, после логаута)
+type RevokedToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	JTI       string    `gorm:"column:jti;uniqueIndex;not null" json:"jti"`
+	Subject   string    `gorm:"index;not null" json:"subject"`
+	ExpiresAt time.Time `gorm:"index;not null" json:"expires_at"` // После этого момента запись можно удалить
+}
+
+// SubjectRevocation — отзыв всех токенов субъекта, выданных раньше RevokedBefore
+type SubjectRevocation struct {
+	Subject       string    `gorm:"primaryKey" json:"subject"` // "operator:<username>", "user:<telegram_id>" или "user:<telegram_id>@<stand>"
+	RevokedBefore time.Time `gorm:"not null" json:"revoked_before"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// TelegramChannel — бот Telegram, через который пользователи стенда пишут в поддержку.
+// Обновления бота приходят на /api/channels/telegram/<stand>/webhook
+type TelegramChannel struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Stand       string    `gorm:"uniqueIndex;not null" json:"stand" example:"ift"`
+	BotUsername string    `json:"bot_username" example:"helpdesk_ift_bot"`
+	WebhookURL  string    `json:"webhook_url" example:"https://helpdesk.example.com/api/channels/telegram/ift/webhook"`
+	// BotToken и SecretToken наружу не отдаются. SecretToken Telegram присылает с каждым обновлением
+	BotToken    string `gorm:"not null" json:"-"`
+	SecretToken string `gorm:"not null" json:"-"`
+	UpdatedBy   string `json:"updated_by"`
+}
+
+// TelegramUpdate — уже обработанное обновление бота. Telegram повторяет доставку,
+// пока не получит 2xx, и повтор с тем же update_id пропускается. Записи старше
+// суток с запасом удаляются в фоне: такие обновления Telegram уже не повторяет
+type TelegramUpdate struct {
+	Stand     string    `gorm:"primaryKey"`
+	UpdateID  int64     `gorm:"primaryKey;autoIncrement:false"`
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -1,29 +1,119 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"time"
 )
 
+// Статусы жизненного цикла тикета
+const (
+	TicketStatusNew             = "new"
+	TicketStatusOpen            = "open"
+	TicketStatusPendingUser     = "pending_user"
+	TicketStatusPendingOperator = "pending_operator"
+	TicketStatusResolved        = "resolved"
+	TicketStatusClosed          = "closed"
+	TicketStatusReopened        = "reopened"
+)
+
+var (
+	ErrInvalidTicketStatus  = errors.New("неизвестный статус тикета")
+	ErrTransitionNotAllowed = errors.New("переход статуса тикета запрещён")
+)
+
+// ticketTransitions — таблица допустимых переходов между статусами тикета
+var ticketTransitions = map[string][]string{
+	TicketStatusNew:             {TicketStatusOpen, TicketStatusPendingUser, TicketStatusPendingOperator, TicketStatusResolved, TicketStatusClosed},
+	TicketStatusOpen:            {TicketStatusPendingUser, TicketStatusPendingOperator, TicketStatusResolved, TicketStatusClosed},
+	TicketStatusPendingUser:     {TicketStatusOpen, TicketStatusPendingOperator, TicketStatusResolved, TicketStatusClosed},
+	TicketStatusPendingOperator: {TicketStatusOpen, TicketStatusPendingUser, TicketStatusResolved, TicketStatusClosed},
+	TicketStatusResolved:        {TicketStatusClosed, TicketStatusReopened},
+	TicketStatusClosed:          {TicketStatusReopened},
+	TicketStatusReopened:        {TicketStatusOpen, TicketStatusPendingUser, TicketStatusPendingOperator, TicketStatusResolved, TicketStatusClosed},
+}
+
+// userTicketTransitions — статусы, в которые пользователь может перевести свой тикет
+var userTicketTransitions = map[string]bool{
+	TicketStatusClosed:   true,
+	TicketStatusReopened: true,
+}
+
 type Ticket struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	DeletedAt   time.Time `gorm:"index" json:"deleted_at,omitempty"` // Изменено на time.Time
-	UserID      uint      `json:"user_id"`
-	Subject     string    `json:"subject"`
-	Description string    `json:"description"`
-	Source      string    `json:"source"`
-	Status      string    `json:"status" gorm:"default:'open'"`
-	ShortID     string    `json:"short_id" gorm:"default:gen_random_uuid()"`
-	ClosedAt    time.Time `json:"closed_at,omitempty"`
-	ClosedBy    string    `json:"closed_by,omitempty"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   time.Time  `gorm:"index" json:"deleted_at,omitempty"` // Изменено на time.Time
+	UserID      uint       `json:"user_id"`
+	Subject     string     `json:"subject"`
+	Description string     `json:"description"`
+	Source      string     `json:"source"`
+	Status      string     `json:"status" gorm:"default:'new'"`
+	ShortID     string     `json:"short_id" gorm:"default:gen_random_uuid()"`
+	ClosedAt    time.Time  `json:"closed_at,omitempty"`
+	ClosedBy    string     `json:"closed_by,omitempty"`
+	AssigneeID  *uint      `gorm:"index" json:"assignee_id"`
+	Assignee    *Operator  `gorm:"foreignKey:AssigneeID;constraint:OnDelete:SET NULL" json:"assignee,omitempty"`
+	AssignedAt  *time.Time `json:"assigned_at,omitempty"`
+	Stand       string     `gorm:"index;not null;default:''" json:"stand"`
+	// LastMessageAt — время последнего сообщения (или создания тикета), используется для сортировки
+	LastMessageAt time.Time `gorm:"index" json:"last_message_at"`
+	// ExternalID — Message-ID письма, открывшего тикет (почтовый канал)
+	ExternalID string `gorm:"index;not null;default:''" json:"-"`
 }
 
 func (t *Ticket) BeforeCreate(tx *gorm.DB) error {
 	if t.ShortID == "" {
 		t.ShortID = uuid.New().String()
 	}
+	if t.Status == "" {
+		t.Status = TicketStatusNew
+	}
+	if t.LastMessageAt.IsZero() {
+		t.LastMessageAt = time.Now()
+	}
+	return nil
+}
+
+// IsValidTicketStatus проверяет, что статус входит в жизненный цикл тикета
+func IsValidTicketStatus(status string) bool {
+	_, ok := ticketTransitions[status]
+	return ok
+}
+
+// CanTransition сообщает, разрешён ли переход тикета в статус to для роли role
+func (t *Ticket) CanTransition(to, role string) bool {
+	if role == RoleUser && !userTicketTransitions[to] {
+		return false
+	}
+	for _, next := range ticketTransitions[t.Status] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition переводит тикет в новый статус, заполняя ClosedAt/ClosedBy при закрытии
+// и сбрасывая их при переоткрытии. actor — роль, инициировавшая переход.
+func (t *Ticket) Transition(to, actor string) error {
+	if !IsValidTicketStatus(to) {
+		return fmt.Errorf("%w: %s", ErrInvalidTicketStatus, to)
+	}
+	if !t.CanTransition(to, actor) {
+		return fmt.Errorf("%w: %s -> %s", ErrTransitionNotAllowed, t.Status, to)
+	}
+
+	t.Status = to
+	switch to {
+	case TicketStatusClosed:
+		t.ClosedAt = time.Now()
+		t.ClosedBy = actor
+	case TicketStatusReopened:
+		t.ClosedAt = time.Time{}
+		t.ClosedBy = ""
+	}
 	return nil
 }
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var allTicketStatuses = []string{
+	TicketStatusNew, TicketStatusOpen, TicketStatusPendingUser, TicketStatusPendingOperator,
+	TicketStatusResolved, TicketStatusClosed, TicketStatusReopened,
+}
+
+func TestTicketTransitionTable(t *testing.T) {
+	// Ожидаемые переходы для операторов; пользователю из них доступны только closed и reopened
+	allowed := map[string]map[string]bool{
+		TicketStatusNew:             {TicketStatusOpen: true, TicketStatusPendingUser: true, TicketStatusPendingOperator: true, TicketStatusResolved: true, TicketStatusClosed: true},
+		TicketStatusOpen:            {TicketStatusPendingUser: true, TicketStatusPendingOperator: true, TicketStatusResolved: true, TicketStatusClosed: true},
+		TicketStatusPendingUser:     {TicketStatusOpen: true, TicketStatusPendingOperator: true, TicketStatusResolved: true, TicketStatusClosed: true},
+		TicketStatusPendingOperator: {TicketStatusOpen: true, TicketStatusPendingUser: true, TicketStatusResolved: true, TicketStatusClosed: true},
+		TicketStatusResolved:        {TicketStatusClosed: true, TicketStatusReopened: true},
+		TicketStatusClosed:          {TicketStatusReopened: true},
+		TicketStatusReopened:        {TicketStatusOpen: true, TicketStatusPendingUser: true, TicketStatusPendingOperator: true, TicketStatusResolved: true, TicketStatusClosed: true},
+	}
+	userAllowed := map[string]bool{TicketStatusClosed: true, TicketStatusReopened: true}
+
+	for _, from := range allTicketStatuses {
+		for _, to := range allTicketStatuses {
+			for _, role := range []string{RoleUser, RoleOperator, RoleSupervisor, RoleAdmin} {
+				want := allowed[from][to] && (role != RoleUser || userAllowed[to])
+				ticket := Ticket{Status: from}
+				if got := ticket.CanTransition(to, role); got != want {
+					t.Errorf("CanTransition(%s -> %s, %s) = %v, want %v", from, to, role, got, want)
+				}
+
+				err := ticket.Transition(to, role)
+				if want && err != nil {
+					t.Errorf("Transition(%s -> %s, %s) = %v", from, to, role, err)
+				}
+				if !want {
+					if !errors.Is(err, ErrTransitionNotAllowed) {
+						t.Errorf("Transition(%s -> %s, %s) = %v, want ErrTransitionNotAllowed", from, to, role, err)
+					}
+					if ticket.Status != from {
+						t.Errorf("rejected transition %s -> %s changed status to %s", from, to, ticket.Status)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestTicketTransitionUnknownStatus(t *testing.T) {
+	ticket := Ticket{Status: TicketStatusOpen}
+	if err := ticket.Transition("archived", RoleAdmin); !errors.Is(err, ErrInvalidTicketStatus) {
+		t.Fatalf("Transition to unknown status = %v, want ErrInvalidTicketStatus", err)
+	}
+	if IsValidTicketStatus("archived") || !IsValidTicketStatus(TicketStatusPendingUser) {
+		t.Fatal("IsValidTicketStatus disagrees with the transition table")
+	}
+}
+
+func TestTicketTransitionClosedFields(t *testing.T) {
+	ticket := Ticket{Status: TicketStatusResolved}
+	before := time.Now()
+	if err := ticket.Transition(TicketStatusClosed, RoleUser); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if ticket.ClosedBy != RoleUser || ticket.ClosedAt.Before(before) {
+		t.Fatalf("closed ticket: ClosedBy = %q, ClosedAt = %v", ticket.ClosedBy, ticket.ClosedAt)
+	}
+
+	if err := ticket.Transition(TicketStatusReopened, RoleOperator); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if ticket.ClosedBy != "" || !ticket.ClosedAt.IsZero() {
+		t.Fatalf("reopened ticket keeps ClosedBy = %q, ClosedAt = %v", ticket.ClosedBy, ticket.ClosedAt)
+	}
+}
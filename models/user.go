@@ -14,6 +14,29 @@ type User struct {
 	DeletedAt  gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 	TelegramID string         `gorm:"unique;not null" json:"telegram_id"`
 	UUID       string         `gorm:"unique;not null" json:"uuid"`
+	// Email — адрес пользователя почтового канала. У такого пользователя нет Telegram,
+	// и в TelegramID записывается EmailUserTelegramID(адрес), чтобы не нарушать уникальность
+	Email string `gorm:"index;not null;default:''" json:"email,omitempty"`
+}
+
+// EmailUserTelegramID — значение TelegramID пользователя, пришедшего по почте
+func EmailUserTelegramID(address string) string {
+	return "email:" + address
+}
+
+// ValidTelegramID сообщает, похоже ли значение на Telegram ID пользователя: положительное десятичное
+// число без ведущих нулей, чтобы у одного пользователя была одна запись (и одна запись блоклиста).
+// Значения вида EmailUserTelegramID не проходят, поэтому клиент не может выдать себя за почтового пользователя
+func ValidTelegramID(id string) bool {
+	if id == "" || len(id) > 20 || id[0] == '0' {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -0,0 +1,26 @@
+package models
+
+import "testing"
+
+func TestValidTelegramID(t *testing.T) {
+	for _, tc := range []struct {
+		id   string
+		want bool
+	}{
+		{"88376478", true},
+		{"1", true},
+		{"", false},
+		{"0", false},
+		{"088376478", false},
+		{EmailUserTelegramID("user@example.com"), false},
+		{"-100123", false},
+		{"+123", false},
+		{"12.5", false},
+		{"123abc", false},
+		{"123456789012345678901", false},
+	} {
+		if got := ValidTelegramID(tc.id); got != tc.want {
+			t.Errorf("ValidTelegramID(%q) = %v, want %v", tc.id, got, tc.want)
+		}
+	}
+}
@@ -4,6 +4,14 @@ import (
 	"time"
 )
 
+// Значения Permission заявки whitelist и решения по ней
+const (
+	WhitelistPending = "pending"
+	WhitelistApprove = "approve"
+	WhitelistDeny    = "deny"
+	WhitelistRevoke  = "revoke"
+)
+
 type Whitelist struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	TelegramID   string    `gorm:"not null;index:idx_telegram_from,unique" json:"telegram_id"` // Уникальный индекс
@@ -14,8 +22,11 @@ type Whitelist struct {
 	LastName     string    `gorm:"not null;default:''" json:"last_name"`  // если необходимо
 	Username     string    `gorm:"not null;default:''" json:"username"`   // если необходимо
 	LanguageCode string    `json:"language_code"`
-	Permission   string    `gorm:"default:'pending'" json:"permission"` // "pending", "approve", "deny"
+	Permission   string    `gorm:"default:'pending'" json:"permission"` // "pending", "approve", "deny", "revoke"
 	CreatedAt    time.Time `json:"create_date"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	DeletedAt    time.Time `gorm:"index" json:"deleted_at,omitempty"`
+
+	// ExpiresAt — когда одобрение истекает; после этого доступ отзывается автоматически
+	ExpiresAt *time.Time `gorm:"index" json:"expires_at,omitempty"`
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// WhitelistDecision — запись журнала решений по заявке whitelist: кто, когда и почему
+// изменил доступ. OperatorID пуст у решений, принятых системой (по истечении срока или по правилу)
+type WhitelistDecision struct {
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	CreatedAt        time.Time  `gorm:"index" json:"created_at"`
+	WhitelistID      uint       `gorm:"index;not null" json:"whitelist_id"`
+	OperatorID       *uint      `gorm:"index" json:"operator_id"`
+	OperatorUsername string     `gorm:"not null;default:''" json:"operator_username"` // Имя на момент решения; "system" для автоматических
+	OldPermission    string     `gorm:"not null" json:"old_permission"`
+	NewPermission    string     `gorm:"not null" json:"new_permission"`
+	Comment          string     `gorm:"type:text;not null;default:''" json:"comment"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"` // Срок действия, назначенный при одобрении
+
+	// RuleID — правило автоматического решения, которое приняло это решение
+	RuleID *uint `gorm:"index" json:"rule_id,omitempty"`
+}
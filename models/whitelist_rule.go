@@ -0,0 +1,87 @@
+package models
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// usernamePatterns — кеш скомпилированных UsernamePattern: правила проверяются на каждой новой заявке
+var usernamePatterns sync.Map // шаблон -> compiledPattern
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	err error
+}
+
+// CompileUsernamePattern компилирует UsernamePattern правила (без учёта регистра).
+// Результат, в том числе ошибка, запоминается для следующих проверок
+func CompileUsernamePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := usernamePatterns.Load(pattern); ok {
+		compiled := cached.(compiledPattern)
+		return compiled.re, compiled.err
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	usernamePatterns.Store(pattern, compiledPattern{re: re, err: err})
+	return re, err
+}
+
+// WhitelistRule — правило автоматического решения по новой заявке whitelist.
+// Правила проверяются по возрастанию Priority (при равенстве — по ID), срабатывает первое подходящее.
+// Заданные условия объединяются по И, пустые условия не проверяются
+type WhitelistRule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Name      string    `gorm:"not null" json:"name" example:"dev для всех"`
+	Priority  int       `gorm:"index;not null;default:0" json:"priority" example:"10"` // Меньше — раньше
+	Enabled   bool      `gorm:"not null;default:true" json:"enabled"`
+	Action    string    `gorm:"not null" json:"action" example:"approve"` // approve или deny
+
+	// Условия
+	Stand             string   `gorm:"not null;default:''" json:"stand" example:"dev"`
+	UsernamePattern   string   `gorm:"not null;default:''" json:"username_pattern" example:"^qa_"` // Регулярное выражение, без учёта регистра
+	TelegramIDs       []string `gorm:"type:jsonb;serializer:json" json:"telegram_ids" example:"123456789"`
+	LanguageCode      string   `gorm:"not null;default:''" json:"language_code" example:"ru"`
+	ApprovedElsewhere bool     `gorm:"not null;default:false" json:"approved_elsewhere"` // У пользователя уже есть действующий (не истёкший) одобренный доступ к другому стенду
+
+	CreatedBy string `gorm:"not null;default:''" json:"created_by"`
+}
+
+// HasConditions сообщает, задано ли у правила хотя бы одно условие
+func (r WhitelistRule) HasConditions() bool {
+	return r.Stand != "" || r.UsernamePattern != "" || len(r.TelegramIDs) > 0 || r.LanguageCode != "" || r.ApprovedElsewhere
+}
+
+// Matches проверяет, подходит ли заявка под условия правила. approvedElsewhere — есть ли у
+// пользователя действующая одобренная заявка на другой стенд
+func (r WhitelistRule) Matches(whitelist Whitelist, approvedElsewhere bool) bool {
+	if r.Stand != "" && r.Stand != whitelist.From {
+		return false
+	}
+	if r.LanguageCode != "" && r.LanguageCode != whitelist.LanguageCode {
+		return false
+	}
+	if r.ApprovedElsewhere && !approvedElsewhere {
+		return false
+	}
+	if len(r.TelegramIDs) > 0 {
+		found := false
+		for _, id := range r.TelegramIDs {
+			if id == whitelist.TelegramID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.UsernamePattern != "" {
+		pattern, err := CompileUsernamePattern(r.UsernamePattern)
+		if err != nil || !pattern.MatchString(whitelist.Username) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package models
+
+import "testing"
+
+func TestWhitelistRuleMatches(t *testing.T) {
+	entry := Whitelist{TelegramID: "123456", From: "dev", Username: "QA_Ivanov", LanguageCode: "ru"}
+
+	for _, tc := range []struct {
+		name              string
+		rule              WhitelistRule
+		approvedElsewhere bool
+		want              bool
+	}{
+		{"stand", WhitelistRule{Stand: "dev"}, false, true},
+		{"other stand", WhitelistRule{Stand: "prom"}, false, false},
+		{"language", WhitelistRule{LanguageCode: "ru"}, false, true},
+		{"other language", WhitelistRule{LanguageCode: "en"}, false, false},
+		{"telegram id listed", WhitelistRule{TelegramIDs: []string{"1", "123456"}}, false, true},
+		{"telegram id not listed", WhitelistRule{TelegramIDs: []string{"1", "1234567"}}, false, false},
+		{"username pattern ignores case", WhitelistRule{UsernamePattern: "^qa_"}, false, true},
+		{"username pattern anchored", WhitelistRule{UsernamePattern: "^ivanov$"}, false, false},
+		{"username pattern substring", WhitelistRule{UsernamePattern: "ivan"}, false, true},
+		{"invalid username pattern", WhitelistRule{UsernamePattern: "qa_("}, false, false},
+		{"approved elsewhere", WhitelistRule{ApprovedElsewhere: true}, true, true},
+		{"not approved elsewhere", WhitelistRule{ApprovedElsewhere: true}, false, false},
+		{"approval elsewhere not required", WhitelistRule{Stand: "dev"}, true, true},
+		{"all conditions", WhitelistRule{Stand: "dev", LanguageCode: "ru", TelegramIDs: []string{"123456"}, UsernamePattern: "^qa_", ApprovedElsewhere: true}, true, true},
+		{"one condition fails", WhitelistRule{Stand: "dev", LanguageCode: "ru", TelegramIDs: []string{"123456"}, UsernamePattern: "^dev_", ApprovedElsewhere: true}, true, false},
+		{"no conditions", WhitelistRule{}, false, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			// Второй вызов берёт шаблон из кеша и должен дать тот же ответ
+			for i := 0; i < 2; i++ {
+				if got := tc.rule.Matches(entry, tc.approvedElsewhere); got != tc.want {
+					t.Fatalf("Matches = %v, want %v (call %d)", got, tc.want, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestWhitelistRuleHasConditions(t *testing.T) {
+	if (WhitelistRule{Name: "empty", Action: WhitelistApprove}).HasConditions() {
+		t.Error("rule without conditions reports HasConditions")
+	}
+	for _, rule := range []WhitelistRule{
+		{Stand: "dev"}, {UsernamePattern: "^qa_"}, {TelegramIDs: []string{"1"}}, {LanguageCode: "ru"}, {ApprovedElsewhere: true},
+	} {
+		if !rule.HasConditions() {
+			t.Errorf("%+v: HasConditions = false", rule)
+		}
+	}
+}
+
+func TestCompileUsernamePatternCaches(t *testing.T) {
+	first, err := CompileUsernamePattern("^cache_test_")
+	if err != nil {
+		t.Fatalf("CompileUsernamePattern: %v", err)
+	}
+	second, _ := CompileUsernamePattern("^cache_test_")
+	if first != second {
+		t.Error("pattern compiled twice")
+	}
+	if _, err := CompileUsernamePattern("(unclosed"); err == nil {
+		t.Error("invalid pattern compiled")
+	}
+	if _, err := CompileUsernamePattern("(unclosed"); err == nil {
+		t.Error("cached invalid pattern lost its error")
+	}
+}
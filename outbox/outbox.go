@@ -0,0 +1,324 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// batchSize — сколько сообщений диспетчер забирает за один опрос
+	batchSize = 50
+	// leaseDuration — на сколько откладывается сообщение, взятое в работу. Если процесс упадёт
+	// посреди доставки, сообщение снова станет доступным по истечении аренды
+	leaseDuration = 2 * time.Minute
+	// baseBackoff и maxBackoff — пределы экспоненциальной задержки между попытками
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// deliveryTimeout — сколько ждать одну попытку доставки
+	deliveryTimeout = 30 * time.Second
+)
+
+// ErrNotDead возвращается при попытке переотправить сообщение, которое не в статусе dead
+var ErrNotDead = errors.New("outbox message is not dead-lettered")
+
+// Handler доставляет одно сообщение. Ошибка означает, что попытку нужно повторить позже
+type Handler func(ctx context.Context, msg models.OutboxMessage) error
+
+// DeadHandler вызывается после того, как сообщение исчерпало попытки и ушло в dead;
+// err — ошибка последней попытки
+type DeadHandler func(msg models.OutboxMessage, err error)
+
+// Options — настройки диспетчера
+type Options struct {
+	PollInterval     time.Duration // Как часто искать сообщения к отправке
+	MaxAttempts      int           // После стольких неудач сообщение уходит в dead
+	StandConcurrency int           // Сколько сообщений одного стенда доставляется одновременно в одном экземпляре сервиса
+}
+
+// Dispatcher в фоне доставляет сообщения outbox обработчиками, зарегистрированными по типу
+type Dispatcher struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+	opts   Options
+
+	mu       sync.Mutex
+	handlers map[string]Handler
+	dead     map[string]DeadHandler
+	inflight map[string]int // Сколько сообщений каждого стенда доставляется прямо сейчас
+}
+
+// NewDispatcher создаёт диспетчер. Доставка начинается после Start
+func NewDispatcher(db *gorm.DB, logger *logrus.Logger, opts Options) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 2 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 10
+	}
+	if opts.StandConcurrency <= 0 {
+		opts.StandConcurrency = 2
+	}
+	return &Dispatcher{
+		db:       db,
+		logger:   logger,
+		opts:     opts,
+		handlers: make(map[string]Handler),
+		dead:     make(map[string]DeadHandler),
+		inflight: make(map[string]int),
+	}
+}
+
+// Register задаёт обработчик доставки для сообщений типа kind
+func (d *Dispatcher) Register(kind string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[kind] = handler
+}
+
+// OnDead задаёт обработчик, который вызывается, когда сообщение типа kind уходит в dead
+func (d *Dispatcher) OnDead(kind string, handler DeadHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dead[kind] = handler
+}
+
+// Start запускает фоновый цикл доставки
+func (d *Dispatcher) Start() {
+	go func() {
+		ticker := time.NewTicker(d.opts.PollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := d.poll(); err != nil {
+				d.logger.Errorf("Outbox poll failed: %v", err)
+			}
+		}
+	}()
+}
+
+// poll забирает созревшие сообщения и раздаёт их на доставку
+func (d *Dispatcher) poll() error {
+	var batch []models.OutboxMessage
+	now := time.Now()
+
+	// FOR UPDATE SKIP LOCKED позволяет нескольким экземплярам сервиса опрашивать outbox
+	// параллельно, не забирая одни и те же сообщения; аренда через next_attempt_at
+	// защищает сообщение, пока оно доставляется уже после коммита
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		// Берём не больше StandConcurrency сообщений каждого стенда, чтобы очередь одного
+		// недоступного стенда не забивала пакет и не задерживала остальные
+		due := tx.Raw(`SELECT id FROM (
+                SELECT id, row_number() OVER (PARTITION BY stand ORDER BY next_attempt_at, id) AS rn
+                FROM outbox_messages WHERE status = ? AND next_attempt_at <= ?
+            ) AS due WHERE rn <= ?`, models.OutboxStatusPending, now, d.opts.StandConcurrency)
+
+		var candidates []models.OutboxMessage
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id IN (?)", due).
+			Order("next_attempt_at asc, id asc").
+			Limit(batchSize).
+			Find(&candidates).Error
+		if err != nil {
+			return err
+		}
+
+		batch = d.acquire(candidates)
+		if len(batch) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(batch))
+		for i, msg := range batch {
+			ids[i] = msg.ID
+		}
+		return tx.Model(&models.OutboxMessage{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(leaseDuration)).Error
+	})
+	if err != nil {
+		d.release(batch)
+		return err
+	}
+
+	for _, msg := range batch {
+		go d.deliver(msg)
+	}
+	return nil
+}
+
+// acquire оставляет из кандидатов только те сообщения, для стендов которых есть свободные
+// слоты доставки, и занимает эти слоты
+func (d *Dispatcher) acquire(candidates []models.OutboxMessage) []models.OutboxMessage {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var acquired []models.OutboxMessage
+	for _, msg := range candidates {
+		if d.inflight[msg.Stand] >= d.opts.StandConcurrency {
+			continue
+		}
+		d.inflight[msg.Stand]++
+		acquired = append(acquired, msg)
+	}
+	return acquired
+}
+
+// release освобождает слоты доставки сообщений
+func (d *Dispatcher) release(batch []models.OutboxMessage) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, msg := range batch {
+		if d.inflight[msg.Stand]--; d.inflight[msg.Stand] <= 0 {
+			delete(d.inflight, msg.Stand)
+		}
+	}
+}
+
+// deliver доставляет одно сообщение и сохраняет результат попытки
+func (d *Dispatcher) deliver(msg models.OutboxMessage) {
+	defer d.release([]models.OutboxMessage{msg})
+
+	d.mu.Lock()
+	handler, ok := d.handlers[msg.Kind]
+	d.mu.Unlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for kind %s", msg.Kind)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+		err = handler(ctx, msg)
+		cancel()
+	}
+
+	if err == nil {
+		now := time.Now()
+		err := d.db.Model(&models.OutboxMessage{}).Where("id = ?", msg.ID).Updates(map[string]interface{}{
+			"status":       models.OutboxStatusDelivered,
+			"attempts":     msg.Attempts + 1,
+			"last_error":   "",
+			"delivered_at": now,
+		}).Error
+		if err != nil {
+			d.logger.Errorf("Failed to mark outbox message %d delivered: %v", msg.ID, err)
+		}
+		return
+	}
+
+	attempts := msg.Attempts + 1
+	updates, dead := failureUpdates(attempts, d.opts.MaxAttempts, err, time.Now())
+	if dead {
+		d.logger.Errorf("Outbox message %d (%s, stand %s) dead-lettered after %d attempts: %v", msg.ID, msg.Kind, msg.Stand, attempts, err)
+	} else {
+		d.logger.Warnf("Outbox message %d (%s, stand %s) attempt %d failed: %v", msg.ID, msg.Kind, msg.Stand, attempts, err)
+	}
+	if dbErr := d.db.Model(&models.OutboxMessage{}).Where("id = ?", msg.ID).Updates(updates).Error; dbErr != nil {
+		d.logger.Errorf("Failed to record outbox message %d failure: %v", msg.ID, dbErr)
+		return
+	}
+	if dead {
+		d.mu.Lock()
+		onDead := d.dead[msg.Kind]
+		d.mu.Unlock()
+		if onDead != nil {
+			onDead(msg, err)
+		}
+	}
+}
+
+// failureUpdates возвращает изменения сообщения после неудачной попытки номер attempts:
+// следующая попытка через backoff или, если попытки исчерпаны, статус dead
+func failureUpdates(attempts, maxAttempts int, err error, now time.Time) (updates map[string]interface{}, dead bool) {
+	updates = map[string]interface{}{
+		"attempts":   attempts,
+		"last_error": err.Error(),
+	}
+	if attempts >= maxAttempts {
+		updates["status"] = models.OutboxStatusDead
+		return updates, true
+	}
+	updates["next_attempt_at"] = now.Add(backoff(attempts))
+	return updates, false
+}
+
+// backoff возвращает задержку перед попыткой attempts+1: 10s, 20s, 40s… но не больше часа,
+// с разбросом ±20%, чтобы повторы к одному стенду не шли залпом
+func backoff(attempts int) time.Duration {
+	delay := baseBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(delay)/5*2+1)) - delay/5
+	return delay + jitter
+}
+
+// Enqueue записывает сообщение в outbox. tx должна быть транзакцией, в которой
+// сохраняется само изменение, — тогда уведомление не потеряется и не уйдёт без него
+func Enqueue(tx *gorm.DB, kind, stand string, payload interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.OutboxMessage{
+		Kind:          kind,
+		Stand:         stand,
+		Payload:       string(raw),
+		Status:        models.OutboxStatusPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// Retry возвращает сообщение из dead в очередь с обнулённым счётчиком попыток
+func Retry(db *gorm.DB, id uint) error {
+	result := db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, models.OutboxStatusDead).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := db.Model(&models.OutboxMessage{}).Where("id = ?", id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return ErrNotDead
+	}
+	return nil
+}
+
+// RetryDead возвращает в очередь все dead-сообщения, при необходимости только одного стенда
+// и только перечисленных типов (пустой kinds — любых). Возвращает число переотправленных сообщений
+func RetryDead(db *gorm.DB, stand string, kinds []string) (int64, error) {
+	query := db.Model(&models.OutboxMessage{}).Where("status = ?", models.OutboxStatusDead)
+	if stand != "" {
+		query = query.Where("stand = ?", stand)
+	}
+	if len(kinds) > 0 {
+		query = query.Where("kind IN ?", kinds)
+	}
+	result := query.Updates(map[string]interface{}{
+		"status":          models.OutboxStatusPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,180 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"helpdesk-api/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBackoff(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		base     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, 80 * time.Second},
+		{8, 1280 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{50, time.Hour},
+	} {
+		low, high := tc.base-tc.base/5, tc.base+tc.base/5
+		for i := 0; i < 200; i++ {
+			if delay := backoff(tc.attempts); delay < low || delay > high {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tc.attempts, delay, low, high)
+			}
+		}
+	}
+}
+
+func TestFailureUpdates(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	failure := errors.New("stand responded 503")
+
+	for _, tc := range []struct {
+		attempts, maxAttempts int
+		dead                  bool
+	}{
+		{1, 3, false},
+		{2, 3, false},
+		{3, 3, true},
+		{4, 3, true},
+		{1, 1, true},
+	} {
+		t.Run(fmt.Sprintf("%d of %d", tc.attempts, tc.maxAttempts), func(t *testing.T) {
+			updates, dead := failureUpdates(tc.attempts, tc.maxAttempts, failure, now)
+			if dead != tc.dead {
+				t.Fatalf("dead = %v, want %v", dead, tc.dead)
+			}
+			if updates["attempts"] != tc.attempts || updates["last_error"] != failure.Error() {
+				t.Fatalf("updates = %v", updates)
+			}
+			if tc.dead {
+				if updates["status"] != models.OutboxStatusDead {
+					t.Fatalf("status = %v, want dead", updates["status"])
+				}
+				if _, ok := updates["next_attempt_at"]; ok {
+					t.Fatal("dead message is scheduled for another attempt")
+				}
+				return
+			}
+			if _, ok := updates["status"]; ok {
+				t.Fatalf("status changed to %v before attempts are exhausted", updates["status"])
+			}
+			next, _ := updates["next_attempt_at"].(time.Time)
+			if delay := next.Sub(now); delay <= 0 || delay > maxBackoff+maxBackoff/5 {
+				t.Fatalf("next attempt in %v", delay)
+			}
+		})
+	}
+}
+
+func TestAcquireLimitsStandConcurrency(t *testing.T) {
+	d := NewDispatcher(nil, logrus.New(), Options{StandConcurrency: 2})
+	candidates := []models.OutboxMessage{
+		{ID: 1, Stand: "ift"}, {ID: 2, Stand: "ift"}, {ID: 3, Stand: "ift"}, {ID: 4, Stand: "prom"},
+	}
+	first := d.acquire(candidates)
+	if len(first) != 3 || first[0].ID != 1 || first[1].ID != 2 || first[2].ID != 4 {
+		t.Fatalf("acquired %+v, want messages 1, 2 and 4", first)
+	}
+	if again := d.acquire(candidates[2:3]); len(again) != 0 {
+		t.Fatalf("acquired %+v while ift has no free slots", again)
+	}
+
+	d.release(first[:1])
+	if again := d.acquire(candidates[2:3]); len(again) != 1 {
+		t.Fatalf("acquired %+v after a slot was released", again)
+	}
+	d.release(append(first[1:], candidates[2]))
+	if len(d.inflight) != 0 {
+		t.Fatalf("inflight = %v after everything was released", d.inflight)
+	}
+}
+
+// openTestDB подключается к тестовой базе из HELPDESK_TEST_DSN; без неё тест пропускается
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("HELPDESK_TEST_DSN")
+	if dsn == "" {
+		t.Skip("HELPDESK_TEST_DSN is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&models.OutboxMessage{}); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return db
+}
+
+func TestDeliverDeadLetters(t *testing.T) {
+	db := openTestDB(t)
+	stand := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() { db.Where("stand = ?", stand).Delete(&models.OutboxMessage{}) })
+
+	quiet := logrus.New()
+	quiet.SetOutput(io.Discard)
+	d := NewDispatcher(db, quiet, Options{MaxAttempts: 2})
+	failure := errors.New("stand responded 503")
+	d.Register("test.kind", func(ctx context.Context, msg models.OutboxMessage) error { return failure })
+	var deadErr error
+	d.OnDead("test.kind", func(msg models.OutboxMessage, err error) { deadErr = err })
+
+	if err := Enqueue(db, "test.kind", stand, map[string]string{"hello": "world"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	var msg models.OutboxMessage
+	db.Where("stand = ?", stand).First(&msg)
+
+	d.inflight[stand] = 1 // deliver освобождает слот, занятый в poll
+	d.deliver(msg)
+	db.First(&msg, msg.ID)
+	if msg.Status != models.OutboxStatusPending || msg.Attempts != 1 || msg.LastError != failure.Error() {
+		t.Fatalf("after first failure: %+v", msg)
+	}
+	if !msg.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("next attempt at %v is not in the future", msg.NextAttemptAt)
+	}
+	if deadErr != nil {
+		t.Fatal("OnDead called before attempts are exhausted")
+	}
+
+	d.inflight[stand] = 1
+	d.deliver(msg)
+	db.First(&msg, msg.ID)
+	if msg.Status != models.OutboxStatusDead || msg.Attempts != 2 {
+		t.Fatalf("after last failure: %+v", msg)
+	}
+	if !errors.Is(deadErr, failure) {
+		t.Fatalf("OnDead err = %v, want %v", deadErr, failure)
+	}
+
+	if n, err := RetryDead(db, stand, []string{"other.kind"}); err != nil || n != 0 {
+		t.Fatalf("RetryDead for another kind = %d, %v", n, err)
+	}
+	if n, err := RetryDead(db, stand, []string{"test.kind"}); err != nil || n != 1 {
+		t.Fatalf("RetryDead = %d, %v", n, err)
+	}
+	db.First(&msg, msg.ID)
+	if msg.Status != models.OutboxStatusPending || msg.Attempts != 0 {
+		t.Fatalf("after retry: %+v", msg)
+	}
+	if err := Retry(db, msg.ID); !errors.Is(err, ErrNotDead) {
+		t.Fatalf("Retry of a pending message = %v, want ErrNotDead", err)
+	}
+}
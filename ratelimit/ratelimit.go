@@ -0,0 +1,67 @@
+// Package ratelimit — ограничение частоты запросов по ключу (IP, telegram_id …) фиксированными окнами.
+// Счётчики хранятся в памяти экземпляра, поэтому при нескольких экземплярах лимит действует на каждый.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter пропускает не больше limit запросов с одним ключом за окно window
+type Limiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	start time.Time
+	count int
+}
+
+// New создаёт ограничитель. limit <= 0 отключает ограничение
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Allow учитывает запрос с ключом key. Если лимит исчерпан, возвращает false и время
+// до начала следующего окна
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.limit <= 0 {
+		return true, 0
+	}
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Раз в окно выбрасываем ключи, окна которых уже закончились, чтобы карта не росла бесконечно
+	if now.Sub(l.lastSweep) > l.window {
+		for key, b := range l.buckets {
+			if now.Sub(b.start) >= l.window {
+				delete(l.buckets, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok || now.Sub(b.start) >= l.window {
+		l.buckets[key] = &bucket{start: now, count: 1}
+		return true, 0
+	}
+	if b.count >= l.limit {
+		return false, b.start.Add(l.window).Sub(now)
+	}
+	b.count++
+	return true, 0
+}
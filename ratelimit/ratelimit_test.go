@@ -0,0 +1,94 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock — управляемые часы для Limiter
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestLimiter(limit int, window time.Duration) (*Limiter, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	l := New(limit, window)
+	l.now = clock.now
+	return l, clock
+}
+
+func TestAllow(t *testing.T) {
+	type step struct {
+		advance    time.Duration
+		key        string
+		want       bool
+		retryAfter time.Duration
+	}
+	tests := []struct {
+		name   string
+		limit  int
+		window time.Duration
+		steps  []step
+	}{
+		{
+			name: "limit within window", limit: 2, window: time.Minute,
+			steps: []step{
+				{0, "a", true, 0},
+				{10 * time.Second, "a", true, 0},
+				{5 * time.Second, "a", false, 45 * time.Second},
+				{15 * time.Second, "a", false, 30 * time.Second},
+			},
+		},
+		{
+			name: "window rollover", limit: 1, window: time.Minute,
+			steps: []step{
+				{0, "a", true, 0},
+				{59 * time.Second, "a", false, time.Second},
+				{time.Second, "a", true, 0},
+				{30 * time.Second, "a", false, 30 * time.Second},
+			},
+		},
+		{
+			name: "keys are independent", limit: 1, window: time.Minute,
+			steps: []step{
+				{0, "a", true, 0},
+				{0, "b", true, 0},
+				{0, "a", false, time.Minute},
+				{0, "b", false, time.Minute},
+			},
+		},
+		{
+			name: "zero limit disables", limit: 0, window: time.Minute,
+			steps: []step{{0, "a", true, 0}, {0, "a", true, 0}, {0, "a", true, 0}},
+		},
+		{
+			name: "negative limit disables", limit: -1, window: time.Minute,
+			steps: []step{{0, "a", true, 0}, {0, "a", true, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, clock := newTestLimiter(tt.limit, tt.window)
+			for i, s := range tt.steps {
+				clock.advance(s.advance)
+				ok, retryAfter := l.Allow(s.key)
+				if ok != s.want || retryAfter != s.retryAfter {
+					t.Fatalf("step %d: Allow(%q) = %v, %v; want %v, %v", i, s.key, ok, retryAfter, s.want, s.retryAfter)
+				}
+			}
+		})
+	}
+}
+
+func TestAllowSweepsExpiredBuckets(t *testing.T) {
+	l, clock := newTestLimiter(1, time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		l.Allow(key)
+	}
+	clock.advance(2 * time.Minute)
+	l.Allow("d")
+	if len(l.buckets) != 1 {
+		t.Fatalf("buckets after sweep = %d, want 1", len(l.buckets))
+	}
+}
@@ -2,33 +2,47 @@ package routes
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"helpdesk-api/auth"
 	"helpdesk-api/config"
 	"helpdesk-api/handlers"
 	"helpdesk-api/middleware"
 	"helpdesk-api/models"
+	"helpdesk-api/storage"
 )
 
-func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *logrus.Logger) {
+func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *logrus.Logger, store storage.Storage) {
 	handlers.LoadEndpoints(db)
+	revocations := auth.NewRevocationStore(db, logger)
+	permissions := auth.NewPermissionStore(db, logger)
+	blocklist := auth.NewBlocklistStore(db, logger)
+	guard := middleware.NewPublicGuard(db, cfg, blocklist)
 
 	public := router.Group("/api")
 	{
-		public.POST("/consumers/token/", func(c *gin.Context) {
+		public.POST("/consumers/token/", guard.Middleware(middleware.JSONField("telegram_id")), func(c *gin.Context) {
 			handlers.RegisterConsumer(c, db, cfg)
 		})
 		public.POST("/token/", func(c *gin.Context) {
 			handlers.Login(c, db, cfg)
 		})
-		public.POST("/whitelist", func(c *gin.Context) {
+		public.POST("/token/refresh/", func(c *gin.Context) {
+			handlers.RefreshTokens(c, db, cfg)
+		})
+		public.POST("/whitelist", guard.Middleware(middleware.JSONField("user", "id")), func(c *gin.Context) {
 			handlers.AddWhitelistRequest(c, db)
 		})
+		public.POST("/channels/telegram/:stand/webhook", func(c *gin.Context) {
+			handlers.TelegramWebhook(c, db, cfg, blocklist)
+		})
+		public.POST("/channels/email/inbound", func(c *gin.Context) {
+			handlers.IngestEmail(c, db, cfg)
+		})
 	}
 
 	protected := router.Group("/api")
-	protected.Use(middleware.JWTMiddleware(cfg.JWTSecret))
+	protected.Use(middleware.JWTMiddleware(cfg.JWTSecret, revocations, permissions))
 	{
 		protected.POST("/tickets/create", func(c *gin.Context) {
 			handlers.CreateTicket(c, db)
@@ -37,36 +51,144 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 			handlers.ListTickets(c, db)
 		})
 		protected.POST("/tickets/:ticket_id/messages/", func(c *gin.Context) {
-			handlers.AddMessage(c, db)
+			handlers.AddMessage(c, db, cfg, store)
 		})
 		protected.GET("/tickets/:ticket_id/messages/", func(c *gin.Context) {
 			handlers.GetTicketHistory(c, db)
 		})
+		protected.GET("/tickets/:ticket_id/attachments/:attachment_id", func(c *gin.Context) {
+			handlers.DownloadAttachment(c, db, store)
+		})
 		protected.POST("/tickets/:ticket_id/close/", func(c *gin.Context) {
 			handlers.CloseTicket(c, db)
 		})
+		protected.POST("/tickets/:ticket_id/reopen/", func(c *gin.Context) {
+			handlers.ReopenTicket(c, db)
+		})
+		protected.POST("/tickets/:ticket_id/transition", func(c *gin.Context) {
+			handlers.TransitionTicket(c, db)
+		})
+		protected.GET("/ws", func(c *gin.Context) {
+			handlers.TicketEventsWS(c, db)
+		})
+		protected.GET("/events/stream", func(c *gin.Context) {
+			handlers.EventStream(c, db)
+		})
 		protected.POST("/logout/", func(c *gin.Context) {
-			handlers.Logout(c, db, cfg)
+			handlers.Logout(c, db, cfg, revocations)
 		})
 
 		operator := protected.Group("/operator")
-		operator.Use(operatorMiddleware())
+		operator.Use(middleware.RequireStaff())
 		{
-			operator.POST("/ticket/:ticket_id/close/", func(c *gin.Context) {
+			operator.POST("/ticket/:ticket_id/close/", middleware.RequirePermission(models.PermTicketsCloseAny), func(c *gin.Context) {
 				handlers.CloseTicketOperator(c, db)
 			})
-			operator.POST("/whitelist/:telegram_id/edit", func(c *gin.Context) {
+			operator.POST("/ticket/:ticket_id/claim/", middleware.RequirePermission(models.PermTicketsAssign), func(c *gin.Context) {
+				handlers.ClaimTicket(c, db)
+			})
+			operator.POST("/ticket/:ticket_id/release/", middleware.RequirePermission(models.PermTicketsAssign), func(c *gin.Context) {
+				handlers.ReleaseTicket(c, db)
+			})
+			operator.POST("/ticket/:ticket_id/transfer/", middleware.RequirePermission(models.PermTicketsAssign), func(c *gin.Context) {
+				handlers.TransferTicket(c, db)
+			})
+			operator.POST("/ticket/:ticket_id/notes/", middleware.RequirePermission(models.PermTicketsReply), func(c *gin.Context) {
+				handlers.AddTicketNote(c, db, permissions)
+			})
+			operator.GET("/notifications/", func(c *gin.Context) {
+				handlers.ListNotifications(c, db)
+			})
+			operator.POST("/notifications/read-all", func(c *gin.Context) {
+				handlers.MarkAllNotificationsRead(c, db)
+			})
+			operator.POST("/notifications/:id/read", func(c *gin.Context) {
+				handlers.MarkNotificationRead(c, db)
+			})
+			operator.POST("/sessions/revoke-all", func(c *gin.Context) {
+				handlers.RevokeAllSessions(c, db, revocations)
+			})
+			operator.GET("/search", middleware.RequirePermission(models.PermTicketsReadAll), func(c *gin.Context) {
+				handlers.Search(c, db)
+			})
+			operator.POST("/whitelist/:id/edit", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
 				handlers.EditWhitelist(c, db)
 			})
-			operator.GET("/whitelist/", func(c *gin.Context) {
+			operator.POST("/whitelist/bulk", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.BulkEditWhitelist(c, db)
+			})
+			operator.GET("/whitelist/:id/history", middleware.RequirePermission(models.PermWhitelistRead), func(c *gin.Context) {
+				handlers.GetWhitelistHistory(c, db)
+			})
+			operator.GET("/whitelist/user/:telegram_id", middleware.RequirePermission(models.PermWhitelistRead), func(c *gin.Context) {
+				handlers.GetUserWhitelist(c, db)
+			})
+			operator.POST("/whitelist/user/:telegram_id/:stand/edit", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.EditUserWhitelist(c, db)
+			})
+			operator.GET("/whitelist/", middleware.RequirePermission(models.PermWhitelistRead), func(c *gin.Context) {
 				handlers.GetWhitelistPending(c, db)
 			})
-			operator.GET("/whitelist/all", func(c *gin.Context) {
+			operator.GET("/whitelist/all", middleware.RequirePermission(models.PermWhitelistRead), func(c *gin.Context) {
 				handlers.GetWhitelistAll(c, db)
 			})
+			operator.GET("/whitelist/export", middleware.RequirePermission(models.PermWhitelistRead), func(c *gin.Context) {
+				handlers.ExportWhitelist(c, db)
+			})
+
+			operator.GET("/whitelist-rules/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.ListWhitelistRules(c, db)
+			})
+			operator.POST("/whitelist-rules/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.CreateWhitelistRule(c, db)
+			})
+			operator.PUT("/whitelist-rules/:id", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.UpdateWhitelistRule(c, db)
+			})
+			operator.DELETE("/whitelist-rules/:id", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.DeleteWhitelistRule(c, db)
+			})
+
+			operator.GET("/blocklist/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.ListBlocklist(c, db)
+			})
+			operator.POST("/blocklist/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.CreateBlocklistEntry(c, db, blocklist)
+			})
+			operator.DELETE("/blocklist/:id", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.DeleteBlocklistEntry(c, db, blocklist)
+			})
+
+			operator.GET("/outbox/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.ListOutbox(c, db)
+			})
+			operator.POST("/outbox/retry", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.RetryOutbox(c, db)
+			})
+			operator.POST("/outbox/:id/retry", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.RetryOutboxMessage(c, db)
+			})
+
+			operator.GET("/templates/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.ListMessageTemplates(c, db)
+			})
+			operator.POST("/templates/", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.CreateMessageTemplate(c, db)
+			})
+			operator.POST("/templates/preview", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.PreviewMessageTemplate(c, db)
+			})
+			operator.PUT("/templates/:id", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.UpdateMessageTemplate(c, db)
+			})
+			operator.DELETE("/templates/:id", middleware.RequirePermission(models.PermWhitelistApprove), func(c *gin.Context) {
+				handlers.DeleteMessageTemplate(c, db)
+			})
 
 			// Маршруты для управления настройками
-			operator.GET("/settings/", func(c *gin.Context) {
+			settings := operator.Group("/settings")
+			settings.Use(middleware.RequirePermission(models.PermSettingsManage))
+			settings.GET("/", func(c *gin.Context) {
 				var endpoints []models.Endpoint
 				if err := db.Find(&endpoints).Error; err != nil {
 					logger.Errorf("Failed to fetch endpoints: %v", err)
@@ -77,7 +199,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 				c.JSON(200, endpoints)
 			})
 
-			operator.POST("/settings/", func(c *gin.Context) {
+			settings.POST("/", func(c *gin.Context) {
 				var endpoint models.Endpoint
 				if err := c.ShouldBindJSON(&endpoint); err != nil {
 					logger.Errorf("Invalid JSON for endpoint: %v", err)
@@ -85,7 +207,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 					return
 				}
 				if endpoint.Name == "" || endpoint.URL == "" {
-					logger.Errorf("Missing required fields: %+v", endpoint)
+					logger.Errorf("Missing required fields: name=%q url=%q", endpoint.Name, endpoint.URL)
 					c.JSON(400, gin.H{"error": "Name and URL are required"})
 					return
 				}
@@ -95,11 +217,12 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 					return
 				}
 				handlers.LoadEndpoints(db)
-				logger.Infof("Created endpoint: %+v", endpoint)
-				c.JSON(201, endpoint)
+				logger.Infof("Created endpoint %d: %s %s", endpoint.ID, endpoint.Name, endpoint.URL)
+				// Секрет подписи показывается один раз, дальше — только через ротацию
+				c.JSON(201, gin.H{"id": endpoint.ID, "name": endpoint.Name, "url": endpoint.URL, "secret": endpoint.Secret})
 			})
 
-			operator.PUT("/settings/:id", func(c *gin.Context) {
+			settings.PUT("/:id", func(c *gin.Context) {
 				id := c.Param("id")
 				var endpoint models.Endpoint
 				if err := db.First(&endpoint, id).Error; err != nil {
@@ -113,7 +236,7 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 					return
 				}
 				if endpoint.Name == "" || endpoint.URL == "" {
-					logger.Errorf("Missing required fields: %+v", endpoint)
+					logger.Errorf("Missing required fields: name=%q url=%q", endpoint.Name, endpoint.URL)
 					c.JSON(400, gin.H{"error": "Name and URL are required"})
 					return
 				}
@@ -123,11 +246,25 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 					return
 				}
 				handlers.LoadEndpoints(db)
-				logger.Infof("Updated endpoint: %+v", endpoint)
+				logger.Infof("Updated endpoint %d: %s %s", endpoint.ID, endpoint.Name, endpoint.URL)
 				c.JSON(200, endpoint)
 			})
 
-			operator.DELETE("/settings/:id", func(c *gin.Context) {
+			settings.POST("/:id/secret", func(c *gin.Context) {
+				handlers.RotateEndpointSecret(c, db)
+			})
+
+			settings.GET("/telegram/", func(c *gin.Context) {
+				handlers.ListTelegramChannels(c, db)
+			})
+			settings.PUT("/telegram/:stand", func(c *gin.Context) {
+				handlers.SetTelegramChannel(c, db, cfg)
+			})
+			settings.DELETE("/telegram/:stand", func(c *gin.Context) {
+				handlers.DeleteTelegramChannel(c, db, cfg)
+			})
+
+			settings.DELETE("/:id", func(c *gin.Context) {
 				id := c.Param("id")
 				if id == "" {
 					logger.Errorf("Invalid endpoint ID: %s", id)
@@ -144,32 +281,44 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB, cfg *config.Config, logger *lo
 				c.JSON(204, nil)
 			})
 		}
-	}
-}
 
-func operatorMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		claims, exists := c.Get("claims")
-		if !exists {
-			c.JSON(401, gin.H{"error": "Unauthorized"})
-			c.Abort()
-			return
-		}
-
-		jwtClaims, ok := claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(500, gin.H{"error": "Invalid claims type"})
-			c.Abort()
-			return
-		}
-
-		role, ok := jwtClaims["role"].(string)
-		if !ok || role != "operator" {
-			c.JSON(403, gin.H{"error": "Forbidden: Operator access required"})
-			c.Abort()
-			return
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequirePermission(models.PermOperatorsManage))
+		{
+			admin.GET("/operators/", func(c *gin.Context) {
+				handlers.ListOperators(c, db)
+			})
+			admin.POST("/operators/", func(c *gin.Context) {
+				handlers.CreateOperator(c, db)
+			})
+			admin.POST("/operators/:id/disable", func(c *gin.Context) {
+				handlers.DisableOperator(c, db, revocations, permissions)
+			})
+			admin.POST("/operators/:id/enable", func(c *gin.Context) {
+				handlers.EnableOperator(c, db, revocations, permissions)
+			})
+			admin.POST("/operators/:id/password", func(c *gin.Context) {
+				handlers.ResetOperatorPassword(c, db, revocations)
+			})
+			admin.PUT("/operators/:id/role", func(c *gin.Context) {
+				handlers.ChangeOperatorRole(c, db, revocations, permissions)
+			})
+			admin.GET("/permissions", handlers.ListPermissions)
+			admin.GET("/audit", func(c *gin.Context) {
+				handlers.ListAuditLog(c, db)
+			})
+			admin.GET("/roles/", func(c *gin.Context) {
+				handlers.ListRoles(c, db)
+			})
+			admin.POST("/roles/", func(c *gin.Context) {
+				handlers.CreateRole(c, db, permissions)
+			})
+			admin.PUT("/roles/:name", func(c *gin.Context) {
+				handlers.UpdateRole(c, db, permissions)
+			})
+			admin.DELETE("/roles/:name", func(c *gin.Context) {
+				handlers.DeleteRole(c, db, permissions)
+			})
 		}
-
-		c.Next()
 	}
 }
@@ -0,0 +1,191 @@
+// Package standauth подписывает запросы helpdesk-api к стендам и проверяет эти подписи
+// на стороне стенда.
+//
+// Каждый запрос несёт три заголовка:
+//
+//	X-Helpdesk-Delivery-ID: уникальный ID доставки (повторы одной доставки приходят с тем же ID)
+//	X-Helpdesk-Timestamp:   время отправки, Unix-секунды
+//	X-Helpdesk-Signature:   v1=<hex HMAC-SHA256>
+//
+// Подписывается строка "v1:<timestamp>:<delivery id>:<тело запроса>" общим секретом стенда.
+//
+// На стенде достаточно обернуть обработчик:
+//
+//	verifier := standauth.NewVerifier(os.Getenv("HELPDESK_SECRET"), 5*time.Minute)
+//	http.Handle("/notify", verifier.Middleware(notifyHandler))
+package standauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Заголовки подписанного запроса
+const (
+	HeaderDeliveryID = "X-Helpdesk-Delivery-ID"
+	HeaderTimestamp  = "X-Helpdesk-Timestamp"
+	HeaderSignature  = "X-Helpdesk-Signature"
+)
+
+// signatureVersion — версия схемы подписи, префикс значения заголовка
+const signatureVersion = "v1"
+
+// maxBodySize — ограничение на размер тела, которое Verifier читает в память
+const maxBodySize = 1 << 20
+
+var (
+	ErrMissingHeaders = errors.New("standauth: missing signature headers")
+	ErrBadTimestamp   = errors.New("standauth: timestamp is malformed or outside the allowed window")
+	ErrBadSignature   = errors.New("standauth: signature mismatch")
+	ErrBodyTooLarge   = errors.New("standauth: request body exceeds 1 MB")
+	// ErrReplayed — доставка с таким ID уже была принята. Повтор может быть законным
+	// (helpdesk-api не получил ответ и повторил попытку), поэтому стенду стоит ответить 200,
+	// не выполняя действие второй раз
+	ErrReplayed = errors.New("standauth: delivery already processed")
+)
+
+// Sign вычисляет значение заголовка X-Helpdesk-Signature
+func Sign(secret, deliveryID string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signatureVersion + ":" + strconv.FormatInt(timestamp.Unix(), 10) + ":" + deliveryID + ":"))
+	mac.Write(body)
+	return signatureVersion + "=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest проставляет запросу заголовки доставки и подписи. body должен совпадать с телом запроса
+func SignRequest(req *http.Request, secret, deliveryID string, body []byte) {
+	now := time.Now()
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, deliveryID, now, body))
+}
+
+// Verifier проверяет подписи входящих запросов и отбрасывает повторы
+type Verifier struct {
+	secret    string
+	tolerance time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // delivery ID -> когда запись можно забыть
+}
+
+// NewVerifier создаёт проверяющего с общим секретом стенда. tolerance — допустимое
+// расхождение часов; запросы с меткой времени за его пределами отвергаются, поэтому
+// ID доставок достаточно помнить ограниченное время
+func NewVerifier(secret string, tolerance time.Duration) *Verifier {
+	return &Verifier{
+		secret:    secret,
+		tolerance: tolerance,
+		seen:      make(map[string]time.Time),
+	}
+}
+
+// Verify проверяет подпись запроса и возвращает его тело. Тело запроса при этом
+// подменяется копией, так что его можно читать дальше как обычно
+func (v *Verifier) Verify(r *http.Request) ([]byte, error) {
+	deliveryID := r.Header.Get(HeaderDeliveryID)
+	rawTimestamp := r.Header.Get(HeaderTimestamp)
+	signature := r.Header.Get(HeaderSignature)
+	if deliveryID == "" || rawTimestamp == "" || signature == "" {
+		return nil, ErrMissingHeaders
+	}
+
+	unix, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil {
+		return nil, ErrBadTimestamp
+	}
+	timestamp := time.Unix(unix, 0)
+	now := time.Now()
+	if timestamp.Before(now.Add(-v.tolerance)) || timestamp.After(now.Add(v.tolerance)) {
+		return nil, ErrBadTimestamp
+	}
+
+	// Лишний байт отличает тело ровно в предел от обрезанного: подпись обрезанного не сошлась бы
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodySize {
+		return nil, ErrBodyTooLarge
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := Sign(v.secret, deliveryID, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrBadSignature
+	}
+
+	if !v.remember(deliveryID, now) {
+		return body, ErrReplayed
+	}
+	return body, nil
+}
+
+// remember запоминает ID доставки и сообщает, что он встретился впервые
+func (v *Verifier) remember(deliveryID string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for id, expires := range v.seen {
+		if now.After(expires) {
+			delete(v.seen, id)
+		}
+	}
+	if _, ok := v.seen[deliveryID]; ok {
+		return false
+	}
+	// Запрос с допустимой меткой времени может прийти ещё 2*tolerance
+	v.seen[deliveryID] = now.Add(2 * v.tolerance)
+	return true
+}
+
+// Forget удаляет ID доставки из списка принятых, чтобы повтор этой доставки был обработан заново.
+// Нужен, если стенд не смог выполнить действие после успешной проверки подписи
+func (v *Verifier) Forget(deliveryID string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.seen, deliveryID)
+}
+
+// Middleware пропускает к next только подписанные запросы. Повтор уже принятой доставки
+// подтверждается ответом 200 без вызова next. Если next ответил 5xx, доставка не считается
+// принятой и её повтор будет обработан
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := v.Verify(r)
+		switch {
+		case err == nil:
+			recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+			if recorder.status >= http.StatusInternalServerError {
+				v.Forget(r.Header.Get(HeaderDeliveryID))
+			}
+		case errors.Is(err, ErrReplayed):
+			w.WriteHeader(http.StatusOK)
+		case errors.Is(err, ErrBodyTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		}
+	})
+}
+
+// statusRecorder запоминает код ответа обработчика
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,189 @@
+package standauth
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testSecret = "stand-secret"
+
+// signedRequest собирает запрос, подписанный как это делает helpdesk-api
+func signedRequest(t *testing.T, deliveryID string, timestamp time.Time, body []byte) *http.Request {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	req.Header.Set(HeaderDeliveryID, deliveryID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(testSecret, deliveryID, timestamp, body))
+	return req
+}
+
+func TestVerifyRoundTrip(t *testing.T) {
+	body := []byte(`{"chatId":42,"message":"Доступ одобрен"}`)
+	req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	SignRequest(req, testSecret, "outbox-1", body)
+
+	verifier := NewVerifier(testSecret, 5*time.Minute)
+	got, err := verifier.Verify(req)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Fatalf("Verify returned body %q, want %q", got, body)
+	}
+	// Тело остаётся доступным обработчику
+	rest, _ := io.ReadAll(req.Body)
+	if !bytes.Equal(rest, body) {
+		t.Fatalf("request body after Verify = %q, want %q", rest, body)
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"chatId":42}`)
+
+	tests := []struct {
+		name    string
+		request func() *http.Request
+		want    error
+	}{
+		{
+			name: "tampered body",
+			request: func() *http.Request {
+				req := signedRequest(t, "outbox-2", now, body)
+				req.Body = io.NopCloser(bytes.NewReader([]byte(`{"chatId":43}`)))
+				return req
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name: "wrong secret",
+			request: func() *http.Request {
+				req := signedRequest(t, "outbox-3", now, body)
+				req.Header.Set(HeaderSignature, Sign("other-secret", "outbox-3", now, body))
+				return req
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name: "delivery id swapped",
+			request: func() *http.Request {
+				req := signedRequest(t, "outbox-4", now, body)
+				req.Header.Set(HeaderDeliveryID, "outbox-5")
+				return req
+			},
+			want: ErrBadSignature,
+		},
+		{
+			name:    "stale timestamp",
+			request: func() *http.Request { return signedRequest(t, "outbox-6", now.Add(-10*time.Minute), body) },
+			want:    ErrBadTimestamp,
+		},
+		{
+			name:    "timestamp from the future",
+			request: func() *http.Request { return signedRequest(t, "outbox-7", now.Add(10*time.Minute), body) },
+			want:    ErrBadTimestamp,
+		},
+		{
+			name: "malformed timestamp",
+			request: func() *http.Request {
+				req := signedRequest(t, "outbox-8", now, body)
+				req.Header.Set(HeaderTimestamp, "yesterday")
+				return req
+			},
+			want: ErrBadTimestamp,
+		},
+		{
+			name: "missing signature",
+			request: func() *http.Request {
+				req := signedRequest(t, "outbox-9", now, body)
+				req.Header.Del(HeaderSignature)
+				return req
+			},
+			want: ErrMissingHeaders,
+		},
+		{
+			name: "oversize body",
+			request: func() *http.Request {
+				return signedRequest(t, "outbox-10", now, bytes.Repeat([]byte("a"), maxBodySize+1))
+			},
+			want: ErrBodyTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := NewVerifier(testSecret, 5*time.Minute)
+			if _, err := verifier.Verify(tt.request()); !errors.Is(err, tt.want) {
+				t.Fatalf("Verify error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyBodyAtLimit(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), maxBodySize)
+	verifier := NewVerifier(testSecret, 5*time.Minute)
+	if _, err := verifier.Verify(signedRequest(t, "outbox-11", time.Now(), body)); err != nil {
+		t.Fatalf("Verify of a body exactly at the limit: %v", err)
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"chatId":42}`)
+	verifier := NewVerifier(testSecret, 5*time.Minute)
+
+	if _, err := verifier.Verify(signedRequest(t, "outbox-12", now, body)); err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	if _, err := verifier.Verify(signedRequest(t, "outbox-12", now, body)); !errors.Is(err, ErrReplayed) {
+		t.Fatalf("replayed delivery error = %v, want %v", err, ErrReplayed)
+	}
+
+	verifier.Forget("outbox-12")
+	if _, err := verifier.Verify(signedRequest(t, "outbox-12", now, body)); err != nil {
+		t.Fatalf("delivery after Forget: %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"chatId":42}`)
+	calls := 0
+	status := http.StatusOK
+	handler := NewVerifier(testSecret, 5*time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	}))
+	serve := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := serve(signedRequest(t, "outbox-13", now, body)); code != http.StatusOK || calls != 1 {
+		t.Fatalf("signed request: code %d, calls %d", code, calls)
+	}
+	if code := serve(signedRequest(t, "outbox-13", now, body)); code != http.StatusOK || calls != 1 {
+		t.Fatalf("replay must be acknowledged without calling the handler: code %d, calls %d", code, calls)
+	}
+	if code := serve(signedRequest(t, "outbox-14", now.Add(-time.Hour), body)); code != http.StatusUnauthorized {
+		t.Fatalf("stale request: code %d, want 401", code)
+	}
+	if code := serve(signedRequest(t, "outbox-15", now, bytes.Repeat([]byte("a"), maxBodySize+1))); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversize request: code %d, want 413", code)
+	}
+
+	// Ответ 5xx не засчитывает доставку, и её повтор снова доходит до обработчика
+	status = http.StatusInternalServerError
+	serve(signedRequest(t, "outbox-16", now, body))
+	status = http.StatusOK
+	if code := serve(signedRequest(t, "outbox-16", now, body)); code != http.StatusOK || calls != 3 {
+		t.Fatalf("retry after 5xx: code %d, calls %d", code, calls)
+	}
+}
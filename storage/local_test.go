@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalRejectsKeysOutsideDir(t *testing.T) {
+	store, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	for _, key := range []string{
+		"",
+		"/",
+		"../secret",
+		"tickets/../../secret",
+		"tickets/1/..",
+		"./tickets/1/a",
+		"/etc/passwd",
+		"tickets//1/a",
+		"tickets/1/a/",
+		`tickets\..\..\secret`,
+		`..\secret`,
+	} {
+		if path, err := store.path(key); err == nil {
+			t.Errorf("path(%q) = %q, want error", key, path)
+		}
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "text/plain"); err == nil {
+			t.Errorf("Put(%q) succeeded, want error", key)
+		}
+		if _, err := store.Open(context.Background(), key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Open(%q) error = %v, want invalid key", key, err)
+		}
+	}
+
+	path, err := store.path("tickets/1/3f2c")
+	if err != nil || path != filepath.Join(store.dir, "tickets", "1", "3f2c") {
+		t.Fatalf("path(tickets/1/3f2c) = %q, %v", path, err)
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+	const key, content = "tickets/7/file", "attachment body"
+
+	if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	f, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, _ := io.ReadAll(f)
+	f.Close()
+	if string(got) != content {
+		t.Fatalf("Open read %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Open after Delete: error = %v, want ErrNotFound", err)
+	}
+	// Удаление отсутствующего файла не ошибка
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("second Delete: %v", err)
+	}
+}
+
+func TestLocalPutSizeMismatch(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocal(dir)
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+	const key = "tickets/7/short"
+
+	err = store.Put(ctx, key, strings.NewReader("only ten b"), 100, "text/plain")
+	if err == nil || !strings.Contains(err.Error(), "wrote 10 bytes of 100") {
+		t.Fatalf("Put with wrong size: error = %v", err)
+	}
+	if _, err := store.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("file of failed Put is visible: error = %v", err)
+	}
+	// Временный файл тоже убран
+	entries, _ := os.ReadDir(filepath.Join(dir, "tickets", "7"))
+	if len(entries) != 0 {
+		t.Fatalf("leftover files after failed Put: %v", entries)
+	}
+}
@@ -0,0 +1,26 @@
+// Package storage хранит файлы вложений. Бэкенд выбирается конфигурацией: локальный каталог
+// (NewLocal) или S3-совместимое хранилище (NewS3) — AWS S3, MinIO и подобные.
+//
+// Для разработки подойдёт MinIO из docker-compose:
+//
+//	store, err := storage.NewS3("http://localhost:9000", "us-east-1", "attachments", "minioadmin", "minioadmin")
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound возвращается, если файла с таким ключом нет
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage — хранилище файлов по ключу. Ключ — относительный путь из сегментов через "/"
+type Storage interface {
+	// Put сохраняет size байт из r под ключом key, заменяя прежний файл
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open открывает файл для чтения; ErrNotFound, если его нет
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete удаляет файл; отсутствие файла ошибкой не считается
+	Delete(ctx context.Context, key string) error
+}
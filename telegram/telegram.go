@@ -0,0 +1,174 @@
+// Package telegram — минимальный клиент Telegram Bot API и типы входящих обновлений,
+// нужные каналу поддержки через бота.
+//
+// Адрес Bot API задаётся при создании клиента, поэтому в тестах и на стендах без доступа
+// в интернет клиент можно направить на локальный фейковый сервер:
+//
+//	client := telegram.NewClient("http://127.0.0.1:8081", "123:abc")
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultAPIURL — адрес Bot API по умолчанию
+const DefaultAPIURL = "https://api.telegram.org"
+
+// HeaderSecretToken — заголовок, в котором Telegram присылает secret_token, заданный в setWebhook
+const HeaderSecretToken = "X-Telegram-Bot-Api-Secret-Token"
+
+// requestTimeout — сколько ждать ответа Bot API на один вызов
+const requestTimeout = 15 * time.Second
+
+// Update — входящее обновление. Канал поддержки обрабатывает только message
+type Update struct {
+	UpdateID      int64    `json:"update_id"`
+	Message       *Message `json:"message,omitempty"`
+	EditedMessage *Message `json:"edited_message,omitempty"`
+}
+
+// Message — сообщение в чате с ботом
+type Message struct {
+	MessageID int64  `json:"message_id"`
+	From      *User  `json:"from,omitempty"`
+	Chat      Chat   `json:"chat"`
+	Date      int64  `json:"date"`
+	Text      string `json:"text,omitempty"`
+	Caption   string `json:"caption,omitempty"`
+}
+
+// User — отправитель сообщения
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+// Chat — чат, в который пришло сообщение
+type Chat struct {
+	ID   int64  `json:"id"`
+	Type string `json:"type"` // private, group, supergroup или channel
+}
+
+// ChatTypePrivate — личный чат пользователя с ботом
+const ChatTypePrivate = "private"
+
+// APIError — отказ Bot API (ok=false в ответе)
+type APIError struct {
+	Method      string
+	Code        int
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("telegram %s: %d %s", e.Method, e.Code, e.Description)
+}
+
+// Client вызывает методы Bot API от имени одного бота
+type Client struct {
+	apiURL string
+	token  string
+	http   *http.Client
+}
+
+// NewClient создаёт клиент бота с токеном token. Пустой apiURL — DefaultAPIURL
+func NewClient(apiURL, token string) *Client {
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+	return &Client{
+		apiURL: strings.TrimRight(apiURL, "/"),
+		token:  token,
+		http:   &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// GetMe возвращает учётную запись бота; заодно проверяет, что токен действителен
+func (c *Client) GetMe(ctx context.Context) (*User, error) {
+	var me User
+	if err := c.call(ctx, "getMe", struct{}{}, &me); err != nil {
+		return nil, err
+	}
+	return &me, nil
+}
+
+// SetWebhook направляет обновления бота на url. Telegram будет присылать secretToken
+// в заголовке HeaderSecretToken
+func (c *Client) SetWebhook(ctx context.Context, url, secretToken string) error {
+	params := map[string]interface{}{
+		"url":             url,
+		"secret_token":    secretToken,
+		"allowed_updates": []string{"message"},
+	}
+	return c.call(ctx, "setWebhook", params, nil)
+}
+
+// DeleteWebhook отключает доставку обновлений бота на webhook
+func (c *Client) DeleteWebhook(ctx context.Context) error {
+	return c.call(ctx, "deleteWebhook", struct{}{}, nil)
+}
+
+// SendMessage отправляет текстовое сообщение в чат chatID
+func (c *Client) SendMessage(ctx context.Context, chatID int64, text string) (*Message, error) {
+	params := map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	}
+	var sent Message
+	if err := c.call(ctx, "sendMessage", params, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// call выполняет метод Bot API и разбирает поле result ответа в result (если он не nil)
+func (c *Client) call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL+"/bot"+c.token+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// В тексте ошибки net/http есть URL, а в нём токен бота
+		return fmt.Errorf("telegram %s: %s", method, strings.ReplaceAll(err.Error(), c.token, "<token>"))
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		OK          bool            `json:"ok"`
+		Result      json.RawMessage `json:"result"`
+		ErrorCode   int             `json:"error_code"`
+		Description string          `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("telegram %s: status %d: invalid response: %w", method, resp.StatusCode, err)
+	}
+	if !envelope.OK {
+		code := envelope.ErrorCode
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return &APIError{Method: method, Code: code, Description: envelope.Description}
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("telegram %s: invalid result: %w", method, err)
+		}
+	}
+	return nil
+}
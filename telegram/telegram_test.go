@@ -0,0 +1,143 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const testToken = "123456:secret-bot-token"
+
+// fakeBotAPI — локальный сервер Bot API: запоминает вызовы и отвечает заданными result
+type fakeBotAPI struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	calls   []fakeCall
+	results map[string]string // метод -> JSON result; нет в карте — ответ ok=false
+}
+
+type fakeCall struct {
+	Method string
+	Params map[string]interface{}
+}
+
+func newFakeBotAPI(t *testing.T, results map[string]string) *fakeBotAPI {
+	t.Helper()
+	api := &fakeBotAPI{results: results}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefix := "/bot" + testToken + "/"
+		if !strings.HasPrefix(r.URL.Path, prefix) {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"ok":false,"error_code":401,"description":"Unauthorized"}`)
+			return
+		}
+		method := strings.TrimPrefix(r.URL.Path, prefix)
+		var params map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&params)
+
+		api.mu.Lock()
+		api.calls = append(api.calls, fakeCall{Method: method, Params: params})
+		result, ok := api.results[method]
+		api.mu.Unlock()
+
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`)
+			return
+		}
+		io.WriteString(w, `{"ok":true,"result":`+result+`}`)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+func (api *fakeBotAPI) lastCall(t *testing.T) fakeCall {
+	t.Helper()
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	if len(api.calls) == 0 {
+		t.Fatal("no Bot API calls recorded")
+	}
+	return api.calls[len(api.calls)-1]
+}
+
+func TestGetMe(t *testing.T) {
+	api := newFakeBotAPI(t, map[string]string{
+		"getMe": `{"id":123456,"is_bot":true,"first_name":"Helpdesk","username":"helpdesk_ift_bot"}`,
+	})
+	me, err := NewClient(api.URL, testToken).GetMe(context.Background())
+	if err != nil {
+		t.Fatalf("GetMe: %v", err)
+	}
+	if me.Username != "helpdesk_ift_bot" || !me.IsBot {
+		t.Fatalf("GetMe = %+v", me)
+	}
+}
+
+func TestSetWebhook(t *testing.T) {
+	api := newFakeBotAPI(t, map[string]string{"setWebhook": `true`})
+	err := NewClient(api.URL, testToken).SetWebhook(context.Background(), "https://helpdesk.example.com/api/channels/telegram/ift/webhook", "s3cret")
+	if err != nil {
+		t.Fatalf("SetWebhook: %v", err)
+	}
+	call := api.lastCall(t)
+	if call.Method != "setWebhook" {
+		t.Fatalf("method = %s", call.Method)
+	}
+	if call.Params["secret_token"] != "s3cret" || call.Params["url"] != "https://helpdesk.example.com/api/channels/telegram/ift/webhook" {
+		t.Fatalf("setWebhook params = %v", call.Params)
+	}
+	allowed, _ := call.Params["allowed_updates"].([]interface{})
+	if len(allowed) != 1 || allowed[0] != "message" {
+		t.Fatalf("allowed_updates = %v, want [message]", call.Params["allowed_updates"])
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	api := newFakeBotAPI(t, map[string]string{
+		"sendMessage": `{"message_id":77,"chat":{"id":555,"type":"private"},"date":1700000000,"text":"Ответ оператора"}`,
+	})
+	sent, err := NewClient(api.URL, testToken).SendMessage(context.Background(), 555, "Ответ оператора")
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if sent.MessageID != 77 || sent.Chat.ID != 555 {
+		t.Fatalf("SendMessage = %+v", sent)
+	}
+	call := api.lastCall(t)
+	if call.Method != "sendMessage" || call.Params["chat_id"] != float64(555) || call.Params["text"] != "Ответ оператора" {
+		t.Fatalf("sendMessage call = %+v", call)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	api := newFakeBotAPI(t, map[string]string{})
+	_, err := NewClient(api.URL, testToken).SendMessage(context.Background(), 1, "hi")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if apiErr.Method != "sendMessage" || apiErr.Code != 400 || !strings.Contains(apiErr.Description, "chat not found") {
+		t.Fatalf("APIError = %+v", apiErr)
+	}
+}
+
+func TestTransportErrorHidesToken(t *testing.T) {
+	api := newFakeBotAPI(t, map[string]string{})
+	api.Close() // соединение будет отвергнуто
+
+	_, err := NewClient(api.URL, testToken).GetMe(context.Background())
+	if err == nil {
+		t.Fatal("GetMe against a closed server succeeded")
+	}
+	if strings.Contains(err.Error(), testToken) {
+		t.Fatalf("error leaks bot token: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readBook распаковывает книгу и возвращает её части по именам
+func readBook(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		body, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		parts[f.Name] = string(body)
+	}
+	return parts
+}
+
+// sheetRows разбирает лист обратно в строки текстовых ячеек
+func sheetRows(t *testing.T, sheet string) [][]string {
+	t.Helper()
+	var parsed struct {
+		Rows []struct {
+			Cells []struct {
+				Type string `xml:"t,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(sheet), &parsed); err != nil {
+		t.Fatalf("sheet is not valid XML: %v\n%s", err, sheet)
+	}
+	var rows [][]string
+	for _, row := range parsed.Rows {
+		values := []string{}
+		for _, cell := range row.Cells {
+			if cell.Type != "inlineStr" {
+				t.Fatalf("cell type = %q, want inlineStr", cell.Type)
+			}
+			values = append(values, cell.Text)
+		}
+		rows = append(rows, values)
+	}
+	return rows
+}
+
+func TestWriter(t *testing.T) {
+	rows := [][]string{
+		{"id", "username", "text"},
+		{"1", "ivanov", "Прошу доступ к стенду"},
+		{"2", "<script>", "a & b > c"},
+		{"3", "", "многострочный\nтекст\tс табуляцией"},
+		{"4", "=HYPERLINK(\"http://evil\")", "  пробелы по краям  "},
+		{},
+	}
+	var out bytes.Buffer
+	w, err := NewWriter(&out, "Whitelist")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row); err != nil {
+			t.Fatalf("WriteRow: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	parts := readBook(t, out.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		body, ok := parts[name]
+		if !ok {
+			t.Fatalf("part %s is missing", name)
+		}
+		if err := xml.Unmarshal([]byte(body), new(struct{})); err != nil {
+			t.Fatalf("part %s is not valid XML: %v", name, err)
+		}
+	}
+
+	got := sheetRows(t, parts["xl/worksheets/sheet1.xml"])
+	if !reflect.DeepEqual(got, rows) {
+		t.Fatalf("rows = %q, want %q", got, rows)
+	}
+}
+
+func TestWriterInvalidXMLCharacters(t *testing.T) {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, "Sheet")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.WriteRow([]string{"bell\x07 and nul\x00"}); err != nil {
+		t.Fatalf("WriteRow: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got := sheetRows(t, readBook(t, out.Bytes())["xl/worksheets/sheet1.xml"])
+	if len(got) != 1 || strings.ContainsAny(got[0][0], "\x07\x00") {
+		t.Fatalf("rows = %q: control characters must be replaced", got)
+	}
+}
+
+func TestWorkbookSheetName(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"Whitelist", "Whitelist"},
+		{"ift/prom [2024]", "ift_prom _2024_"},
+		{"a:b*c?d\\e", "a_b_c_d_e"},
+		{"", "Sheet1"},
+		{"Заявки & решения", "Заявки & решения"},
+		{strings.Repeat("я", 40), strings.Repeat("я", maxSheetNameLength)},
+	} {
+		var parsed struct {
+			Sheets []struct {
+				Name string `xml:"name,attr"`
+			} `xml:"sheets>sheet"`
+		}
+		if err := xml.Unmarshal([]byte(workbookXML(tc.in)), &parsed); err != nil {
+			t.Fatalf("workbookXML(%q) is not valid XML: %v", tc.in, err)
+		}
+		if len(parsed.Sheets) != 1 || parsed.Sheets[0].Name != tc.want {
+			t.Errorf("sheet name for %q = %+v, want %q", tc.in, parsed.Sheets, tc.want)
+		}
+	}
+}
+
+func TestWriterClosed(t *testing.T) {
+	w, err := NewWriter(io.Discard, "Sheet")
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := w.WriteRow([]string{"late"}); !errors.Is(err, errClosed) {
+		t.Errorf("WriteRow after Close = %v, want errClosed", err)
+	}
+	if err := w.Close(); !errors.Is(err, errClosed) {
+		t.Errorf("second Close = %v, want errClosed", err)
+	}
+}